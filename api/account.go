@@ -12,7 +12,7 @@ import (
 )
 
 type createAccountRequest struct {
-	Currency string `json:"currency" binding:"required,oneof=CAD USD" `
+	Currency string `json:"currency" binding:"required"`
 }
 
 func (s *Server) createAccount(ctx *gin.Context) {
@@ -22,6 +22,10 @@ func (s *Server) createAccount(ctx *gin.Context) {
 		return
 	}
 
+	if !s.checkCurrency(ctx, req.Currency) {
+		return
+	}
+
 	authPayload := ctx.MustGet(authPayloadKey).(*token.Payload)
 
 	arg := db.CreateAccountParams{
@@ -43,7 +47,7 @@ func (s *Server) createAccount(ctx *gin.Context) {
 		return
 	}
 
-	ctx.JSON(http.StatusCreated, account)
+	ctx.JSON(http.StatusCreated, s.newAccountResponse(ctx, account))
 }
 
 type getAccountRequest struct {
@@ -57,31 +61,18 @@ func (s *Server) getAccount(ctx *gin.Context) {
 		return
 	}
 
-	account, err := s.store.GetAccount(ctx, req.ID)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			ctx.JSON(http.StatusNotFound, errorResponse(err))
-			return
-		}
-
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
-		return
-	}
-
-	authPayload := ctx.MustGet(authPayloadKey).(*token.Payload)
-
-	if account.Owner != authPayload.Username {
-		err := errors.New("wrong account id")
-		ctx.JSON(http.StatusForbidden, errorResponse(err))
+	account, ok := s.authorizeAccount(ctx, req.ID, accountRead)
+	if !ok {
 		return
 	}
 
-	ctx.JSON(http.StatusOK, account)
+	ctx.JSON(http.StatusOK, s.newAccountResponse(ctx, account))
 }
 
 type listAccountsRequest struct {
-	Limit  int32 `form:"limit"`
-	Offset int32 `form:"offset"`
+	Owner  string `form:"owner"`
+	Limit  int32  `form:"limit"`
+	Offset int32  `form:"offset"`
 }
 
 func (s *Server) listAccounts(ctx *gin.Context) {
@@ -104,7 +95,18 @@ func (s *Server) listAccounts(ctx *gin.Context) {
 	}
 
 	authPayload := ctx.MustGet(authPayloadKey).(*token.Payload)
-	arg := db.ListAccountsParams{Owner: authPayload.Username, Limit: req.Limit, Offset: req.Offset}
+
+	owner := authPayload.Username
+	if req.Owner != "" && req.Owner != owner {
+		if !hasRole(authPayload, roleBanker, roleAdmin) {
+			err := errors.New("cannot list accounts of another user")
+			ctx.JSON(http.StatusForbidden, errorResponse(err))
+			return
+		}
+		owner = req.Owner
+	}
+
+	arg := db.ListAccountsParams{Owner: owner, Limit: req.Limit, Offset: req.Offset}
 
 	accounts, err := s.store.ListAccounts(ctx, arg)
 	if err != nil {
@@ -117,12 +119,17 @@ func (s *Server) listAccounts(ctx *gin.Context) {
 		return
 	}
 
+	data := make([]accountResponse, len(accounts))
+	for i, account := range accounts {
+		data[i] = s.newAccountResponse(ctx, account)
+	}
+
 	ctx.JSON(http.StatusOK, gin.H{
 		"_metadata": map[string]interface{}{
 			"count":  len(accounts),
 			"offset": req.Offset,
 		},
-		"data": accounts,
+		"data": data,
 	})
 }
 
@@ -130,6 +137,8 @@ type deleteAccountRequest struct {
 	ID int64 `uri:"id" binding:"required,min=1"`
 }
 
+// deleteAccount closes an empty account. Accounts are never removed so that
+// transfer history stays intact for both parties.
 func (s *Server) deleteAccount(ctx *gin.Context) {
 	var req deleteAccountRequest
 	if err := ctx.ShouldBindUri(&req); err != nil {
@@ -137,14 +146,20 @@ func (s *Server) deleteAccount(ctx *gin.Context) {
 		return
 	}
 
-	err := s.store.DeleteAccountTx(ctx, req.ID)
+	if _, ok := s.authorizeAccount(ctx, req.ID, accountWrite); !ok {
+		return
+	}
+
+	_, err := s.store.CloseAccountTx(ctx, req.ID)
 	if err != nil {
-		if err == sql.ErrNoRows {
+		switch err {
+		case db.ErrAccountHasBalance, db.ErrAccountNotActive:
+			ctx.JSON(http.StatusForbidden, errorResponse(err))
+		case sql.ErrNoRows:
 			ctx.JSON(http.StatusNotFound, errorResponse(err))
-			return
+		default:
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		}
-
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
 
@@ -0,0 +1,84 @@
+package api
+
+import (
+	"database/sql"
+	"net/http"
+
+	db "github.com/ferueda/simplebank-go/db/sqlc"
+	"github.com/ferueda/simplebank-go/token"
+	"github.com/gin-gonic/gin"
+)
+
+type accountStatusUri struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+type changeAccountStatusRequest struct {
+	Reason string `json:"reason" binding:"required"`
+}
+
+func (s *Server) freezeAccount(ctx *gin.Context) {
+	s.changeAccountStatus(ctx, db.AccountStatusFrozen)
+}
+
+func (s *Server) unfreezeAccount(ctx *gin.Context) {
+	s.changeAccountStatus(ctx, db.AccountStatusActive)
+}
+
+func (s *Server) changeAccountStatus(ctx *gin.Context, status string) {
+	var uri accountStatusUri
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	var req changeAccountStatusRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authPayloadKey).(*token.Payload)
+
+	arg := db.ChangeAccountStatusTxParams{
+		AccountID: uri.ID,
+		Status:    status,
+		Reason:    req.Reason,
+		ChangedBy: authPayload.Username,
+	}
+
+	account, err := s.store.ChangeAccountStatusTx(ctx, arg)
+	if err != nil {
+		switch err {
+		case db.ErrInvalidStatusChange:
+			ctx.JSON(http.StatusForbidden, errorResponse(err))
+		case sql.ErrNoRows:
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+		default:
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, s.newAccountResponse(ctx, account))
+}
+
+func (s *Server) listAccountStatusChanges(ctx *gin.Context) {
+	var uri accountStatusUri
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if _, ok := s.authorizeAccount(ctx, uri.ID, accountRead); !ok {
+		return
+	}
+
+	changes, err := s.store.ListAccountStatusChanges(ctx, uri.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"data": changes})
+}
@@ -0,0 +1,101 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	mockdb "github.com/ferueda/simplebank-go/db/mock"
+	db "github.com/ferueda/simplebank-go/db/sqlc"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFreezeAccount(t *testing.T) {
+	server := newTestServer(t)
+	owner := createTestUser(t, roleCustomer)
+	recipient := createTestUser(t, roleCustomer)
+	admin := createTestUser(t, roleAdmin)
+	account := createTestAccount(t, owner)
+	recipientAcc := createTestAccount(t, recipient)
+
+	freezeURL := fmt.Sprintf("/accounts/%d/freeze", account.ID)
+	body := changeAccountStatusRequest{Reason: "under investigation"}
+
+	recorder := doRequest(t, server, owner, http.MethodPost, freezeURL, body)
+	require.Equal(t, http.StatusForbidden, recorder.Code)
+
+	recorder = doRequest(t, server, admin, http.MethodPost, freezeURL, body)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	recorder = doRequest(t, server, admin, http.MethodPost, freezeURL, body)
+	require.Equal(t, http.StatusForbidden, recorder.Code)
+
+	transfer := transferRequest{
+		FromAccountId: account.ID,
+		ToAccountId:   recipientAcc.ID,
+		Amount:        10,
+		Currency:      account.Currency,
+	}
+
+	recorder = doRequest(t, server, owner, http.MethodPost, "/transfers", transfer)
+	require.Equal(t, http.StatusForbidden, recorder.Code)
+
+	unfreezeURL := fmt.Sprintf("/accounts/%d/unfreeze", account.ID)
+	recorder = doRequest(t, server, admin, http.MethodPost, unfreezeURL, changeAccountStatusRequest{Reason: "cleared"})
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	recorder = doRequest(t, server, owner, http.MethodPost, "/transfers", transfer)
+	require.Equal(t, http.StatusCreated, recorder.Code)
+
+	recorder = doRequest(t, server, admin, http.MethodGet, fmt.Sprintf("/accounts/%d/status_changes", account.ID), nil)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	changes, err := testStore.ListAccountStatusChanges(context.Background(), account.ID)
+	require.NoError(t, err)
+	require.Len(t, changes, 2)
+	require.Equal(t, db.AccountStatusFrozen, changes[0].ToStatus)
+	require.Equal(t, admin.Username, changes[0].ChangedBy)
+}
+
+func TestChangeAccountStatusResponse(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	admin, _ := randomUser(t, roleAdmin)
+	account := db.Account{
+		ID:               1,
+		Owner:            randomString(8),
+		Balance:          1_234,
+		Currency:         "CAD",
+		Status:           db.AccountStatusFrozen,
+		HeldAmount:       234,
+		AvailableBalance: 1_000,
+	}
+
+	store := mockdb.NewMockStore(ctrl)
+	expectAuthenticated(store, admin)
+	expectCurrencies(store)
+	arg := db.ChangeAccountStatusTxParams{
+		AccountID: account.ID,
+		Status:    db.AccountStatusFrozen,
+		Reason:    "under investigation",
+		ChangedBy: admin.Username,
+	}
+	store.EXPECT().ChangeAccountStatusTx(gomock.Any(), gomock.Eq(arg)).Times(1).Return(account, nil)
+
+	server := newTestServerWithStore(t, store)
+	body := changeAccountStatusRequest{Reason: arg.Reason}
+	recorder := doRequest(t, server, admin, http.MethodPost, fmt.Sprintf("/accounts/%d/freeze", account.ID), body)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	// The account is rendered like every other account endpoint renders it.
+	var rsp map[string]interface{}
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+	require.Equal(t, db.AccountStatusFrozen, rsp["status"])
+	require.Equal(t, float64(234), rsp["held_amount"])
+	require.Equal(t, "12.34", rsp["display_balance"])
+	require.Equal(t, "10.00", rsp["display_available_balance"])
+}
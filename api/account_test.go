@@ -0,0 +1,172 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	mockdb "github.com/ferueda/simplebank-go/db/mock"
+	db "github.com/ferueda/simplebank-go/db/sqlc"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+// missingID is never handed out by an id sequence in tests.
+const missingID = int64(1) << 62
+
+func TestCreateAccountCurrency(t *testing.T) {
+	server := newTestServer(t)
+	owner := createTestUser(t, roleCustomer)
+
+	testCases := []struct {
+		name     string
+		currency string
+		status   int
+	}{
+		{name: "Enabled", currency: "CAD", status: http.StatusCreated},
+		{name: "Disabled", currency: "JPY", status: http.StatusBadRequest},
+		{name: "Unknown", currency: "XYZ", status: http.StatusBadRequest},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			body := createAccountRequest{Currency: tc.currency}
+			recorder := doRequest(t, server, owner, http.MethodPost, "/accounts", body)
+			require.Equal(t, tc.status, recorder.Code)
+		})
+	}
+}
+
+// TestCreateAccountCurrencyPerServer checks that each server validates
+// currencies against its own store, even when another one is built later.
+func TestCreateAccountCurrencyPerServer(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	user, _ := randomUser(t, roleCustomer)
+
+	enabled := mockdb.NewMockStore(ctrl)
+	expectAuthenticated(enabled, user)
+	enabled.EXPECT().ListCurrencies(gomock.Any()).AnyTimes().Return([]db.Currency{{Code: "CAD", MinorUnits: 2, Enabled: true}}, nil)
+	enabled.EXPECT().CreateAccount(gomock.Any(), gomock.Any()).Times(1).Return(db.Account{Owner: user.Username, Currency: "CAD"}, nil)
+
+	disabled := mockdb.NewMockStore(ctrl)
+	expectAuthenticated(disabled, user)
+	disabled.EXPECT().ListCurrencies(gomock.Any()).AnyTimes().Return([]db.Currency{{Code: "CAD", MinorUnits: 2, Enabled: false}}, nil)
+	disabled.EXPECT().CreateAccount(gomock.Any(), gomock.Any()).Times(0)
+
+	enabledServer := newTestServerWithStore(t, enabled)
+	disabledServer := newTestServerWithStore(t, disabled)
+
+	body := createAccountRequest{Currency: "CAD"}
+	recorder := doRequest(t, enabledServer, user, http.MethodPost, "/accounts", body)
+	require.Equal(t, http.StatusCreated, recorder.Code)
+
+	recorder = doRequest(t, disabledServer, user, http.MethodPost, "/accounts", body)
+	require.Equal(t, http.StatusBadRequest, recorder.Code)
+}
+
+func TestGetAccountDisplayBalance(t *testing.T) {
+	server := newTestServer(t)
+	owner := createTestUser(t, roleCustomer)
+
+	testCases := []struct {
+		currency string
+		balance  int64
+		display  string
+	}{
+		{currency: "CAD", balance: 1_234, display: "12.34"},
+		{currency: "JPY", balance: 1_234, display: "1234"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.currency, func(t *testing.T) {
+			account, err := testStore.CreateAccount(context.Background(), db.CreateAccountParams{
+				Owner:    owner.Username,
+				Balance:  tc.balance,
+				Currency: tc.currency,
+			})
+			require.NoError(t, err)
+
+			url := fmt.Sprintf("/accounts/%d", account.ID)
+			recorder := doRequest(t, server, owner, http.MethodGet, url, nil)
+			require.Equal(t, http.StatusOK, recorder.Code)
+
+			var body struct {
+				Balance        int64  `json:"balance"`
+				DisplayBalance string `json:"display_balance"`
+			}
+			require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &body))
+			require.Equal(t, tc.balance, body.Balance)
+			require.Equal(t, tc.display, body.DisplayBalance)
+		})
+	}
+}
+
+func TestGetAccountOwnership(t *testing.T) {
+	server := newTestServer(t)
+	owner := createTestUser(t, roleCustomer)
+	other := createTestUser(t, roleCustomer)
+	banker := createTestUser(t, roleBanker)
+	account := createTestAccount(t, owner)
+
+	testCases := []struct {
+		name      string
+		user      db.User
+		accountID int64
+		status    int
+	}{
+		{name: "Owner", user: owner, accountID: account.ID, status: http.StatusOK},
+		{name: "OtherUser", user: other, accountID: account.ID, status: http.StatusForbidden},
+		{name: "Banker", user: banker, accountID: account.ID, status: http.StatusOK},
+		{name: "NotFound", user: owner, accountID: missingID, status: http.StatusNotFound},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			url := fmt.Sprintf("/accounts/%d", tc.accountID)
+			recorder := doRequest(t, server, tc.user, http.MethodGet, url, nil)
+			require.Equal(t, tc.status, recorder.Code)
+		})
+	}
+}
+
+func TestDeleteAccountOwnership(t *testing.T) {
+	server := newTestServer(t)
+	owner := createTestUser(t, roleCustomer)
+	other := createTestUser(t, roleCustomer)
+	banker := createTestUser(t, roleBanker)
+	account := createTestAccount(t, owner)
+	url := fmt.Sprintf("/accounts/%d", account.ID)
+
+	// Staff may read other users' accounts but not delete them.
+	for _, user := range []db.User{other, banker} {
+		recorder := doRequest(t, server, user, http.MethodDelete, url, nil)
+		require.Equal(t, http.StatusForbidden, recorder.Code)
+
+		_, err := testStore.GetAccount(context.Background(), account.ID)
+		require.NoError(t, err)
+	}
+
+	recorder := doRequest(t, server, owner, http.MethodDelete, fmt.Sprintf("/accounts/%d", missingID), nil)
+	require.Equal(t, http.StatusNotFound, recorder.Code)
+
+	// Only empty accounts can be closed.
+	recorder = doRequest(t, server, owner, http.MethodDelete, url, nil)
+	require.Equal(t, http.StatusForbidden, recorder.Code)
+
+	_, err := testStore.UpdateAccount(context.Background(), db.UpdateAccountParams{
+		ID:      account.ID,
+		Balance: 0,
+	})
+	require.NoError(t, err)
+
+	recorder = doRequest(t, server, owner, http.MethodDelete, url, nil)
+	require.Equal(t, http.StatusNoContent, recorder.Code)
+
+	account, err = testStore.GetAccount(context.Background(), account.ID)
+	require.NoError(t, err)
+	require.Equal(t, db.AccountStatusClosed, account.Status)
+}
@@ -0,0 +1,266 @@
+package api
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	db "github.com/ferueda/simplebank-go/db/sqlc"
+	"github.com/ferueda/simplebank-go/token"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	scopeRead  = "read"
+	scopeWrite = "write"
+)
+
+var (
+	errInvalidAPIKey     = errors.New("invalid api key")
+	errRevokedAPIKey     = errors.New("api key has been revoked")
+	errExpiredAPIKey     = errors.New("api key has expired")
+	errInsufficientScope = errors.New("api key scopes do not allow this request")
+	errAPIKeyNotAllowed  = errors.New("this resource cannot be accessed with an api key")
+)
+
+// newAPIKey returns a key of the form <prefix>.<secret> along with its
+// prefix and the hash of its secret. The prefix identifies the key in
+// storage, only the secret hash is kept.
+func newAPIKey() (key, prefix, hashedSecret string, err error) {
+	b := make([]byte, 6)
+	if _, err = rand.Read(b); err != nil {
+		return "", "", "", err
+	}
+	prefix = hex.EncodeToString(b)
+
+	secret, hashedSecret, err := newOneTimeToken()
+	if err != nil {
+		return "", "", "", err
+	}
+
+	return prefix + "." + secret, prefix, hashedSecret, nil
+}
+
+// verifyAPIKey looks up the key and checks it is still usable. It returns
+// the key record and a payload standing in for an access token so handlers
+// can treat both kinds of credentials alike.
+func verifyAPIKey(ctx *gin.Context, store db.Store, key string) (db.ApiKey, *token.Payload, error) {
+	parts := strings.SplitN(key, ".", 2)
+	if len(parts) != 2 {
+		return db.ApiKey{}, nil, errInvalidAPIKey
+	}
+	prefix, secret := parts[0], parts[1]
+
+	apiKey, err := store.GetApiKeyByPrefix(ctx, prefix)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return apiKey, nil, errInvalidAPIKey
+		}
+		return apiKey, nil, err
+	}
+
+	if subtle.ConstantTimeCompare([]byte(hashOneTimeToken(secret)), []byte(apiKey.HashedSecret)) != 1 {
+		return apiKey, nil, errInvalidAPIKey
+	}
+
+	if apiKey.RevokedAt.Valid {
+		return apiKey, nil, errRevokedAPIKey
+	}
+
+	if apiKey.ExpiresAt.Valid && time.Now().After(apiKey.ExpiresAt.Time) {
+		return apiKey, nil, errExpiredAPIKey
+	}
+
+	user, err := store.GetUser(ctx, apiKey.Username)
+	if err != nil {
+		return apiKey, nil, err
+	}
+
+	if err = store.UpdateApiKeyLastUsed(ctx, apiKey.ID); err != nil {
+		return apiKey, nil, err
+	}
+
+	payload := &token.Payload{
+		Username:  user.Username,
+		Role:      user.Role,
+		Type:      token.TokenTypeAccess,
+		IssuedAt:  apiKey.CreatedAt,
+		NotBefore: apiKey.CreatedAt,
+		ExpiredAt: apiKey.ExpiresAt.Time,
+	}
+
+	return apiKey, payload, nil
+}
+
+// hasScopeFor reports whether the key may perform a request with the given
+// method. Read-only keys are limited to safe methods.
+func hasScopeFor(apiKey db.ApiKey, method string) bool {
+	scope := scopeWrite
+	if method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions {
+		scope = scopeRead
+	}
+
+	for _, s := range apiKey.Scopes {
+		if s == scope || s == scopeWrite {
+			return true
+		}
+	}
+	return false
+}
+
+// denyAPIKeys rejects requests authenticated with an api key, for routes
+// that manage the user's credentials. It must run after authMiddleware.
+func denyAPIKeys() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if _, ok := ctx.Get(authAPIKeyKey); ok {
+			ctx.AbortWithStatusJSON(http.StatusForbidden, errorResponse(errAPIKeyNotAllowed))
+			return
+		}
+
+		ctx.Next()
+	}
+}
+
+type createAPIKeyRequest struct {
+	Name      string     `json:"name" binding:"required"`
+	Scopes    []string   `json:"scopes" binding:"required,min=1,dive,oneof=read write"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+type apiKeyResponse struct {
+	ID         int64      `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+type createAPIKeyResponse struct {
+	// Key is only ever returned here; it cannot be recovered later.
+	Key string `json:"key"`
+	apiKeyResponse
+}
+
+func newAPIKeyResponse(apiKey db.ApiKey) apiKeyResponse {
+	return apiKeyResponse{
+		ID:         apiKey.ID,
+		Name:       apiKey.Name,
+		Prefix:     apiKey.Prefix,
+		Scopes:     apiKey.Scopes,
+		LastUsedAt: nullTimePtr(apiKey.LastUsedAt),
+		ExpiresAt:  nullTimePtr(apiKey.ExpiresAt),
+		RevokedAt:  nullTimePtr(apiKey.RevokedAt),
+		CreatedAt:  apiKey.CreatedAt,
+	}
+}
+
+func nullTimePtr(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
+
+func (s *Server) createAPIKey(ctx *gin.Context) {
+	var req createAPIKeyRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	var expiresAt sql.NullTime
+	if req.ExpiresAt != nil {
+		if !req.ExpiresAt.After(time.Now()) {
+			err := errors.New("expires_at must be in the future")
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+			return
+		}
+		expiresAt = sql.NullTime{Time: *req.ExpiresAt, Valid: true}
+	}
+
+	key, prefix, hashedSecret, err := newAPIKey()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authPayloadKey).(*token.Payload)
+
+	arg := db.CreateApiKeyParams{
+		Username:     authPayload.Username,
+		Name:         req.Name,
+		Prefix:       prefix,
+		HashedSecret: hashedSecret,
+		Scopes:       req.Scopes,
+		ExpiresAt:    expiresAt,
+	}
+
+	apiKey, err := s.store.CreateApiKey(ctx, arg)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	resp := createAPIKeyResponse{
+		Key:            key,
+		apiKeyResponse: newAPIKeyResponse(apiKey),
+	}
+	ctx.JSON(http.StatusCreated, resp)
+}
+
+func (s *Server) listAPIKeys(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authPayloadKey).(*token.Payload)
+
+	apiKeys, err := s.store.ListApiKeys(ctx, authPayload.Username)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	resp := make([]apiKeyResponse, len(apiKeys))
+	for i, apiKey := range apiKeys {
+		resp[i] = newAPIKeyResponse(apiKey)
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"data": resp})
+}
+
+type revokeAPIKeyRequest struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+func (s *Server) revokeAPIKey(ctx *gin.Context) {
+	var req revokeAPIKeyRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authPayloadKey).(*token.Payload)
+
+	arg := db.RevokeApiKeyParams{
+		ID:       req.ID,
+		Username: authPayload.Username,
+	}
+
+	_, err := s.store.RevokeApiKey(ctx, arg)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
@@ -0,0 +1,290 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	mockdb "github.com/ferueda/simplebank-go/db/mock"
+	db "github.com/ferueda/simplebank-go/db/sqlc"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHasScopeFor(t *testing.T) {
+	testCases := []struct {
+		scopes  []string
+		method  string
+		allowed bool
+	}{
+		{scopes: []string{scopeRead}, method: http.MethodGet, allowed: true},
+		{scopes: []string{scopeRead}, method: http.MethodHead, allowed: true},
+		{scopes: []string{scopeRead}, method: http.MethodPost, allowed: false},
+		{scopes: []string{scopeRead}, method: http.MethodDelete, allowed: false},
+		{scopes: []string{scopeWrite}, method: http.MethodGet, allowed: true},
+		{scopes: []string{scopeWrite}, method: http.MethodPatch, allowed: true},
+		{scopes: []string{scopeRead, scopeWrite}, method: http.MethodPost, allowed: true},
+		{scopes: []string{}, method: http.MethodGet, allowed: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(fmt.Sprintf("%s/%s", strings.Join(tc.scopes, ","), tc.method), func(t *testing.T) {
+			apiKey := db.ApiKey{Scopes: tc.scopes}
+			require.Equal(t, tc.allowed, hasScopeFor(apiKey, tc.method))
+		})
+	}
+}
+
+func TestAPIKeyAuth(t *testing.T) {
+	user, _ := randomUser(t, roleCustomer)
+
+	testCases := []struct {
+		name       string
+		method     string
+		url        string
+		scopes     []string
+		key        func(key string) string
+		setupKey   func(apiKey *db.ApiKey)
+		buildStubs func(store *mockdb.MockStore, apiKey db.ApiKey)
+		status     int
+	}{
+		{
+			name:   "ReadKey",
+			method: http.MethodGet,
+			url:    "/users/me",
+			scopes: []string{scopeRead},
+			buildStubs: func(store *mockdb.MockStore, apiKey db.ApiKey) {
+				store.EXPECT().UpdateApiKeyLastUsed(gomock.Any(), gomock.Eq(apiKey.ID)).Times(1).Return(nil)
+			},
+			status: http.StatusOK,
+		},
+		{
+			name:   "ReadKeyWriteRequest",
+			method: http.MethodPost,
+			url:    "/accounts",
+			scopes: []string{scopeRead},
+			buildStubs: func(store *mockdb.MockStore, apiKey db.ApiKey) {
+				store.EXPECT().UpdateApiKeyLastUsed(gomock.Any(), gomock.Eq(apiKey.ID)).Times(1).Return(nil)
+				store.EXPECT().CreateAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			status: http.StatusForbidden,
+		},
+		{
+			name:   "CredentialRoute",
+			method: http.MethodPost,
+			url:    "/users/logout",
+			scopes: []string{scopeWrite},
+			buildStubs: func(store *mockdb.MockStore, apiKey db.ApiKey) {
+				store.EXPECT().UpdateApiKeyLastUsed(gomock.Any(), gomock.Eq(apiKey.ID)).Times(1).Return(nil)
+				store.EXPECT().RevokeTokenTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			status: http.StatusForbidden,
+		},
+		{
+			name:   "WrongSecret",
+			method: http.MethodGet,
+			url:    "/users/me",
+			scopes: []string{scopeRead},
+			key: func(key string) string {
+				return key[:strings.Index(key, ".")+1] + randomString(43)
+			},
+			buildStubs: func(store *mockdb.MockStore, apiKey db.ApiKey) {
+				store.EXPECT().UpdateApiKeyLastUsed(gomock.Any(), gomock.Any()).Times(0)
+			},
+			status: http.StatusUnauthorized,
+		},
+		{
+			name:   "Revoked",
+			method: http.MethodGet,
+			url:    "/users/me",
+			scopes: []string{scopeRead},
+			setupKey: func(apiKey *db.ApiKey) {
+				apiKey.RevokedAt = sql.NullTime{Time: time.Now(), Valid: true}
+			},
+			buildStubs: func(store *mockdb.MockStore, apiKey db.ApiKey) {
+				store.EXPECT().UpdateApiKeyLastUsed(gomock.Any(), gomock.Any()).Times(0)
+			},
+			status: http.StatusUnauthorized,
+		},
+		{
+			name:   "Expired",
+			method: http.MethodGet,
+			url:    "/users/me",
+			scopes: []string{scopeRead},
+			setupKey: func(apiKey *db.ApiKey) {
+				apiKey.ExpiresAt = sql.NullTime{Time: time.Now().Add(-time.Minute), Valid: true}
+			},
+			buildStubs: func(store *mockdb.MockStore, apiKey db.ApiKey) {
+				store.EXPECT().UpdateApiKeyLastUsed(gomock.Any(), gomock.Any()).Times(0)
+			},
+			status: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			key, prefix, hashedSecret, err := newAPIKey()
+			require.NoError(t, err)
+
+			apiKey := db.ApiKey{
+				ID:           1,
+				Username:     user.Username,
+				Prefix:       prefix,
+				HashedSecret: hashedSecret,
+				Scopes:       tc.scopes,
+				CreatedAt:    time.Now().Add(-time.Hour),
+			}
+			if tc.setupKey != nil {
+				tc.setupKey(&apiKey)
+			}
+			if tc.key != nil {
+				key = tc.key(key)
+			}
+
+			store := mockdb.NewMockStore(ctrl)
+			store.EXPECT().GetApiKeyByPrefix(gomock.Any(), gomock.Eq(prefix)).Times(1).Return(apiKey, nil)
+			store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).AnyTimes().Return(user, nil)
+			store.EXPECT().IsTokenRevoked(gomock.Any(), gomock.Any()).Times(0)
+			tc.buildStubs(store, apiKey)
+
+			server := newTestServerWithStore(t, store)
+
+			req := newRequest(t, tc.method, tc.url, nil)
+			req.Header.Set(authHeaderKey, fmt.Sprintf("%s %s", authTypeAPIKey, key))
+
+			recorder := httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, req)
+			require.Equal(t, tc.status, recorder.Code)
+		})
+	}
+}
+
+func TestCreateAPIKey(t *testing.T) {
+	user, _ := randomUser(t, roleCustomer)
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: gin.H{"name": "ci", "scopes": []string{scopeRead}},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateApiKey(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.CreateApiKeyParams) (db.ApiKey, error) {
+						require.Equal(t, user.Username, arg.Username)
+						require.Equal(t, []string{scopeRead}, arg.Scopes)
+						require.False(t, arg.ExpiresAt.Valid)
+						return db.ApiKey{
+							ID:           1,
+							Username:     arg.Username,
+							Name:         arg.Name,
+							Prefix:       arg.Prefix,
+							HashedSecret: arg.HashedSecret,
+							Scopes:       arg.Scopes,
+						}, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusCreated, recorder.Code)
+
+				var rsp struct {
+					Key    string `json:"key"`
+					Prefix string `json:"prefix"`
+				}
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.True(t, strings.HasPrefix(rsp.Key, rsp.Prefix+"."))
+			},
+		},
+		{
+			name: "UnknownScope",
+			body: gin.H{"name": "ci", "scopes": []string{"admin"}},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateApiKey(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "ExpiresInThePast",
+			body: gin.H{"name": "ci", "scopes": []string{scopeRead}, "expires_at": time.Now().Add(-time.Hour)},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateApiKey(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			expectAuthenticated(store, user)
+			tc.buildStubs(store)
+
+			server := newTestServerWithStore(t, store)
+			recorder := doRequest(t, server, user, http.MethodPost, "/users/api_keys", tc.body)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestRevokeAPIKey(t *testing.T) {
+	user, _ := randomUser(t, roleCustomer)
+
+	testCases := []struct {
+		name       string
+		buildStubs func(store *mockdb.MockStore)
+		status     int
+	}{
+		{
+			name: "OK",
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.RevokeApiKeyParams{ID: 1, Username: user.Username}
+				store.EXPECT().RevokeApiKey(gomock.Any(), gomock.Eq(arg)).Times(1).Return(db.ApiKey{}, nil)
+			},
+			status: http.StatusNoContent,
+		},
+		{
+			name: "NotFound",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().RevokeApiKey(gomock.Any(), gomock.Any()).Times(1).Return(db.ApiKey{}, sql.ErrNoRows)
+			},
+			status: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			expectAuthenticated(store, user)
+			tc.buildStubs(store)
+
+			server := newTestServerWithStore(t, store)
+			recorder := doRequest(t, server, user, http.MethodDelete, "/users/api_keys/1", nil)
+			require.Equal(t, tc.status, recorder.Code)
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	db "github.com/ferueda/simplebank-go/db/sqlc"
+	"github.com/ferueda/simplebank-go/token"
+	"github.com/gin-gonic/gin"
+)
+
+// accountAccess is the kind of access a request needs to an account.
+type accountAccess int
+
+const (
+	// accountRead lets bankers and admins inspect accounts of any user.
+	accountRead accountAccess = iota
+	// accountWrite is reserved to the account owner.
+	accountWrite
+)
+
+var (
+	errAccountNotOwned  = errors.New("account does not belong to the authenticated user")
+	errTransferNotOwned = errors.New("transfer does not involve an account of the authenticated user")
+
+	errScheduledTransferNotOwned = errors.New("scheduled transfer does not belong to the authenticated user")
+	errPendingTransferNotOwned   = errors.New("pending transfer is not from an account of the authenticated user")
+)
+
+func canAccessAccount(payload *token.Payload, account db.Account, access accountAccess) bool {
+	if account.Owner == payload.Username {
+		return true
+	}
+	return access == accountRead && hasRole(payload, roleBanker, roleAdmin)
+}
+
+// authorizeAccount loads an account and checks the caller may access it. When
+// it returns false the error response has already been written.
+func (s *Server) authorizeAccount(ctx *gin.Context, accountID int64, access accountAccess) (db.Account, bool) {
+	account, err := s.store.GetAccount(ctx, accountID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return account, false
+		}
+
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return account, false
+	}
+
+	authPayload := ctx.MustGet(authPayloadKey).(*token.Payload)
+	if !canAccessAccount(authPayload, account, access) {
+		ctx.JSON(http.StatusForbidden, errorResponse(errAccountNotOwned))
+		return account, false
+	}
+
+	return account, true
+}
+
+// authorizeTransfer checks the caller may read a transfer, which requires
+// read access to at least one of its two accounts. When it returns false the
+// error response has already been written.
+func (s *Server) authorizeTransfer(ctx *gin.Context, transfer db.Transfer) bool {
+	authPayload := ctx.MustGet(authPayloadKey).(*token.Payload)
+
+	for _, accountID := range []int64{transfer.FromAccountID, transfer.ToAccountID} {
+		account, err := s.store.GetAccount(ctx, accountID)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return false
+		}
+
+		if canAccessAccount(authPayload, account, accountRead) {
+			return true
+		}
+	}
+
+	ctx.JSON(http.StatusForbidden, errorResponse(errTransferNotOwned))
+	return false
+}
+
+// authorizeScheduledTransfer loads a scheduled transfer and checks the
+// caller may access it, with the same rules as its source account. When it
+// returns false the error response has already been written.
+func (s *Server) authorizeScheduledTransfer(ctx *gin.Context, id int64, access accountAccess) (db.ScheduledTransfer, bool) {
+	scheduled, err := s.store.GetScheduledTransfer(ctx, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return scheduled, false
+		}
+
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return scheduled, false
+	}
+
+	authPayload := ctx.MustGet(authPayloadKey).(*token.Payload)
+	if scheduled.Owner != authPayload.Username && !(access == accountRead && hasRole(authPayload, roleBanker, roleAdmin)) {
+		ctx.JSON(http.StatusForbidden, errorResponse(errScheduledTransferNotOwned))
+		return scheduled, false
+	}
+
+	return scheduled, true
+}
+
+// authorizePendingTransfer loads a pending transfer and checks the caller
+// may access it, with the same rules as its source account. When it returns
+// false the error response has already been written.
+func (s *Server) authorizePendingTransfer(ctx *gin.Context, id int64, access accountAccess) (db.PendingTransfer, bool) {
+	pending, err := s.store.GetPendingTransfer(ctx, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return pending, false
+		}
+
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return pending, false
+	}
+
+	account, err := s.store.GetAccount(ctx, pending.FromAccountID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return pending, false
+	}
+
+	authPayload := ctx.MustGet(authPayloadKey).(*token.Payload)
+	if !canAccessAccount(authPayload, account, access) {
+		ctx.JSON(http.StatusForbidden, errorResponse(errPendingTransferNotOwned))
+		return pending, false
+	}
+
+	return pending, true
+}
@@ -0,0 +1,110 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	db "github.com/ferueda/simplebank-go/db/sqlc"
+	"github.com/gin-gonic/gin"
+)
+
+// currencyRegistryTTL is how long the currencies table is cached, so that
+// enabling a currency takes effect without a restart.
+const currencyRegistryTTL = time.Minute
+
+// defaultMinorUnits is used to format amounts in a currency missing from
+// the registry.
+const defaultMinorUnits = 2
+
+var (
+	errUnknownCurrency     = errors.New("unknown currency")
+	errUnsupportedCurrency = errors.New("unsupported currency")
+)
+
+// currencyRegistry caches the currencies table.
+type currencyRegistry struct {
+	store db.Store
+
+	mu         sync.Mutex
+	currencies map[string]db.Currency
+	loadedAt   time.Time
+}
+
+func newCurrencyRegistry(store db.Store) *currencyRegistry {
+	return &currencyRegistry{store: store}
+}
+
+// get returns a currency by code, reloading the table when the cached copy
+// is stale.
+func (r *currencyRegistry) get(ctx context.Context, code string) (db.Currency, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.currencies == nil || time.Since(r.loadedAt) > currencyRegistryTTL {
+		currencies, err := r.store.ListCurrencies(ctx)
+		if err != nil {
+			return db.Currency{}, false, err
+		}
+
+		r.currencies = make(map[string]db.Currency, len(currencies))
+		for _, c := range currencies {
+			r.currencies[c.Code] = c
+		}
+		r.loadedAt = time.Now()
+	}
+
+	c, ok := r.currencies[code]
+	return c, ok, nil
+}
+
+// minorUnits returns the exponent of a currency. It fails with
+// errUnknownCurrency if the currency is not in the registry, so that amounts
+// are never scaled by a guess.
+func (r *currencyRegistry) minorUnits(ctx context.Context, code string) (int32, error) {
+	c, ok, err := r.get(ctx, code)
+	if err != nil {
+		return 0, err
+	}
+	if !ok {
+		return 0, fmt.Errorf("%w %s", errUnknownCurrency, code)
+	}
+
+	return c.MinorUnits, nil
+}
+
+// displayMinorUnits is minorUnits for formatting only: it falls back to
+// defaultMinorUnits if the currency is unknown or the registry cannot be
+// loaded.
+func (r *currencyRegistry) displayMinorUnits(ctx context.Context, code string) int32 {
+	minorUnits, err := r.minorUnits(ctx, code)
+	if err != nil {
+		if !errors.Is(err, errUnknownCurrency) {
+			log.Printf("cannot load currencies: %v", err)
+		}
+		return defaultMinorUnits
+	}
+
+	return minorUnits
+}
+
+// checkCurrency reports whether code is an enabled currency. When it returns
+// false the error response has already been written.
+func (s *Server) checkCurrency(ctx *gin.Context, code string) bool {
+	c, ok, err := s.currencies.get(ctx, code)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return false
+	}
+
+	if !ok || !c.Enabled {
+		ctx.JSON(http.StatusBadRequest, errorResponse(fmt.Errorf("%w %s", errUnsupportedCurrency, code)))
+		return false
+	}
+
+	return true
+}
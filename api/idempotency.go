@@ -0,0 +1,77 @@
+package api
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	db "github.com/ferueda/simplebank-go/db/sqlc"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	idempotencyKeyHeader    = "Idempotency-Key"
+	maxIdempotencyKeyLength = 255
+)
+
+var (
+	errInvalidIdempotencyKey  = errors.New("idempotency key must be at most 255 characters")
+	errIdempotencyKeyMismatch = errors.New("idempotency key was already used with a different request")
+)
+
+// idempotencyParams reads the Idempotency-Key header of a request. It returns
+// nil if the client did not send one.
+func (s *Server) idempotencyParams(ctx *gin.Context, username string, req interface{}) (*db.IdempotencyParams, error) {
+	key := ctx.GetHeader(idempotencyKeyHeader)
+	if key == "" {
+		return nil, nil
+	}
+
+	if len(key) > maxIdempotencyKeyLength {
+		return nil, errInvalidIdempotencyKey
+	}
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(body)
+
+	return &db.IdempotencyParams{
+		Username:    username,
+		Key:         key,
+		RequestHash: hex.EncodeToString(sum[:]),
+		ExpiresAt:   time.Now().Add(s.config.IdempotencyKeyDuration),
+	}, nil
+}
+
+// replayIdempotentRequest answers a request with respond, given the response
+// stored under its idempotency key, or with 422 if the key was used for a
+// different request. It returns false, without writing anything, if the key
+// is unknown or has expired.
+func (s *Server) replayIdempotentRequest(ctx *gin.Context, arg db.IdempotencyParams, respond func(response json.RawMessage)) bool {
+	record, err := s.store.GetIdempotencyKey(ctx, db.GetIdempotencyKeyParams{
+		Username: arg.Username,
+		Key:      arg.Key,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return false
+		}
+
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return true
+	}
+
+	if record.RequestHash != arg.RequestHash {
+		ctx.JSON(http.StatusUnprocessableEntity, errorResponse(errIdempotencyKeyMismatch))
+		return true
+	}
+
+	respond(record.Response)
+	return true
+}
@@ -0,0 +1,131 @@
+package api
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	db "github.com/ferueda/simplebank-go/db/sqlc"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	throttleScopeUsername = "username"
+	throttleScopeClientIP = "client_ip"
+)
+
+type loginThrottleKey struct {
+	scope       string
+	subject     string
+	maxAttempts int32
+}
+
+func (s *Server) loginThrottleKeys(ctx *gin.Context, username string) []loginThrottleKey {
+	return []loginThrottleKey{
+		{throttleScopeUsername, username, s.config.LoginMaxAttempts},
+		{throttleScopeClientIP, ctx.ClientIP(), s.config.LoginMaxAttemptsPerIP},
+	}
+}
+
+// checkLoginThrottle responds with 429 and returns false while either the
+// username or the client IP is backing off or locked out.
+func (s *Server) checkLoginThrottle(ctx *gin.Context, username string) bool {
+	for _, key := range s.loginThrottleKeys(ctx, username) {
+		throttle, err := s.store.GetLoginThrottle(ctx, db.GetLoginThrottleParams{
+			Scope:   key.scope,
+			Subject: key.subject,
+		})
+		if err != nil {
+			if err == sql.ErrNoRows {
+				continue
+			}
+
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return false
+		}
+
+		if wait := time.Until(throttle.LockedUntil); wait > 0 {
+			err := fmt.Errorf("too many failed login attempts, retry in %s", wait.Round(time.Second))
+			ctx.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			ctx.JSON(http.StatusTooManyRequests, errorResponse(err))
+			return false
+		}
+	}
+
+	return true
+}
+
+// recordLoginFailure counts a failed attempt against the username and the
+// client IP and pushes back the next allowed attempt for both.
+func (s *Server) recordLoginFailure(ctx *gin.Context, username string) error {
+	for _, key := range s.loginThrottleKeys(ctx, username) {
+		throttle, err := s.store.RecordLoginFailure(ctx, db.RecordLoginFailureParams{
+			Scope:       key.scope,
+			Subject:     key.subject,
+			ResetBefore: time.Now().Add(-s.config.LoginLockoutDuration),
+		})
+		if err != nil {
+			return err
+		}
+
+		delay := loginBackoff(throttle.FailedAttempts, key.maxAttempts, s.config.LoginBackoffBase, s.config.LoginLockoutDuration)
+
+		_, err = s.store.UpdateLoginThrottleLockedUntil(ctx, db.UpdateLoginThrottleLockedUntilParams{
+			Scope:       key.scope,
+			Subject:     key.subject,
+			LockedUntil: time.Now().Add(delay),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *Server) resetLoginThrottle(ctx *gin.Context, username string) error {
+	return s.store.DeleteLoginThrottle(ctx, db.DeleteLoginThrottleParams{
+		Scope:   throttleScopeUsername,
+		Subject: username,
+	})
+}
+
+// loginBackoff doubles the wait after every failed attempt and locks the
+// subject out once maxAttempts is reached.
+func loginBackoff(attempts, maxAttempts int32, base, lockout time.Duration) time.Duration {
+	if attempts >= maxAttempts {
+		return lockout
+	}
+
+	delay := base
+	for i := int32(1); i < attempts && delay < lockout; i++ {
+		delay *= 2
+	}
+
+	if delay > lockout {
+		return lockout
+	}
+	return delay
+}
+
+type unlockUserUri struct {
+	Username string `uri:"username" binding:"required,alphanum"`
+}
+
+func (s *Server) unlockUser(ctx *gin.Context) {
+	var uri unlockUserUri
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if err := s.resetLoginThrottle(ctx, uri.Username); err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
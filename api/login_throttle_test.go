@@ -0,0 +1,137 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	mockdb "github.com/ferueda/simplebank-go/db/mock"
+	db "github.com/ferueda/simplebank-go/db/sqlc"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoginBackoff(t *testing.T) {
+	base := time.Second
+	lockout := 15 * time.Minute
+
+	testCases := []struct {
+		attempts int32
+		delay    time.Duration
+	}{
+		{attempts: 1, delay: time.Second},
+		{attempts: 2, delay: 2 * time.Second},
+		{attempts: 3, delay: 4 * time.Second},
+		{attempts: 4, delay: 8 * time.Second},
+		{attempts: 5, delay: lockout},
+		{attempts: 9, delay: lockout},
+	}
+
+	for _, tc := range testCases {
+		t.Run(fmt.Sprint(tc.attempts), func(t *testing.T) {
+			require.Equal(t, tc.delay, loginBackoff(tc.attempts, 5, base, lockout))
+		})
+	}
+
+	// The doubling is capped at the lockout even below maxAttempts.
+	require.Equal(t, time.Minute, loginBackoff(10, 20, time.Second, time.Minute))
+}
+
+func TestLoginUserThrottled(t *testing.T) {
+	user, password := randomUser(t, roleCustomer)
+
+	testCases := []struct {
+		name       string
+		buildStubs func(store *mockdb.MockStore)
+		status     int
+	}{
+		{
+			name: "LockedOut",
+			buildStubs: func(store *mockdb.MockStore) {
+				throttle := db.LoginThrottle{FailedAttempts: 5, LockedUntil: time.Now().Add(time.Minute)}
+				store.EXPECT().GetLoginThrottle(gomock.Any(), gomock.Any()).Times(1).Return(throttle, nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+			},
+			status: http.StatusTooManyRequests,
+		},
+		{
+			name: "LockExpired",
+			buildStubs: func(store *mockdb.MockStore) {
+				throttle := db.LoginThrottle{FailedAttempts: 5, LockedUntil: time.Now().Add(-time.Second)}
+				store.EXPECT().GetLoginThrottle(gomock.Any(), gomock.Any()).Times(2).Return(throttle, nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().GetUserTotp(gomock.Any(), gomock.Any()).Times(1).Return(db.UserTotp{}, nil)
+				store.EXPECT().DeleteLoginThrottle(gomock.Any(), gomock.Any()).Times(1).Return(nil)
+				store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(1).Return(db.Session{}, nil)
+			},
+			status: http.StatusOK,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServerWithStore(t, store)
+			body := loginUserRequest{Username: user.Username, Password: password}
+			recorder := doPublicRequest(t, server, http.MethodPost, "/users/login", body)
+			require.Equal(t, tc.status, recorder.Code)
+
+			if tc.status == http.StatusTooManyRequests {
+				require.NotEmpty(t, recorder.Header().Get("Retry-After"))
+			}
+		})
+	}
+}
+
+func TestUnlockUser(t *testing.T) {
+	admin, _ := randomUser(t, roleAdmin)
+	banker, _ := randomUser(t, roleBanker)
+	user, _ := randomUser(t, roleCustomer)
+
+	testCases := []struct {
+		name       string
+		caller     db.User
+		buildStubs func(store *mockdb.MockStore)
+		status     int
+	}{
+		{
+			name:   "OK",
+			caller: admin,
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.DeleteLoginThrottleParams{Scope: throttleScopeUsername, Subject: user.Username}
+				store.EXPECT().DeleteLoginThrottle(gomock.Any(), gomock.Eq(arg)).Times(1).Return(nil)
+			},
+			status: http.StatusNoContent,
+		},
+		{
+			name:   "NotAdmin",
+			caller: banker,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().DeleteLoginThrottle(gomock.Any(), gomock.Any()).Times(0)
+			},
+			status: http.StatusForbidden,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			expectAuthenticated(store, tc.caller)
+			tc.buildStubs(store)
+
+			server := newTestServerWithStore(t, store)
+			url := fmt.Sprintf("/users/%s/lockout", user.Username)
+			recorder := doRequest(t, server, tc.caller, http.MethodDelete, url, nil)
+			require.Equal(t, tc.status, recorder.Code)
+		})
+	}
+}
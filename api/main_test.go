@@ -0,0 +1,228 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	mockdb "github.com/ferueda/simplebank-go/db/mock"
+	db "github.com/ferueda/simplebank-go/db/sqlc"
+	"github.com/ferueda/simplebank-go/exchange"
+	"github.com/ferueda/simplebank-go/mail"
+	"github.com/ferueda/simplebank-go/token"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/joho/godotenv"
+	_ "github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	alphabet = "abcdefghijklmnopqrstuvwxyz"
+)
+
+var dbAddr string
+var dbDriver string
+
+func init() {
+	env := os.Getenv("ENV")
+	if env == "dev" || env == "" {
+		err := godotenv.Load("../.env")
+		if err != nil {
+			log.Fatal("Error loading .env file", err)
+		}
+	}
+
+	dbAddr = os.Getenv("DB_HOST")
+	dbDriver = os.Getenv("DB_DRIVER")
+
+	rand.Seed(time.Now().UnixNano())
+}
+
+var testStore db.Store
+
+func TestMain(m *testing.M) {
+	conn, err := sql.Open(dbDriver, dbAddr)
+	if err != nil {
+		log.Fatal("cannot connect to db:", err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	testStore = db.NewStore(conn)
+	os.Exit(m.Run())
+}
+
+func newTestServer(t *testing.T) *Server {
+	return newTestServerWithStore(t, testStore)
+}
+
+// newTestServerWithStore builds a test server on top of store, which lets
+// handler tests run against a mock instead of the test database.
+func newTestServerWithStore(t *testing.T, store db.Store) *Server {
+	tm, err := token.NewPasetoMaker(randomString(32))
+	require.NoError(t, err)
+
+	config := Config{
+		AccessTokenDuration:  time.Minute,
+		RefreshTokenDuration: time.Hour,
+		MFAChallengeDuration: time.Minute,
+		TOTPEncryptionKey:    randomString(32),
+		TOTPIssuer:           "SimpleBank",
+
+		LoginMaxAttempts:      5,
+		LoginMaxAttemptsPerIP: 20,
+		LoginBackoffBase:      time.Second,
+		LoginLockoutDuration:  time.Minute,
+
+		PasswordResetTokenDuration:     time.Hour,
+		EmailVerificationTokenDuration: time.Hour,
+		IdempotencyKeyDuration:         time.Hour,
+		ExchangeSpread:                 "0.01",
+		HoldDuration:                   time.Hour,
+	}
+
+	rates, err := exchange.NewStaticRateProvider(map[string]string{
+		"CAD/USD": "0.75",
+		"CAD/JPY": "110",
+	})
+	require.NoError(t, err)
+
+	server, err := NewServer(config, store, tm, mail.NewMemorySender(), rates)
+	require.NoError(t, err)
+
+	return server
+}
+
+// doRequest sends a request to the server authenticated as user. A non-nil
+// body is sent as JSON.
+func doRequest(t *testing.T, server *Server, user db.User, method, url string, body interface{}) *httptest.ResponseRecorder {
+	accessToken, _, err := server.tokenMaker.CreateToken(user.Username, user.Role, token.TokenTypeAccess, time.Minute)
+	require.NoError(t, err)
+
+	return doRequestWithToken(t, server, accessToken, method, url, body)
+}
+
+// doRequestWithToken sends a request to the server authenticated with a
+// bearer token. A non-nil body is sent as JSON.
+func doRequestWithToken(t *testing.T, server *Server, accessToken string, method, url string, body interface{}) *httptest.ResponseRecorder {
+	req := newRequest(t, method, url, body)
+	req.Header.Set(authHeaderKey, fmt.Sprintf("%s %s", authTypeBearer, accessToken))
+
+	recorder := httptest.NewRecorder()
+	server.router.ServeHTTP(recorder, req)
+	return recorder
+}
+
+// doPublicRequest sends an unauthenticated request to the server. A non-nil
+// body is sent as JSON.
+func doPublicRequest(t *testing.T, server *Server, method, url string, body interface{}) *httptest.ResponseRecorder {
+	req := newRequest(t, method, url, body)
+
+	recorder := httptest.NewRecorder()
+	server.router.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func newRequest(t *testing.T, method, url string, body interface{}) *http.Request {
+	var reqBody io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		require.NoError(t, err)
+		reqBody = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, url, reqBody)
+	require.NoError(t, err)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return req
+}
+
+func createTestUser(t *testing.T, role string) db.User {
+	hashedPass, err := db.HashPassword(randomString(8))
+	require.NoError(t, err)
+
+	user, err := testStore.CreateUser(context.Background(), db.CreateUserParams{
+		Username:       randomString(8),
+		HashedPassword: hashedPass,
+		FullName:       randomString(6),
+		Email:          randomString(6) + "@" + randomString(4) + ".com",
+	})
+	require.NoError(t, err)
+
+	if role != user.Role {
+		user, err = testStore.UpdateUserRole(context.Background(), db.UpdateUserRoleParams{
+			Username: user.Username,
+			Role:     role,
+		})
+		require.NoError(t, err)
+	}
+
+	return user
+}
+
+// randomUser returns a user that only exists in memory, for tests that run
+// against a mock store, along with its password.
+func randomUser(t *testing.T, role string) (db.User, string) {
+	password := randomString(8)
+	hashedPass, err := db.HashPassword(password)
+	require.NoError(t, err)
+
+	user := db.User{
+		Username:          randomString(8),
+		Role:              role,
+		HashedPassword:    hashedPass,
+		FullName:          randomString(6),
+		Email:             randomString(6) + "@" + randomString(4) + ".com",
+		PasswordChangedAt: time.Now().Add(-time.Hour),
+		CreatedAt:         time.Now().Add(-time.Hour),
+	}
+
+	return user, password
+}
+
+func createTestAccount(t *testing.T, owner db.User) db.Account {
+	account, err := testStore.CreateAccount(context.Background(), db.CreateAccountParams{
+		Owner:    owner.Username,
+		Balance:  1_000,
+		Currency: "CAD",
+	})
+	require.NoError(t, err)
+
+	return account
+}
+
+// expectCurrencies stubs the currencies table the registry loads, matching
+// the one the migrations seed.
+func expectCurrencies(store *mockdb.MockStore) {
+	currencies := []db.Currency{
+		{Code: "CAD", Name: "Canadian Dollar", MinorUnits: 2, Enabled: true},
+		{Code: "JPY", Name: "Yen", MinorUnits: 0, Enabled: false},
+		{Code: "USD", Name: "US Dollar", MinorUnits: 2, Enabled: true},
+	}
+	store.EXPECT().ListCurrencies(gomock.Any()).AnyTimes().Return(currencies, nil)
+}
+
+func randomString(n int) string {
+	var sb strings.Builder
+	k := len(alphabet)
+
+	for i := 0; i < n; i++ {
+		c := alphabet[rand.Intn(k)]
+		sb.WriteByte(c)
+	}
+
+	return sb.String()
+}
@@ -1,11 +1,14 @@
 package api
 
 import (
+	"context"
+	"database/sql"
 	"errors"
 	"fmt"
 	"net/http"
 	"strings"
 
+	db "github.com/ferueda/simplebank-go/db/sqlc"
 	"github.com/ferueda/simplebank-go/token"
 	"github.com/gin-gonic/gin"
 )
@@ -13,10 +16,26 @@ import (
 const (
 	authHeaderKey  = "authorization"
 	authTypeBearer = "bearer"
+	authTypeAPIKey = "apikey"
 	authPayloadKey = "authorization_payload"
+	authAPIKeyKey  = "authorization_api_key"
 )
 
-func authMiddleware(tokenMaker token.Maker) gin.HandlerFunc {
+const (
+	roleCustomer = "customer"
+	roleBanker   = "banker"
+	roleAdmin    = "admin"
+)
+
+var (
+	errRevokedToken    = errors.New("token has been revoked")
+	errPasswordChanged = errors.New("token was issued before the last password change")
+	errRoleChanged     = errors.New("token role no longer matches the user role")
+	errUserDeleted     = errors.New("user has been deleted")
+	errNotAccessToken  = errors.New("token is not an access token")
+)
+
+func authMiddleware(tokenMaker token.Maker, store db.Store) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		authHeader := ctx.GetHeader(authHeaderKey)
 		if len(authHeader) == 0 {
@@ -33,20 +52,113 @@ func authMiddleware(tokenMaker token.Maker) gin.HandlerFunc {
 		}
 
 		authType := strings.ToLower(fields[0])
-		if authType != authTypeBearer {
+		switch authType {
+		case authTypeBearer:
+			accessToken := fields[1]
+			payload, err := tokenMaker.VerifyToken(accessToken)
+			if err != nil {
+				ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
+				return
+			}
+
+			if payload.Type != token.TokenTypeAccess {
+				ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(errNotAccessToken))
+				return
+			}
+
+			if err = checkTokenStatus(ctx, store, payload); err != nil {
+				switch err {
+				case errRevokedToken, errPasswordChanged, errRoleChanged, errUserDeleted, sql.ErrNoRows:
+					ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
+				default:
+					ctx.AbortWithStatusJSON(http.StatusInternalServerError, errorResponse(err))
+				}
+				return
+			}
+
+			ctx.Set(authPayloadKey, payload)
+		case authTypeAPIKey:
+			apiKey, payload, err := verifyAPIKey(ctx, store, fields[1])
+			if err != nil {
+				switch err {
+				case errInvalidAPIKey, errRevokedAPIKey, errExpiredAPIKey, sql.ErrNoRows:
+					ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
+				default:
+					ctx.AbortWithStatusJSON(http.StatusInternalServerError, errorResponse(err))
+				}
+				return
+			}
+
+			if !hasScopeFor(apiKey, ctx.Request.Method) {
+				ctx.AbortWithStatusJSON(http.StatusForbidden, errorResponse(errInsufficientScope))
+				return
+			}
+
+			ctx.Set(authPayloadKey, payload)
+			ctx.Set(authAPIKeyKey, apiKey)
+		default:
 			err := fmt.Errorf("unsupproted authorization type %s", authType)
 			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
 			return
 		}
 
-		accessToken := fields[1]
-		payload, err := tokenMaker.VerifyToken(accessToken)
-		if err != nil {
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
+		ctx.Next()
+	}
+}
+
+// checkTokenStatus reports whether a verified token has since been revoked or
+// invalidated by a password change or the deletion of its user.
+func checkTokenStatus(ctx context.Context, store db.Store, payload *token.Payload) error {
+	revoked, err := store.IsTokenRevoked(ctx, payload.ID)
+	if err != nil {
+		return err
+	}
+
+	if revoked {
+		return errRevokedToken
+	}
+
+	user, err := store.GetUser(ctx, payload.Username)
+	if err != nil {
+		return err
+	}
+
+	if user.DeletedAt.Valid {
+		return errUserDeleted
+	}
+
+	if payload.IssuedAt.Before(user.PasswordChangedAt) {
+		return errPasswordChanged
+	}
+
+	if payload.Role != user.Role {
+		return errRoleChanged
+	}
+
+	return nil
+}
+
+// authorizeRoles only lets through requests whose token carries one of the
+// given roles. It must run after authMiddleware.
+func authorizeRoles(roles ...string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		authPayload := ctx.MustGet(authPayloadKey).(*token.Payload)
+
+		if !hasRole(authPayload, roles...) {
+			err := fmt.Errorf("role %s is not allowed to access this resource", authPayload.Role)
+			ctx.AbortWithStatusJSON(http.StatusForbidden, errorResponse(err))
 			return
 		}
 
-		ctx.Set(authPayloadKey, payload)
 		ctx.Next()
 	}
 }
+
+func hasRole(payload *token.Payload, roles ...string) bool {
+	for _, role := range roles {
+		if payload.Role == role {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,88 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	db "github.com/ferueda/simplebank-go/db/sqlc"
+)
+
+// Money is an amount in the minor unit of its currency, such as cents, that
+// renders as a decimal string with as many places as the currency has,
+// e.g. "12.34" for 1234 CAD or "1234" for 1234 JPY.
+type Money struct {
+	Amount     int64
+	Currency   string
+	MinorUnits int32
+}
+
+func (m Money) String() string {
+	if m.MinorUnits <= 0 {
+		return strconv.FormatInt(m.Amount, 10)
+	}
+
+	sign := ""
+	digits := strconv.FormatInt(m.Amount, 10)
+	if m.Amount < 0 {
+		sign, digits = "-", digits[1:]
+	}
+
+	places := int(m.MinorUnits)
+	if len(digits) <= places {
+		digits = strings.Repeat("0", places-len(digits)+1) + digits
+	}
+
+	return sign + digits[:len(digits)-places] + "." + digits[len(digits)-places:]
+}
+
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(m.String())
+}
+
+func (s *Server) money(ctx context.Context, amount int64, currency string) Money {
+	return Money{
+		Amount:     amount,
+		Currency:   currency,
+		MinorUnits: s.currencies.displayMinorUnits(ctx, currency),
+	}
+}
+
+// accountResponse is an account with its balance formatted for display.
+// Balance itself stays in minor units.
+type accountResponse struct {
+	db.Account
+	DisplayBalance          Money `json:"display_balance"`
+	DisplayAvailableBalance Money `json:"display_available_balance"`
+}
+
+func (s *Server) newAccountResponse(ctx context.Context, account db.Account) accountResponse {
+	return accountResponse{
+		Account:                 account,
+		DisplayBalance:          s.money(ctx, account.Balance, account.Currency),
+		DisplayAvailableBalance: s.money(ctx, account.AvailableBalance, account.Currency),
+	}
+}
+
+// transferTxResponse is the result of a transfer with both amounts and
+// balances formatted for display.
+type transferTxResponse struct {
+	db.TransferTxResult
+	Transfer        transferResponse `json:"transfer"`
+	FromAccount     accountResponse  `json:"from_account"`
+	ToAccount       accountResponse  `json:"to_account"`
+	DisplayAmount   Money            `json:"display_amount"`
+	DisplayToAmount Money            `json:"display_to_amount"`
+}
+
+func (s *Server) newTransferTxResponse(ctx context.Context, result db.TransferTxResult) transferTxResponse {
+	return transferTxResponse{
+		TransferTxResult: result,
+		Transfer:         newTransferResponse(result.Transfer),
+		FromAccount:      s.newAccountResponse(ctx, result.FromAccount),
+		ToAccount:        s.newAccountResponse(ctx, result.ToAccount),
+		DisplayAmount:    s.money(ctx, result.Transfer.Amount, result.FromAccount.Currency),
+		DisplayToAmount:  s.money(ctx, result.Transfer.ToAmount, result.ToAccount.Currency),
+	}
+}
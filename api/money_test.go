@@ -0,0 +1,33 @@
+package api
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMoneyString(t *testing.T) {
+	testCases := []struct {
+		money Money
+		want  string
+	}{
+		{money: Money{Amount: 1234, Currency: "CAD", MinorUnits: 2}, want: "12.34"},
+		{money: Money{Amount: 5, Currency: "CAD", MinorUnits: 2}, want: "0.05"},
+		{money: Money{Amount: 0, Currency: "CAD", MinorUnits: 2}, want: "0.00"},
+		{money: Money{Amount: -1234, Currency: "CAD", MinorUnits: 2}, want: "-12.34"},
+		{money: Money{Amount: -5, Currency: "CAD", MinorUnits: 2}, want: "-0.05"},
+		{money: Money{Amount: 1234, Currency: "JPY", MinorUnits: 0}, want: "1234"},
+		{money: Money{Amount: 1234, Currency: "BHD", MinorUnits: 3}, want: "1.234"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.want, func(t *testing.T) {
+			require.Equal(t, tc.want, tc.money.String())
+
+			data, err := json.Marshal(tc.money)
+			require.NoError(t, err)
+			require.Equal(t, `"`+tc.want+`"`, string(data))
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	db "github.com/ferueda/simplebank-go/db/sqlc"
+	"github.com/ferueda/simplebank-go/mail"
+	"github.com/gin-gonic/gin"
+)
+
+type forgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+func (s *Server) forgotPassword(ctx *gin.Context) {
+	var req forgotPasswordRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	// Respond the same way whether or not the email is registered so the
+	// endpoint cannot be used to discover accounts.
+	resp := gin.H{"message": "if the email is registered, a reset link has been sent"}
+
+	user, err := s.store.GetUserByEmail(ctx, req.Email)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusAccepted, resp)
+			return
+		}
+
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	resetToken, hashedToken, err := newOneTimeToken()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	arg := db.CreatePasswordResetTokenParams{
+		Username:    user.Username,
+		HashedToken: hashedToken,
+		ExpiresAt:   time.Now().Add(s.config.PasswordResetTokenDuration),
+	}
+
+	_, err = s.store.CreatePasswordResetToken(ctx, arg)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	link := s.config.PasswordResetURL + "?token=" + url.QueryEscape(resetToken)
+	msg := mail.Message{
+		To:      []string{user.Email},
+		Subject: "Reset your SimpleBank password",
+		Body: fmt.Sprintf(
+			"Hello %s,\n\nUse the link below to choose a new password. It expires in %s.\n\n%s\n\nIf you did not ask for a password reset, you can ignore this email.\n",
+			user.FullName, s.config.PasswordResetTokenDuration, link,
+		),
+	}
+
+	if err = s.mailer.Send(ctx, msg); err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusAccepted, resp)
+}
+
+type resetPasswordRequest struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required,min=6"`
+}
+
+func (s *Server) resetPassword(ctx *gin.Context) {
+	var req resetPasswordRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	hashedPass, err := db.HashPassword(req.NewPassword)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	arg := db.ResetPasswordTxParams{
+		HashedToken:    hashOneTimeToken(req.Token),
+		HashedPassword: hashedPass,
+	}
+
+	user, err := s.store.ResetPasswordTx(ctx, arg)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			err := errors.New("reset token is invalid or has expired")
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+			return
+		}
+
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	resp := newUserResponse(user)
+	ctx.JSON(http.StatusOK, resp)
+}
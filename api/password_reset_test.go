@@ -0,0 +1,161 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/url"
+	"regexp"
+	"testing"
+
+	mockdb "github.com/ferueda/simplebank-go/db/mock"
+	db "github.com/ferueda/simplebank-go/db/sqlc"
+	"github.com/ferueda/simplebank-go/mail"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+var tokenParamRegexp = regexp.MustCompile(`token=(\S+)`)
+
+func TestForgotPassword(t *testing.T) {
+	user, _ := randomUser(t, roleCustomer)
+
+	testCases := []struct {
+		name       string
+		body       forgotPasswordRequest
+		buildStubs func(store *mockdb.MockStore, hashedToken *string)
+		status     int
+		sent       int
+	}{
+		{
+			name: "Registered",
+			body: forgotPasswordRequest{Email: user.Email},
+			buildStubs: func(store *mockdb.MockStore, hashedToken *string) {
+				store.EXPECT().GetUserByEmail(gomock.Any(), gomock.Eq(user.Email)).Times(1).Return(user, nil)
+				store.EXPECT().
+					CreatePasswordResetToken(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.CreatePasswordResetTokenParams) (db.PasswordResetToken, error) {
+						require.Equal(t, user.Username, arg.Username)
+						*hashedToken = arg.HashedToken
+						return db.PasswordResetToken{}, nil
+					})
+			},
+			status: http.StatusAccepted,
+			sent:   1,
+		},
+		{
+			name: "Unregistered",
+			body: forgotPasswordRequest{Email: user.Email},
+			buildStubs: func(store *mockdb.MockStore, hashedToken *string) {
+				store.EXPECT().GetUserByEmail(gomock.Any(), gomock.Eq(user.Email)).Times(1).Return(db.User{}, sql.ErrNoRows)
+				store.EXPECT().CreatePasswordResetToken(gomock.Any(), gomock.Any()).Times(0)
+			},
+			status: http.StatusAccepted,
+		},
+		{
+			name: "InvalidEmail",
+			body: forgotPasswordRequest{Email: "invalid-email"},
+			buildStubs: func(store *mockdb.MockStore, hashedToken *string) {
+				store.EXPECT().GetUserByEmail(gomock.Any(), gomock.Any()).Times(0)
+			},
+			status: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			var hashedToken string
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store, &hashedToken)
+
+			server := newTestServerWithStore(t, store)
+			recorder := doPublicRequest(t, server, http.MethodPost, "/users/password/forgot", tc.body)
+			require.Equal(t, tc.status, recorder.Code)
+
+			messages := server.mailer.(*mail.MemorySender).Messages()
+			require.Len(t, messages, tc.sent)
+			if tc.sent == 0 {
+				return
+			}
+
+			// The emailed token is the one whose hash was stored.
+			require.Equal(t, []string{user.Email}, messages[0].To)
+			resetToken := mailedToken(t, messages[0])
+			require.Equal(t, hashedToken, hashOneTimeToken(resetToken))
+		})
+	}
+}
+
+func TestResetPassword(t *testing.T) {
+	user, _ := randomUser(t, roleCustomer)
+	resetToken, hashedToken, err := newOneTimeToken()
+	require.NoError(t, err)
+	newPassword := randomString(8)
+
+	testCases := []struct {
+		name       string
+		body       resetPasswordRequest
+		buildStubs func(store *mockdb.MockStore)
+		status     int
+	}{
+		{
+			name: "OK",
+			body: resetPasswordRequest{Token: resetToken, NewPassword: newPassword},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ResetPasswordTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.ResetPasswordTxParams) (db.User, error) {
+						require.Equal(t, hashedToken, arg.HashedToken)
+						require.NoError(t, db.ValidateHashedPassword(newPassword, arg.HashedPassword))
+						return user, nil
+					})
+			},
+			status: http.StatusOK,
+		},
+		{
+			name: "InvalidToken",
+			body: resetPasswordRequest{Token: randomString(43), NewPassword: newPassword},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ResetPasswordTx(gomock.Any(), gomock.Any()).Times(1).Return(db.User{}, sql.ErrNoRows)
+			},
+			status: http.StatusBadRequest,
+		},
+		{
+			name: "ShortPassword",
+			body: resetPasswordRequest{Token: resetToken, NewPassword: "abc"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ResetPasswordTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			status: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServerWithStore(t, store)
+			recorder := doPublicRequest(t, server, http.MethodPost, "/users/password/reset", tc.body)
+			require.Equal(t, tc.status, recorder.Code)
+		})
+	}
+}
+
+// mailedToken extracts the one-time token from the link in a message.
+func mailedToken(t *testing.T, msg mail.Message) string {
+	match := tokenParamRegexp.FindStringSubmatch(msg.Body)
+	require.Len(t, match, 2)
+
+	token, err := url.QueryUnescape(match[1])
+	require.NoError(t, err)
+	return token
+}
@@ -0,0 +1,263 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	db "github.com/ferueda/simplebank-go/db/sqlc"
+	"github.com/ferueda/simplebank-go/token"
+	"github.com/gin-gonic/gin"
+)
+
+var errPendingToSameAccount = errors.New("cannot hold a transfer to the same account")
+
+type createPendingTransferRequest struct {
+	FromAccountId int64  `json:"from_account_id" binding:"required,min=1"`
+	ToAccountId   int64  `json:"to_account_id" binding:"required,min=1"`
+	Amount        int64  `json:"amount" binding:"required,gt=0"`
+	Currency      string `json:"currency" binding:"required"`
+}
+
+type pendingTransferResponse struct {
+	ID             int64     `json:"id"`
+	FromAccountID  int64     `json:"from_account_id"`
+	ToAccountID    int64     `json:"to_account_id"`
+	Amount         int64     `json:"amount"`
+	Currency       string    `json:"currency"`
+	CapturedAmount int64     `json:"captured_amount"`
+	TransferID     *int64    `json:"transfer_id"`
+	Status         string    `json:"status"`
+	ExpiresAt      time.Time `json:"expires_at"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+func newPendingTransferResponse(pending db.PendingTransfer) pendingTransferResponse {
+	rsp := pendingTransferResponse{
+		ID:             pending.ID,
+		FromAccountID:  pending.FromAccountID,
+		ToAccountID:    pending.ToAccountID,
+		Amount:         pending.Amount,
+		Currency:       pending.Currency,
+		CapturedAmount: pending.CapturedAmount,
+		Status:         pending.Status,
+		ExpiresAt:      pending.ExpiresAt,
+		CreatedAt:      pending.CreatedAt,
+		UpdatedAt:      pending.UpdatedAt,
+	}
+	if pending.TransferID.Valid {
+		rsp.TransferID = &pending.TransferID.Int64
+	}
+	return rsp
+}
+
+// createPendingTransfer places a hold on the source account for a transfer
+// that is captured or voided later. The hold expires after HoldDuration.
+func (s *Server) createPendingTransfer(ctx *gin.Context) {
+	var req createPendingTransferRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if !s.checkCurrency(ctx, req.Currency) {
+		return
+	}
+
+	if req.FromAccountId == req.ToAccountId {
+		ctx.JSON(http.StatusBadRequest, errorResponse(errPendingToSameAccount))
+		return
+	}
+
+	if _, ok := s.authorizeAccount(ctx, req.FromAccountId, accountWrite); !ok {
+		return
+	}
+
+	authPayload := ctx.MustGet(authPayloadKey).(*token.Payload)
+
+	if !s.checkTransferPolicy(ctx, authPayload.Username) {
+		return
+	}
+
+	result, err := s.store.CreatePendingTransferTx(ctx, db.CreatePendingTransferTxParams{
+		FromAccountID:        req.FromAccountId,
+		ToAccountID:          req.ToAccountId,
+		Amount:               req.Amount,
+		Currency:             req.Currency,
+		ExpiresAt:            time.Now().Add(s.config.HoldDuration),
+		AllowFrozenRecipient: s.config.FrozenAccountsAcceptCredits,
+	})
+	if err != nil {
+		switch err {
+		case db.ErrInsufficientFunds, db.ErrCurrencyMismatch:
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		case db.ErrAccountNotActive, db.ErrAccountFrozen:
+			ctx.JSON(http.StatusForbidden, errorResponse(err))
+		case sql.ErrNoRows:
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+		default:
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, newPendingTransferResponse(result.PendingTransfer))
+}
+
+type pendingTransferUri struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+func (s *Server) getPendingTransfer(ctx *gin.Context) {
+	var uri pendingTransferUri
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	pending, ok := s.authorizePendingTransfer(ctx, uri.ID, accountRead)
+	if !ok {
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newPendingTransferResponse(pending))
+}
+
+type listPendingTransfersRequest struct {
+	AccountId int64 `form:"account_id" binding:"required,min=1"`
+	Limit     int32 `form:"limit"`
+	Offset    int32 `form:"offset"`
+}
+
+func (s *Server) listPendingTransfers(ctx *gin.Context) {
+	var req listPendingTransfersRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if _, ok := s.authorizeAccount(ctx, req.AccountId, accountRead); !ok {
+		return
+	}
+
+	switch {
+	case req.Limit <= 0:
+		req.Limit = 20
+	case req.Limit > 100:
+		req.Limit = 100
+	}
+
+	switch {
+	case req.Offset < 0:
+		req.Offset = 0
+	}
+
+	pending, err := s.store.ListPendingTransfers(ctx, db.ListPendingTransfersParams{
+		FromAccountID: req.AccountId,
+		Limit:         req.Limit,
+		Offset:        req.Offset,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	data := make([]pendingTransferResponse, len(pending))
+	for i, p := range pending {
+		data[i] = newPendingTransferResponse(p)
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"_metadata": map[string]interface{}{
+			"count":  len(data),
+			"offset": req.Offset,
+		},
+		"data": data,
+	})
+}
+
+type capturePendingTransferRequest struct {
+	Amount int64 `json:"amount" binding:"omitempty,gt=0"`
+}
+
+type capturePendingTransferResponse struct {
+	PendingTransfer pendingTransferResponse `json:"pending_transfer"`
+	Transfer        transferTxResponse      `json:"transfer"`
+}
+
+// capturePendingTransfer moves the money held by a pending transfer, or
+// part of it, and releases the rest. Without an amount, all of it is moved.
+func (s *Server) capturePendingTransfer(ctx *gin.Context) {
+	var uri pendingTransferUri
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	// The body is optional.
+	var req capturePendingTransferRequest
+	if ctx.Request.ContentLength != 0 {
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+			return
+		}
+	}
+
+	if _, ok := s.authorizePendingTransfer(ctx, uri.ID, accountWrite); !ok {
+		return
+	}
+
+	result, err := s.store.CapturePendingTransferTx(ctx, db.CapturePendingTransferTxParams{
+		ID:                   uri.ID,
+		Amount:               req.Amount,
+		AllowFrozenRecipient: s.config.FrozenAccountsAcceptCredits,
+	})
+	if err != nil {
+		switch err {
+		case db.ErrInsufficientFunds, db.ErrCurrencyMismatch, db.ErrCaptureExceedsHold:
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		case db.ErrAccountNotActive, db.ErrAccountFrozen, db.ErrTransferNotPending, db.ErrHoldExpired:
+			ctx.JSON(http.StatusForbidden, errorResponse(err))
+		case sql.ErrNoRows:
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+		default:
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, capturePendingTransferResponse{
+		PendingTransfer: newPendingTransferResponse(result.PendingTransfer),
+		Transfer:        s.newTransferTxResponse(ctx, result.Transfer),
+	})
+}
+
+// voidPendingTransfer cancels a pending transfer and releases its hold.
+func (s *Server) voidPendingTransfer(ctx *gin.Context) {
+	var uri pendingTransferUri
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if _, ok := s.authorizePendingTransfer(ctx, uri.ID, accountWrite); !ok {
+		return
+	}
+
+	result, err := s.store.VoidPendingTransferTx(ctx, uri.ID)
+	if err != nil {
+		switch err {
+		case db.ErrTransferNotPending:
+			ctx.JSON(http.StatusForbidden, errorResponse(err))
+		case sql.ErrNoRows:
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+		default:
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newPendingTransferResponse(result.PendingTransfer))
+}
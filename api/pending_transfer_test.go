@@ -0,0 +1,94 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	db "github.com/ferueda/simplebank-go/db/sqlc"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPendingTransfer(t *testing.T) {
+	server := newTestServer(t)
+	owner := createTestUser(t, roleCustomer)
+	recipient := createTestUser(t, roleCustomer)
+	banker := createTestUser(t, roleBanker)
+	fromAcc := createTestAccount(t, owner)
+	toAcc := createTestAccount(t, recipient)
+
+	body := createPendingTransferRequest{FromAccountId: fromAcc.ID, ToAccountId: toAcc.ID, Amount: 400, Currency: "CAD"}
+	recorder := doRequest(t, server, owner, http.MethodPost, "/pending_transfers", body)
+	require.Equal(t, http.StatusCreated, recorder.Code)
+
+	var pending pendingTransferResponse
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &pending))
+	require.Equal(t, db.PendingTransferStatusPending, pending.Status)
+	require.Nil(t, pending.TransferID)
+
+	url := fmt.Sprintf("/pending_transfers/%d", pending.ID)
+
+	testCases := []struct {
+		name   string
+		user   db.User
+		method string
+		url    string
+		body   interface{}
+		status int
+	}{
+		{name: "SameAccount", user: owner, method: http.MethodPost, url: "/pending_transfers", body: createPendingTransferRequest{FromAccountId: fromAcc.ID, ToAccountId: fromAcc.ID, Amount: 100, Currency: "CAD"}, status: http.StatusBadRequest},
+		{name: "RecipientGet", user: recipient, method: http.MethodGet, url: url, status: http.StatusForbidden},
+		{name: "BankerGet", user: banker, method: http.MethodGet, url: url, status: http.StatusOK},
+		{name: "BankerCapture", user: banker, method: http.MethodPost, url: url + "/capture", status: http.StatusForbidden},
+		{name: "NotFound", user: owner, method: http.MethodGet, url: fmt.Sprintf("/pending_transfers/%d", missingID), status: http.StatusNotFound},
+		{name: "HeldMoneyCannotBeSpent", user: owner, method: http.MethodPost, url: "/transfers", body: transferRequest{FromAccountId: fromAcc.ID, ToAccountId: toAcc.ID, Amount: 601, Currency: "CAD"}, status: http.StatusBadRequest},
+		{name: "CaptureTooMuch", user: owner, method: http.MethodPost, url: url + "/capture", body: capturePendingTransferRequest{Amount: 401}, status: http.StatusBadRequest},
+		{name: "Capture", user: owner, method: http.MethodPost, url: url + "/capture", body: capturePendingTransferRequest{Amount: 300}, status: http.StatusCreated},
+		{name: "CaptureAgain", user: owner, method: http.MethodPost, url: url + "/capture", status: http.StatusForbidden},
+		{name: "VoidCaptured", user: owner, method: http.MethodPost, url: url + "/void", status: http.StatusForbidden},
+		{name: "List", user: owner, method: http.MethodGet, url: fmt.Sprintf("/pending_transfers?account_id=%d", fromAcc.ID), status: http.StatusOK},
+		{name: "ListOtherAccount", user: recipient, method: http.MethodGet, url: fmt.Sprintf("/pending_transfers?account_id=%d", fromAcc.ID), status: http.StatusForbidden},
+	}
+
+	// The cases run in order, each one starting from where the last left it.
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := doRequest(t, server, tc.user, tc.method, tc.url, tc.body)
+			require.Equal(t, tc.status, recorder.Code)
+		})
+	}
+
+	// Only the captured part moved; the rest of the hold was released.
+	account, err := testStore.GetAccount(context.Background(), fromAcc.ID)
+	require.NoError(t, err)
+	require.Equal(t, fromAcc.Balance-300, account.Balance)
+	require.Zero(t, account.HeldAmount)
+
+	// Voiding gives the whole hold back.
+	body.Amount = 200
+	recorder = doRequest(t, server, owner, http.MethodPost, "/pending_transfers", body)
+	require.Equal(t, http.StatusCreated, recorder.Code)
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &pending))
+
+	recorder = doRequest(t, server, owner, http.MethodGet, fmt.Sprintf("/accounts/%d", fromAcc.ID), nil)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var rsp struct {
+		Balance                 int64  `json:"balance"`
+		AvailableBalance        int64  `json:"available_balance"`
+		DisplayAvailableBalance string `json:"display_available_balance"`
+	}
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+	require.Equal(t, fromAcc.Balance-300, rsp.Balance)
+	require.Equal(t, fromAcc.Balance-500, rsp.AvailableBalance)
+	require.Equal(t, "5.00", rsp.DisplayAvailableBalance)
+
+	recorder = doRequest(t, server, owner, http.MethodPost, fmt.Sprintf("/pending_transfers/%d/void", pending.ID), nil)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	account, err = testStore.GetAccount(context.Background(), fromAcc.ID)
+	require.NoError(t, err)
+	require.Equal(t, account.Balance, account.AvailableBalance)
+}
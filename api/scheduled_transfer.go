@@ -0,0 +1,333 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	db "github.com/ferueda/simplebank-go/db/sqlc"
+	"github.com/ferueda/simplebank-go/token"
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	errRunAtNotInFuture       = errors.New("run_at must be in the future")
+	errDayOfMonthRequired     = errors.New("day_of_month is required for monthly transfers")
+	errDayOfMonthNotAllowed   = errors.New("day_of_month is only used by monthly transfers")
+	errScheduledCrossCurrency = errors.New("scheduled transfers between currencies are not supported")
+	errScheduledToSameAccount = errors.New("cannot schedule a transfer to the same account")
+)
+
+type createScheduledTransferRequest struct {
+	FromAccountId int64     `json:"from_account_id" binding:"required,min=1"`
+	ToAccountId   int64     `json:"to_account_id" binding:"required,min=1"`
+	Amount        int64     `json:"amount" binding:"required,gt=0"`
+	Currency      string    `json:"currency" binding:"required"`
+	RunAt         time.Time `json:"run_at" binding:"required"`
+	Recurrence    string    `json:"recurrence" binding:"omitempty,oneof=once daily weekly monthly"`
+	DayOfMonth    int32     `json:"day_of_month" binding:"omitempty,min=1,max=31"`
+}
+
+type scheduledTransferResponse struct {
+	ID            int64      `json:"id"`
+	Owner         string     `json:"owner"`
+	FromAccountID int64      `json:"from_account_id"`
+	ToAccountID   int64      `json:"to_account_id"`
+	Amount        int64      `json:"amount"`
+	Currency      string     `json:"currency"`
+	Recurrence    string     `json:"recurrence"`
+	DayOfMonth    *int32     `json:"day_of_month"`
+	NextRunAt     time.Time  `json:"next_run_at"`
+	RetryAt       *time.Time `json:"retry_at"`
+	Status        string     `json:"status"`
+	FailureCount  int32      `json:"failure_count"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+func newScheduledTransferResponse(scheduled db.ScheduledTransfer) scheduledTransferResponse {
+	rsp := scheduledTransferResponse{
+		ID:            scheduled.ID,
+		Owner:         scheduled.Owner,
+		FromAccountID: scheduled.FromAccountID,
+		ToAccountID:   scheduled.ToAccountID,
+		Amount:        scheduled.Amount,
+		Currency:      scheduled.Currency,
+		Recurrence:    scheduled.Recurrence,
+		NextRunAt:     scheduled.NextRunAt,
+		RetryAt:       nullTimePtr(scheduled.RetryAt),
+		Status:        scheduled.Status,
+		FailureCount:  scheduled.FailureCount,
+		CreatedAt:     scheduled.CreatedAt,
+		UpdatedAt:     scheduled.UpdatedAt,
+	}
+	if scheduled.DayOfMonth.Valid {
+		rsp.DayOfMonth = &scheduled.DayOfMonth.Int32
+	}
+	return rsp
+}
+
+type scheduledTransferRunResponse struct {
+	ID           int64     `json:"id"`
+	ScheduledFor time.Time `json:"scheduled_for"`
+	TransferID   *int64    `json:"transfer_id"`
+	Status       string    `json:"status"`
+	Error        string    `json:"error,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+func newScheduledTransferRunResponse(run db.ScheduledTransferRun) scheduledTransferRunResponse {
+	rsp := scheduledTransferRunResponse{
+		ID:           run.ID,
+		ScheduledFor: run.ScheduledFor,
+		Status:       run.Status,
+		Error:        run.Error,
+		CreatedAt:    run.CreatedAt,
+	}
+	if run.TransferID.Valid {
+		rsp.TransferID = &run.TransferID.Int64
+	}
+	return rsp
+}
+
+func (s *Server) createScheduledTransfer(ctx *gin.Context) {
+	var req createScheduledTransferRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if !s.checkCurrency(ctx, req.Currency) {
+		return
+	}
+
+	if req.Recurrence == "" {
+		req.Recurrence = db.RecurrenceOnce
+	}
+
+	var dayOfMonth sql.NullInt32
+	switch {
+	case req.Recurrence == db.RecurrenceMonthly && req.DayOfMonth == 0:
+		ctx.JSON(http.StatusBadRequest, errorResponse(errDayOfMonthRequired))
+		return
+	case req.Recurrence != db.RecurrenceMonthly && req.DayOfMonth != 0:
+		ctx.JSON(http.StatusBadRequest, errorResponse(errDayOfMonthNotAllowed))
+		return
+	case req.Recurrence == db.RecurrenceMonthly:
+		dayOfMonth = sql.NullInt32{Int32: req.DayOfMonth, Valid: true}
+	}
+
+	if !req.RunAt.After(time.Now()) {
+		ctx.JSON(http.StatusBadRequest, errorResponse(errRunAtNotInFuture))
+		return
+	}
+
+	if req.FromAccountId == req.ToAccountId {
+		ctx.JSON(http.StatusBadRequest, errorResponse(errScheduledToSameAccount))
+		return
+	}
+
+	fromAcc, ok := s.authorizeAccount(ctx, req.FromAccountId, accountWrite)
+	if !ok {
+		return
+	}
+
+	if fromAcc.Currency != req.Currency {
+		ctx.JSON(http.StatusBadRequest, errorResponse(db.ErrCurrencyMismatch))
+		return
+	}
+
+	// The rate a future run would get cannot be quoted now, so only
+	// transfers within a currency can be scheduled.
+	toAcc, err := s.store.GetAccount(ctx, req.ToAccountId)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if toAcc.Currency != req.Currency {
+		ctx.JSON(http.StatusBadRequest, errorResponse(errScheduledCrossCurrency))
+		return
+	}
+
+	authPayload := ctx.MustGet(authPayloadKey).(*token.Payload)
+
+	if !s.checkTransferPolicy(ctx, authPayload.Username) {
+		return
+	}
+
+	scheduled, err := s.store.CreateScheduledTransfer(ctx, db.CreateScheduledTransferParams{
+		Owner:         authPayload.Username,
+		FromAccountID: req.FromAccountId,
+		ToAccountID:   req.ToAccountId,
+		Amount:        req.Amount,
+		Currency:      req.Currency,
+		Recurrence:    req.Recurrence,
+		DayOfMonth:    dayOfMonth,
+		NextRunAt:     req.RunAt,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, newScheduledTransferResponse(scheduled))
+}
+
+type scheduledTransferUri struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+func (s *Server) getScheduledTransfer(ctx *gin.Context) {
+	var uri scheduledTransferUri
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	scheduled, ok := s.authorizeScheduledTransfer(ctx, uri.ID, accountRead)
+	if !ok {
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newScheduledTransferResponse(scheduled))
+}
+
+type listScheduledTransfersRequest struct {
+	Limit  int32 `form:"limit"`
+	Offset int32 `form:"offset"`
+}
+
+func (s *Server) listScheduledTransfers(ctx *gin.Context) {
+	var req listScheduledTransfersRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	switch {
+	case req.Limit <= 0:
+		req.Limit = 20
+	case req.Limit > 100:
+		req.Limit = 100
+	}
+
+	switch {
+	case req.Offset < 0:
+		req.Offset = 0
+	}
+
+	authPayload := ctx.MustGet(authPayloadKey).(*token.Payload)
+
+	scheduled, err := s.store.ListScheduledTransfers(ctx, db.ListScheduledTransfersParams{
+		Owner:  authPayload.Username,
+		Limit:  req.Limit,
+		Offset: req.Offset,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	data := make([]scheduledTransferResponse, len(scheduled))
+	for i, st := range scheduled {
+		data[i] = newScheduledTransferResponse(st)
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"_metadata": map[string]interface{}{
+			"count":  len(data),
+			"offset": req.Offset,
+		},
+		"data": data,
+	})
+}
+
+func (s *Server) pauseScheduledTransfer(ctx *gin.Context) {
+	scheduled, ok := s.changeScheduledTransferStatus(ctx, db.ScheduledTransferStatusPaused)
+	if !ok {
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newScheduledTransferResponse(scheduled))
+}
+
+func (s *Server) resumeScheduledTransfer(ctx *gin.Context) {
+	scheduled, ok := s.changeScheduledTransferStatus(ctx, db.ScheduledTransferStatusActive)
+	if !ok {
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newScheduledTransferResponse(scheduled))
+}
+
+// cancelScheduledTransfer stops a scheduled transfer for good. It is kept,
+// with its runs, for the record.
+func (s *Server) cancelScheduledTransfer(ctx *gin.Context) {
+	if _, ok := s.changeScheduledTransferStatus(ctx, db.ScheduledTransferStatusCancelled); !ok {
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// changeScheduledTransferStatus moves the scheduled transfer named in the
+// uri to status on behalf of its owner. When it returns false the error
+// response has already been written.
+func (s *Server) changeScheduledTransferStatus(ctx *gin.Context, status string) (db.ScheduledTransfer, bool) {
+	var uri scheduledTransferUri
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return db.ScheduledTransfer{}, false
+	}
+
+	if _, ok := s.authorizeScheduledTransfer(ctx, uri.ID, accountWrite); !ok {
+		return db.ScheduledTransfer{}, false
+	}
+
+	scheduled, err := s.store.ChangeScheduledTransferStatusTx(ctx, uri.ID, status)
+	if err != nil {
+		switch err {
+		case db.ErrInvalidStatusChange:
+			ctx.JSON(http.StatusForbidden, errorResponse(err))
+		case sql.ErrNoRows:
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+		default:
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		}
+		return scheduled, false
+	}
+
+	return scheduled, true
+}
+
+func (s *Server) listScheduledTransferRuns(ctx *gin.Context) {
+	var uri scheduledTransferUri
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if _, ok := s.authorizeScheduledTransfer(ctx, uri.ID, accountRead); !ok {
+		return
+	}
+
+	runs, err := s.store.ListScheduledTransferRuns(ctx, uri.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	data := make([]scheduledTransferRunResponse, len(runs))
+	for i, run := range runs {
+		data[i] = newScheduledTransferRunResponse(run)
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"data": data})
+}
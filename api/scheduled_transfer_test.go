@@ -0,0 +1,131 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	db "github.com/ferueda/simplebank-go/db/sqlc"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateScheduledTransferChecks(t *testing.T) {
+	server := newTestServer(t)
+	owner := createTestUser(t, roleCustomer)
+	other := createTestUser(t, roleCustomer)
+	fromAcc := createTestAccount(t, owner)
+	toAcc := createTestAccount(t, other)
+	runAt := time.Now().Add(time.Hour)
+
+	testCases := []struct {
+		name   string
+		body   createScheduledTransferRequest
+		status int
+	}{
+		{
+			name:   "Once",
+			body:   createScheduledTransferRequest{FromAccountId: fromAcc.ID, ToAccountId: toAcc.ID, Amount: 10, Currency: "CAD", RunAt: runAt},
+			status: http.StatusCreated,
+		},
+		{
+			name:   "Monthly",
+			body:   createScheduledTransferRequest{FromAccountId: fromAcc.ID, ToAccountId: toAcc.ID, Amount: 10, Currency: "CAD", RunAt: runAt, Recurrence: db.RecurrenceMonthly, DayOfMonth: 31},
+			status: http.StatusCreated,
+		},
+		{
+			name:   "MonthlyWithoutDay",
+			body:   createScheduledTransferRequest{FromAccountId: fromAcc.ID, ToAccountId: toAcc.ID, Amount: 10, Currency: "CAD", RunAt: runAt, Recurrence: db.RecurrenceMonthly},
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "DayWithoutMonthly",
+			body:   createScheduledTransferRequest{FromAccountId: fromAcc.ID, ToAccountId: toAcc.ID, Amount: 10, Currency: "CAD", RunAt: runAt, Recurrence: db.RecurrenceWeekly, DayOfMonth: 1},
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "InThePast",
+			body:   createScheduledTransferRequest{FromAccountId: fromAcc.ID, ToAccountId: toAcc.ID, Amount: 10, Currency: "CAD", RunAt: time.Now().Add(-time.Hour)},
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "SameAccount",
+			body:   createScheduledTransferRequest{FromAccountId: fromAcc.ID, ToAccountId: fromAcc.ID, Amount: 10, Currency: "CAD", RunAt: runAt},
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "OtherUsersAccount",
+			body:   createScheduledTransferRequest{FromAccountId: toAcc.ID, ToAccountId: fromAcc.ID, Amount: 10, Currency: "CAD", RunAt: runAt},
+			status: http.StatusForbidden,
+		},
+		{
+			name:   "RecipientNotFound",
+			body:   createScheduledTransferRequest{FromAccountId: fromAcc.ID, ToAccountId: missingID, Amount: 10, Currency: "CAD", RunAt: runAt},
+			status: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := doRequest(t, server, owner, http.MethodPost, "/scheduled_transfers", tc.body)
+			require.Equal(t, tc.status, recorder.Code)
+		})
+	}
+}
+
+func TestScheduledTransferOwnership(t *testing.T) {
+	server := newTestServer(t)
+	owner := createTestUser(t, roleCustomer)
+	other := createTestUser(t, roleCustomer)
+	banker := createTestUser(t, roleBanker)
+
+	scheduled, err := testStore.CreateScheduledTransfer(context.Background(), db.CreateScheduledTransferParams{
+		Owner:         owner.Username,
+		FromAccountID: createTestAccount(t, owner).ID,
+		ToAccountID:   createTestAccount(t, other).ID,
+		Amount:        10,
+		Currency:      "CAD",
+		Recurrence:    db.RecurrenceDaily,
+		NextRunAt:     time.Now().Add(time.Hour),
+	})
+	require.NoError(t, err)
+
+	url := fmt.Sprintf("/scheduled_transfers/%d", scheduled.ID)
+
+	testCases := []struct {
+		name   string
+		user   db.User
+		method string
+		url    string
+		status int
+	}{
+		{name: "OtherUserGet", user: other, method: http.MethodGet, url: url, status: http.StatusForbidden},
+		{name: "OtherUserPause", user: other, method: http.MethodPost, url: url + "/pause", status: http.StatusForbidden},
+		{name: "BankerGet", user: banker, method: http.MethodGet, url: url, status: http.StatusOK},
+		{name: "BankerCancel", user: banker, method: http.MethodDelete, url: url, status: http.StatusForbidden},
+		{name: "NotFound", user: owner, method: http.MethodGet, url: fmt.Sprintf("/scheduled_transfers/%d", missingID), status: http.StatusNotFound},
+		{name: "Pause", user: owner, method: http.MethodPost, url: url + "/pause", status: http.StatusOK},
+		{name: "PauseAgain", user: owner, method: http.MethodPost, url: url + "/pause", status: http.StatusForbidden},
+		{name: "Resume", user: owner, method: http.MethodPost, url: url + "/resume", status: http.StatusOK},
+		{name: "Cancel", user: owner, method: http.MethodDelete, url: url, status: http.StatusNoContent},
+		{name: "ResumeCancelled", user: owner, method: http.MethodPost, url: url + "/resume", status: http.StatusForbidden},
+		{name: "Runs", user: owner, method: http.MethodGet, url: url + "/runs", status: http.StatusOK},
+	}
+
+	// The cases run in order, each one starting from where the last left it.
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := doRequest(t, server, tc.user, tc.method, tc.url, nil)
+			require.Equal(t, tc.status, recorder.Code)
+		})
+	}
+
+	recorder := doRequest(t, server, owner, http.MethodGet, url, nil)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var rsp scheduledTransferResponse
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+	require.Equal(t, db.ScheduledTransferStatusCancelled, rsp.Status)
+}
@@ -1,34 +1,148 @@
 package api
 
 import (
+	"fmt"
+	"math/big"
+	"time"
+
 	db "github.com/ferueda/simplebank-go/db/sqlc"
+	"github.com/ferueda/simplebank-go/exchange"
+	"github.com/ferueda/simplebank-go/mail"
 	"github.com/ferueda/simplebank-go/token"
+	"github.com/ferueda/simplebank-go/totp"
 	"github.com/gin-gonic/gin"
 )
 
+type Config struct {
+	AccessTokenDuration  time.Duration
+	RefreshTokenDuration time.Duration
+	MFAChallengeDuration time.Duration
+	TOTPEncryptionKey    string
+	TOTPIssuer           string
+
+	LoginMaxAttempts      int32
+	LoginMaxAttemptsPerIP int32
+	LoginBackoffBase      time.Duration
+	LoginLockoutDuration  time.Duration
+
+	PasswordResetTokenDuration time.Duration
+	PasswordResetURL           string
+
+	EmailVerificationTokenDuration time.Duration
+	EmailVerificationURL           string
+	// TransferRequiresVerifiedEmail blocks outgoing transfers until the
+	// sender has verified their email address.
+	TransferRequiresVerifiedEmail bool
+	// FrozenAccountsAcceptCredits lets transfers into frozen accounts go
+	// through. Transfers out of them are always refused.
+	FrozenAccountsAcceptCredits bool
+	// IdempotencyKeyDuration is how long a transfer can be retried with the
+	// same Idempotency-Key and get the original result back.
+	IdempotencyKeyDuration time.Duration
+	// ExchangeSpread is the fraction of a converted amount kept as a fee on
+	// cross-currency transfers, e.g. "0.01". Empty means no spread.
+	ExchangeSpread string
+	// HoldDuration is how long a pending transfer holds its amount before
+	// the hold expires and it can no longer be captured.
+	HoldDuration time.Duration
+}
+
 type Server struct {
-	store      *db.Store
+	config     Config
+	store      db.Store
 	tokenMaker token.Maker
+	totpCipher *totp.Cipher
+	mailer     mail.Sender
+	rates      exchange.RateProvider
+	spread     *big.Rat
+	currencies *currencyRegistry
 	router     *gin.Engine
 }
 
-func NewServer(store *db.Store, tm token.Maker) (*Server, error) {
-	s := Server{store: store, tokenMaker: tm}
+func NewServer(config Config, store db.Store, tm token.Maker, mailer mail.Sender, rates exchange.RateProvider) (*Server, error) {
+	totpCipher, err := totp.NewCipher(config.TOTPEncryptionKey)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create totp cipher: %w", err)
+	}
+
+	spread := new(big.Rat)
+	if config.ExchangeSpread != "" {
+		spread, err = exchange.ParseRate(config.ExchangeSpread)
+		if err != nil {
+			return nil, fmt.Errorf("invalid exchange spread: %w", err)
+		}
+		if spread.Cmp(big.NewRat(1, 1)) >= 0 {
+			return nil, exchange.ErrInvalidSpread
+		}
+	}
+
+	s := Server{
+		config:     config,
+		store:      store,
+		tokenMaker: tm,
+		totpCipher: totpCipher,
+		mailer:     mailer,
+		rates:      rates,
+		spread:     spread,
+		currencies: newCurrencyRegistry(store),
+	}
+
 	r := gin.Default()
 
 	r.POST("/users", s.createUser)
 	r.POST("/users/login", s.loginUser)
+	r.POST("/users/login/mfa", s.verifyLoginMFA)
+	r.POST("/users/password/forgot", s.forgotPassword)
+	r.POST("/users/password/reset", s.resetPassword)
+	r.GET("/users/verify_email", s.verifyEmail)
+	r.POST("/tokens/renew_access", s.renewAccessToken)
+	r.GET("/.well-known/jwks.json", s.getJWKS)
 
-	authRoutes := r.Group("/").Use(authMiddleware(s.tokenMaker))
+	authRoutes := r.Group("/").Use(authMiddleware(s.tokenMaker, s.store))
+
+	authRoutes.GET("/users/me", s.getCurrentUser)
+	authRoutes.PATCH("/users/me", denyAPIKeys(), s.updateCurrentUser)
+	authRoutes.DELETE("/users/me", denyAPIKeys(), s.deleteCurrentUser)
+	authRoutes.GET("/users/me/export", denyAPIKeys(), s.exportUser)
+	authRoutes.POST("/users/logout", denyAPIKeys(), s.logoutUser)
+	authRoutes.PATCH("/users/password", denyAPIKeys(), s.updateUserPassword)
+	authRoutes.POST("/users/totp", denyAPIKeys(), s.enrollTotp)
+	authRoutes.POST("/users/totp/confirm", denyAPIKeys(), s.confirmTotp)
+	authRoutes.DELETE("/users/totp", denyAPIKeys(), s.disableTotp)
+	authRoutes.POST("/users/api_keys", denyAPIKeys(), s.createAPIKey)
+	authRoutes.GET("/users/api_keys", denyAPIKeys(), s.listAPIKeys)
+	authRoutes.DELETE("/users/api_keys/:id", denyAPIKeys(), s.revokeAPIKey)
+	authRoutes.PATCH("/users/:username/role", authorizeRoles(roleAdmin), s.updateUserRole)
+	authRoutes.DELETE("/users/:username/lockout", authorizeRoles(roleAdmin), s.unlockUser)
+	authRoutes.POST("/tokens/introspect", authorizeRoles(roleAdmin), s.introspectToken)
 
 	authRoutes.POST("/accounts", s.createAccount)
 	authRoutes.GET("/accounts", s.listAccounts)
 	authRoutes.GET("/accounts/:id", s.getAccount)
 	authRoutes.DELETE("/accounts/:id", s.deleteAccount)
+	authRoutes.POST("/accounts/:id/freeze", authorizeRoles(roleAdmin), s.freezeAccount)
+	authRoutes.POST("/accounts/:id/unfreeze", authorizeRoles(roleAdmin), s.unfreezeAccount)
+	authRoutes.GET("/accounts/:id/status_changes", authorizeRoles(roleBanker, roleAdmin), s.listAccountStatusChanges)
 
 	authRoutes.POST("/transfers", s.createTransfer)
 	authRoutes.GET("/transfers", s.listTransfers)
 	authRoutes.GET("/transfers/:id", s.getTransfer)
+	authRoutes.POST("/transfers/:id/reverse", s.reverseTransfer)
+	authRoutes.GET("/transfers/:id/reversals", s.listTransferReversals)
+
+	authRoutes.POST("/pending_transfers", s.createPendingTransfer)
+	authRoutes.GET("/pending_transfers", s.listPendingTransfers)
+	authRoutes.GET("/pending_transfers/:id", s.getPendingTransfer)
+	authRoutes.POST("/pending_transfers/:id/capture", s.capturePendingTransfer)
+	authRoutes.POST("/pending_transfers/:id/void", s.voidPendingTransfer)
+
+	authRoutes.POST("/scheduled_transfers", s.createScheduledTransfer)
+	authRoutes.GET("/scheduled_transfers", s.listScheduledTransfers)
+	authRoutes.GET("/scheduled_transfers/:id", s.getScheduledTransfer)
+	authRoutes.DELETE("/scheduled_transfers/:id", s.cancelScheduledTransfer)
+	authRoutes.POST("/scheduled_transfers/:id/pause", s.pauseScheduledTransfer)
+	authRoutes.POST("/scheduled_transfers/:id/resume", s.resumeScheduledTransfer)
+	authRoutes.GET("/scheduled_transfers/:id/runs", s.listScheduledTransferRuns)
 
 	s.router = r
 	return &s, nil
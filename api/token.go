@@ -0,0 +1,172 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/ferueda/simplebank-go/token"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type renewAccessTokenRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+type renewAccessTokenResponse struct {
+	AccessToken          string    `json:"access_token"`
+	AccessTokenExpiresAt time.Time `json:"access_token_expires_at"`
+}
+
+func (s *Server) renewAccessToken(ctx *gin.Context) {
+	var req renewAccessTokenRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	refreshPayload, err := s.tokenMaker.VerifyToken(req.RefreshToken)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		return
+	}
+
+	if refreshPayload.Type != token.TokenTypeRefresh {
+		err := errors.New("token is not a refresh token")
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		return
+	}
+
+	session, err := s.store.GetSession(ctx, refreshPayload.ID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if session.IsBlocked {
+		err := errors.New("blocked session")
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		return
+	}
+
+	if session.Username != refreshPayload.Username {
+		err := errors.New("incorrect session user")
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		return
+	}
+
+	if session.RefreshToken != req.RefreshToken {
+		err := errors.New("mismatched session token")
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		return
+	}
+
+	if time.Now().After(session.ExpiresAt) {
+		err := errors.New("expired session")
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		return
+	}
+
+	user, err := s.store.GetUser(ctx, session.Username)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if refreshPayload.IssuedAt.Before(user.PasswordChangedAt) {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(errPasswordChanged))
+		return
+	}
+
+	accessToken, accessPayload, err := s.tokenMaker.CreateToken(user.Username, user.Role, token.TokenTypeAccess, s.config.AccessTokenDuration)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	resp := renewAccessTokenResponse{
+		AccessToken:          accessToken,
+		AccessTokenExpiresAt: accessPayload.ExpiredAt,
+	}
+
+	ctx.JSON(http.StatusOK, resp)
+}
+
+type introspectTokenRequest struct {
+	Token string `form:"token" json:"token" binding:"required"`
+}
+
+// introspectTokenResponse follows RFC 7662. Only "active" is set for tokens
+// that fail verification.
+type introspectTokenResponse struct {
+	Active    bool       `json:"active"`
+	Revoked   bool       `json:"revoked"`
+	ID        *uuid.UUID `json:"jti,omitempty"`
+	Username  string     `json:"username,omitempty"`
+	Role      string     `json:"role,omitempty"`
+	TokenType string     `json:"token_type,omitempty"`
+	Issuer    string     `json:"iss,omitempty"`
+	Audience  string     `json:"aud,omitempty"`
+	IssuedAt  int64      `json:"iat,omitempty"`
+	NotBefore int64      `json:"nbf,omitempty"`
+	ExpiredAt int64      `json:"exp,omitempty"`
+}
+
+// introspectToken reports whether a token is active and what it claims. RFC
+// 7662 requires callers to be authorized, so it is routed to admins only.
+func (s *Server) introspectToken(ctx *gin.Context) {
+	var req introspectTokenRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	payload, err := s.tokenMaker.VerifyToken(req.Token)
+	if err != nil {
+		ctx.JSON(http.StatusOK, introspectTokenResponse{Active: false})
+		return
+	}
+
+	resp := introspectTokenResponse{
+		ID:        &payload.ID,
+		Username:  payload.Username,
+		Role:      payload.Role,
+		TokenType: string(payload.Type),
+		Issuer:    payload.Issuer,
+		Audience:  payload.Audience,
+		IssuedAt:  payload.IssuedAt.Unix(),
+		NotBefore: payload.NotBefore.Unix(),
+		ExpiredAt: payload.ExpiredAt.Unix(),
+	}
+
+	err = checkTokenStatus(ctx, s.store, payload)
+	switch err {
+	case nil:
+		resp.Active = true
+	case errRevokedToken:
+		resp.Revoked = true
+	case errPasswordChanged, errRoleChanged, errUserDeleted, sql.ErrNoRows:
+	default:
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, resp)
+}
+
+func (s *Server) getJWKS(ctx *gin.Context) {
+	jwks := token.JWKSet{Keys: []token.JWK{}}
+
+	if provider, ok := s.tokenMaker.(token.PublicKeyProvider); ok {
+		jwks.Keys = provider.PublicJWKs()
+	}
+
+	ctx.JSON(http.StatusOK, jwks)
+}
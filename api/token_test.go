@@ -0,0 +1,256 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mockdb "github.com/ferueda/simplebank-go/db/mock"
+	db "github.com/ferueda/simplebank-go/db/sqlc"
+	"github.com/ferueda/simplebank-go/token"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenewAccessToken(t *testing.T) {
+	user, _ := randomUser(t, roleCustomer)
+
+	testCases := []struct {
+		name       string
+		tokenType  token.TokenType
+		duration   time.Duration
+		buildStubs func(store *mockdb.MockStore, refreshToken string, payload *token.Payload)
+		status     int
+	}{
+		{
+			name:      "OK",
+			tokenType: token.TokenTypeRefresh,
+			duration:  time.Hour,
+			buildStubs: func(store *mockdb.MockStore, refreshToken string, payload *token.Payload) {
+				store.EXPECT().GetSession(gomock.Any(), gomock.Eq(payload.ID)).Times(1).Return(newTestSession(user, refreshToken, payload), nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+			},
+			status: http.StatusOK,
+		},
+		{
+			name:      "AccessToken",
+			tokenType: token.TokenTypeAccess,
+			duration:  time.Minute,
+			buildStubs: func(store *mockdb.MockStore, refreshToken string, payload *token.Payload) {
+				store.EXPECT().GetSession(gomock.Any(), gomock.Any()).Times(0)
+			},
+			status: http.StatusUnauthorized,
+		},
+		{
+			name:      "ExpiredToken",
+			tokenType: token.TokenTypeRefresh,
+			duration:  -time.Minute,
+			buildStubs: func(store *mockdb.MockStore, refreshToken string, payload *token.Payload) {
+				store.EXPECT().GetSession(gomock.Any(), gomock.Any()).Times(0)
+			},
+			status: http.StatusUnauthorized,
+		},
+		{
+			name:      "SessionNotFound",
+			tokenType: token.TokenTypeRefresh,
+			duration:  time.Hour,
+			buildStubs: func(store *mockdb.MockStore, refreshToken string, payload *token.Payload) {
+				store.EXPECT().GetSession(gomock.Any(), gomock.Eq(payload.ID)).Times(1).Return(db.Session{}, sql.ErrNoRows)
+			},
+			status: http.StatusNotFound,
+		},
+		{
+			name:      "BlockedSession",
+			tokenType: token.TokenTypeRefresh,
+			duration:  time.Hour,
+			buildStubs: func(store *mockdb.MockStore, refreshToken string, payload *token.Payload) {
+				session := newTestSession(user, refreshToken, payload)
+				session.IsBlocked = true
+				store.EXPECT().GetSession(gomock.Any(), gomock.Eq(payload.ID)).Times(1).Return(session, nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+			},
+			status: http.StatusUnauthorized,
+		},
+		{
+			name:      "MismatchedSessionToken",
+			tokenType: token.TokenTypeRefresh,
+			duration:  time.Hour,
+			buildStubs: func(store *mockdb.MockStore, refreshToken string, payload *token.Payload) {
+				session := newTestSession(user, randomString(32), payload)
+				store.EXPECT().GetSession(gomock.Any(), gomock.Eq(payload.ID)).Times(1).Return(session, nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+			},
+			status: http.StatusUnauthorized,
+		},
+		{
+			name:      "IssuedBeforePasswordChange",
+			tokenType: token.TokenTypeRefresh,
+			duration:  time.Hour,
+			buildStubs: func(store *mockdb.MockStore, refreshToken string, payload *token.Payload) {
+				changed := user
+				changed.PasswordChangedAt = time.Now().Add(time.Minute)
+				store.EXPECT().GetSession(gomock.Any(), gomock.Eq(payload.ID)).Times(1).Return(newTestSession(user, refreshToken, payload), nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(changed, nil)
+			},
+			status: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			server := newTestServerWithStore(t, store)
+
+			refreshToken, payload, err := server.tokenMaker.CreateToken(user.Username, user.Role, tc.tokenType, tc.duration)
+			require.NoError(t, err)
+			tc.buildStubs(store, refreshToken, payload)
+
+			body := renewAccessTokenRequest{RefreshToken: refreshToken}
+			recorder := doPublicRequest(t, server, http.MethodPost, "/tokens/renew_access", body)
+			require.Equal(t, tc.status, recorder.Code)
+		})
+	}
+}
+
+// newTestSession returns the session a login would have stored for a
+// refresh token.
+func newTestSession(user db.User, refreshToken string, payload *token.Payload) db.Session {
+	return db.Session{
+		ID:           payload.ID,
+		Username:     user.Username,
+		RefreshToken: refreshToken,
+		ExpiresAt:    payload.ExpiredAt,
+		CreatedAt:    payload.IssuedAt,
+	}
+}
+
+func TestIntrospectToken(t *testing.T) {
+	admin, _ := randomUser(t, roleAdmin)
+	customer, _ := randomUser(t, roleCustomer)
+
+	testCases := []struct {
+		name          string
+		caller        db.User
+		token         func(token string) string
+		buildStubs    func(store *mockdb.MockStore, payload *token.Payload)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:   "Active",
+			caller: admin,
+			buildStubs: func(store *mockdb.MockStore, payload *token.Payload) {
+				expectAuthenticated(store, admin)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(customer.Username)).Times(1).Return(customer, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				rsp := decodeIntrospection(t, recorder)
+				require.True(t, rsp.Active)
+				require.Equal(t, customer.Username, rsp.Username)
+				require.Equal(t, string(token.TokenTypeAccess), rsp.TokenType)
+			},
+		},
+		{
+			name:   "Revoked",
+			caller: admin,
+			buildStubs: func(store *mockdb.MockStore, payload *token.Payload) {
+				store.EXPECT().IsTokenRevoked(gomock.Any(), gomock.Eq(payload.ID)).Times(1).Return(true, nil)
+				expectAuthenticated(store, admin)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				rsp := decodeIntrospection(t, recorder)
+				require.False(t, rsp.Active)
+				require.True(t, rsp.Revoked)
+			},
+		},
+		{
+			name:   "UserDeleted",
+			caller: admin,
+			buildStubs: func(store *mockdb.MockStore, payload *token.Payload) {
+				deleted := customer
+				deleted.DeletedAt = sql.NullTime{Time: time.Now(), Valid: true}
+				expectAuthenticated(store, admin)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(customer.Username)).Times(1).Return(deleted, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				rsp := decodeIntrospection(t, recorder)
+				require.False(t, rsp.Active)
+				require.False(t, rsp.Revoked)
+			},
+		},
+		{
+			name:   "InvalidToken",
+			caller: admin,
+			token: func(token string) string {
+				return token + "x"
+			},
+			buildStubs: func(store *mockdb.MockStore, payload *token.Payload) {
+				expectAuthenticated(store, admin)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.False(t, decodeIntrospection(t, recorder).Active)
+			},
+		},
+		{
+			name:   "NotAdmin",
+			caller: customer,
+			buildStubs: func(store *mockdb.MockStore, payload *token.Payload) {
+				expectAuthenticated(store, customer)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			server := newTestServerWithStore(t, store)
+
+			introspected, payload, err := server.tokenMaker.CreateToken(customer.Username, customer.Role, token.TokenTypeAccess, time.Minute)
+			require.NoError(t, err)
+			if tc.token != nil {
+				introspected = tc.token(introspected)
+			}
+			tc.buildStubs(store, payload)
+
+			body := introspectTokenRequest{Token: introspected}
+			recorder := doRequest(t, server, tc.caller, http.MethodPost, "/tokens/introspect", body)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestIntrospectTokenUnauthenticated(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	server := newTestServerWithStore(t, store)
+
+	body := introspectTokenRequest{Token: randomString(32)}
+	recorder := doPublicRequest(t, server, http.MethodPost, "/tokens/introspect", body)
+	require.Equal(t, http.StatusUnauthorized, recorder.Code)
+}
+
+func decodeIntrospection(t *testing.T, recorder *httptest.ResponseRecorder) introspectTokenResponse {
+	var rsp introspectTokenResponse
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+	return rsp
+}
@@ -0,0 +1,298 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	db "github.com/ferueda/simplebank-go/db/sqlc"
+	"github.com/ferueda/simplebank-go/token"
+	"github.com/ferueda/simplebank-go/totp"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const maxMFAAttempts = 5
+
+var errInvalidMFACode = errors.New("invalid two-factor authentication code")
+
+type enrollTotpResponse struct {
+	Secret        string   `json:"secret"`
+	URL           string   `json:"url"`
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+func (s *Server) enrollTotp(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authPayloadKey).(*token.Payload)
+
+	existing, err := s.store.GetUserTotp(ctx, authPayload.Username)
+	if err != nil && err != sql.ErrNoRows {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if err == nil && existing.IsEnabled {
+		err := errors.New("two-factor authentication is already enabled")
+		ctx.JSON(http.StatusForbidden, errorResponse(err))
+		return
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	encryptedSecret, err := s.totpCipher.Encrypt(secret)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	recoveryCodes, err := totp.GenerateRecoveryCodes()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	hashedCodes := make([]string, len(recoveryCodes))
+	for i, code := range recoveryCodes {
+		hashedCodes[i] = totp.HashRecoveryCode(code)
+	}
+
+	arg := db.EnrollTotpTxParams{
+		Username:            authPayload.Username,
+		EncryptedSecret:     encryptedSecret,
+		HashedRecoveryCodes: hashedCodes,
+	}
+
+	_, err = s.store.EnrollTotpTx(ctx, arg)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	resp := enrollTotpResponse{
+		Secret:        secret,
+		URL:           totp.URL(s.config.TOTPIssuer, authPayload.Username, secret),
+		RecoveryCodes: recoveryCodes,
+	}
+
+	ctx.JSON(http.StatusCreated, resp)
+}
+
+type confirmTotpRequest struct {
+	Code string `json:"code" binding:"required,numeric,len=6"`
+}
+
+func (s *Server) confirmTotp(ctx *gin.Context) {
+	var req confirmTotpRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authPayloadKey).(*token.Payload)
+
+	userTotp, err := s.store.GetUserTotp(ctx, authPayload.Username)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	secret, err := s.totpCipher.Decrypt(userTotp.EncryptedSecret)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	step, ok := totp.Validate(req.Code, secret, time.Now())
+	if !ok {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(errInvalidMFACode))
+		return
+	}
+
+	arg := db.EnableUserTotpParams{
+		Username:     authPayload.Username,
+		LastUsedStep: step,
+	}
+
+	_, err = s.store.EnableUserTotp(ctx, arg)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+type disableTotpRequest struct {
+	Password string `json:"password" binding:"required,min=6"`
+}
+
+func (s *Server) disableTotp(ctx *gin.Context) {
+	var req disableTotpRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authPayloadKey).(*token.Payload)
+
+	user, err := s.store.GetUser(ctx, authPayload.Username)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if err = db.ValidateHashedPassword(req.Password, user.HashedPassword); err != nil {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		return
+	}
+
+	err = s.store.DisableTotpTx(ctx, user.Username)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+type mfaChallengeResponse struct {
+	MFARequired       bool      `json:"mfa_required"`
+	MFAToken          uuid.UUID `json:"mfa_token"`
+	MFATokenExpiresAt time.Time `json:"mfa_token_expires_at"`
+}
+
+type verifyLoginMFARequest struct {
+	MFAToken uuid.UUID `json:"mfa_token" binding:"required"`
+	Code     string    `json:"code" binding:"required"`
+}
+
+func (s *Server) verifyLoginMFA(ctx *gin.Context) {
+	var req verifyLoginMFARequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	challenge, err := s.store.IncrementMfaChallengeAttempts(ctx, req.MFAToken)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusUnauthorized, errorResponse(errors.New("invalid mfa token")))
+			return
+		}
+
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if time.Now().After(challenge.ExpiresAt) || challenge.Attempts > maxMFAAttempts {
+		if err = s.store.DeleteMfaChallenge(ctx, challenge.ID); err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+
+		ctx.JSON(http.StatusUnauthorized, errorResponse(errors.New("mfa token has expired")))
+		return
+	}
+
+	if !s.checkLoginThrottle(ctx, challenge.Username) {
+		return
+	}
+
+	ok, err := s.checkMFACode(ctx, challenge.Username, req.Code)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if !ok {
+		if err := s.recordLoginFailure(ctx, challenge.Username); err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+
+		ctx.JSON(http.StatusUnauthorized, errorResponse(errInvalidMFACode))
+		return
+	}
+
+	if err = s.store.DeleteMfaChallenge(ctx, challenge.ID); err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if err = s.resetLoginThrottle(ctx, challenge.Username); err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	user, err := s.store.GetUser(ctx, challenge.Username)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusUnauthorized, errorResponse(errors.New("invalid mfa token")))
+			return
+		}
+
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	resp, err := s.createUserSession(ctx, user)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, resp)
+}
+
+// checkMFACode accepts either a current TOTP code, which may only be used
+// once, or an unused recovery code. A user who is no longer enrolled has no
+// valid codes.
+func (s *Server) checkMFACode(ctx *gin.Context, username string, code string) (bool, error) {
+	userTotp, err := s.store.GetUserTotp(ctx, username)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	secret, err := s.totpCipher.Decrypt(userTotp.EncryptedSecret)
+	if err != nil {
+		return false, err
+	}
+
+	if step, ok := totp.Validate(code, secret, time.Now()); ok {
+		_, err = s.store.UpdateUserTotpLastUsedStep(ctx, db.UpdateUserTotpLastUsedStepParams{
+			Step:     step,
+			Username: username,
+		})
+		if err == sql.ErrNoRows {
+			return false, nil
+		}
+		return err == nil, err
+	}
+
+	_, err = s.store.UseTotpRecoveryCode(ctx, db.UseTotpRecoveryCodeParams{
+		Username:   username,
+		HashedCode: totp.HashRecoveryCode(code),
+	})
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return err == nil, err
+}
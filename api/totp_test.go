@@ -0,0 +1,392 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mockdb "github.com/ferueda/simplebank-go/db/mock"
+	db "github.com/ferueda/simplebank-go/db/sqlc"
+	"github.com/ferueda/simplebank-go/totp"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoginUserMFARequired(t *testing.T) {
+	user, password := randomUser(t, roleCustomer)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	server := newTestServerWithStore(t, store)
+	userTotp := newTestUserTotp(t, server, user)
+
+	challenge := db.MfaChallenge{ID: uuid.New(), Username: user.Username, ExpiresAt: time.Now().Add(time.Minute)}
+
+	expectLoginAllowed(store)
+	store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+	store.EXPECT().DeleteLoginThrottle(gomock.Any(), gomock.Any()).Times(0)
+	store.EXPECT().GetUserTotp(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(userTotp, nil)
+	store.EXPECT().CreateMfaChallenge(gomock.Any(), gomock.Any()).Times(1).Return(challenge, nil)
+	store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(0)
+
+	body := loginUserRequest{Username: user.Username, Password: password}
+	recorder := doPublicRequest(t, server, http.MethodPost, "/users/login", body)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var rsp mfaChallengeResponse
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+	require.True(t, rsp.MFARequired)
+	require.Equal(t, challenge.ID, rsp.MFAToken)
+}
+
+func TestVerifyLoginMFA(t *testing.T) {
+	user, _ := randomUser(t, roleCustomer)
+	recoveryCode := randomString(10)
+
+	testCases := []struct {
+		name          string
+		code          func(t *testing.T, secret string) string
+		buildStubs    func(store *mockdb.MockStore, challenge db.MfaChallenge, userTotp db.UserTotp)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "TOTPCode",
+			code: currentTOTPCode,
+			buildStubs: func(store *mockdb.MockStore, challenge db.MfaChallenge, userTotp db.UserTotp) {
+				store.EXPECT().IncrementMfaChallengeAttempts(gomock.Any(), gomock.Eq(challenge.ID)).Times(1).Return(challenge, nil)
+				expectLoginAllowed(store)
+				store.EXPECT().GetUserTotp(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(userTotp, nil)
+				store.EXPECT().UpdateUserTotpLastUsedStep(gomock.Any(), gomock.Any()).Times(1).Return(userTotp, nil)
+				expectMFALoginCompleted(store, user, challenge)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp loginUserResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.NotEmpty(t, rsp.AccessToken)
+				require.NotEmpty(t, rsp.RefreshToken)
+			},
+		},
+		{
+			name: "RecoveryCode",
+			code: func(t *testing.T, secret string) string {
+				return recoveryCode
+			},
+			buildStubs: func(store *mockdb.MockStore, challenge db.MfaChallenge, userTotp db.UserTotp) {
+				arg := db.UseTotpRecoveryCodeParams{Username: user.Username, HashedCode: totp.HashRecoveryCode(recoveryCode)}
+				store.EXPECT().IncrementMfaChallengeAttempts(gomock.Any(), gomock.Eq(challenge.ID)).Times(1).Return(challenge, nil)
+				expectLoginAllowed(store)
+				store.EXPECT().GetUserTotp(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(userTotp, nil)
+				store.EXPECT().UseTotpRecoveryCode(gomock.Any(), gomock.Eq(arg)).Times(1).Return(db.TotpRecoveryCode{}, nil)
+				expectMFALoginCompleted(store, user, challenge)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "ReplayedTOTPCode",
+			code: currentTOTPCode,
+			buildStubs: func(store *mockdb.MockStore, challenge db.MfaChallenge, userTotp db.UserTotp) {
+				store.EXPECT().IncrementMfaChallengeAttempts(gomock.Any(), gomock.Eq(challenge.ID)).Times(1).Return(challenge, nil)
+				expectLoginAllowed(store)
+				store.EXPECT().GetUserTotp(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(userTotp, nil)
+				store.EXPECT().UpdateUserTotpLastUsedStep(gomock.Any(), gomock.Any()).Times(1).Return(db.UserTotp{}, sql.ErrNoRows)
+				expectLoginFailure(store)
+				store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidCode",
+			code: func(t *testing.T, secret string) string {
+				return "000000x"
+			},
+			buildStubs: func(store *mockdb.MockStore, challenge db.MfaChallenge, userTotp db.UserTotp) {
+				store.EXPECT().IncrementMfaChallengeAttempts(gomock.Any(), gomock.Eq(challenge.ID)).Times(1).Return(challenge, nil)
+				expectLoginAllowed(store)
+				store.EXPECT().GetUserTotp(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(userTotp, nil)
+				store.EXPECT().UseTotpRecoveryCode(gomock.Any(), gomock.Any()).Times(1).Return(db.TotpRecoveryCode{}, sql.ErrNoRows)
+				expectLoginFailure(store)
+				store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "NotEnrolled",
+			code: currentTOTPCode,
+			buildStubs: func(store *mockdb.MockStore, challenge db.MfaChallenge, userTotp db.UserTotp) {
+				store.EXPECT().IncrementMfaChallengeAttempts(gomock.Any(), gomock.Eq(challenge.ID)).Times(1).Return(challenge, nil)
+				expectLoginAllowed(store)
+				store.EXPECT().GetUserTotp(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(db.UserTotp{}, sql.ErrNoRows)
+				expectLoginFailure(store)
+				store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "UserNotFound",
+			code: currentTOTPCode,
+			buildStubs: func(store *mockdb.MockStore, challenge db.MfaChallenge, userTotp db.UserTotp) {
+				store.EXPECT().IncrementMfaChallengeAttempts(gomock.Any(), gomock.Eq(challenge.ID)).Times(1).Return(challenge, nil)
+				expectLoginAllowed(store)
+				store.EXPECT().GetUserTotp(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(userTotp, nil)
+				store.EXPECT().UpdateUserTotpLastUsedStep(gomock.Any(), gomock.Any()).Times(1).Return(userTotp, nil)
+				store.EXPECT().DeleteMfaChallenge(gomock.Any(), gomock.Eq(challenge.ID)).Times(1).Return(nil)
+				store.EXPECT().DeleteLoginThrottle(gomock.Any(), gomock.Any()).Times(1).Return(nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(db.User{}, sql.ErrNoRows)
+				store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "LockedOut",
+			code: currentTOTPCode,
+			buildStubs: func(store *mockdb.MockStore, challenge db.MfaChallenge, userTotp db.UserTotp) {
+				throttle := db.LoginThrottle{FailedAttempts: 5, LockedUntil: time.Now().Add(time.Minute)}
+				store.EXPECT().IncrementMfaChallengeAttempts(gomock.Any(), gomock.Eq(challenge.ID)).Times(1).Return(challenge, nil)
+				store.EXPECT().GetLoginThrottle(gomock.Any(), gomock.Any()).Times(1).Return(throttle, nil)
+				store.EXPECT().GetUserTotp(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+			},
+		},
+		{
+			name: "TooManyAttempts",
+			code: currentTOTPCode,
+			buildStubs: func(store *mockdb.MockStore, challenge db.MfaChallenge, userTotp db.UserTotp) {
+				challenge.Attempts = maxMFAAttempts + 1
+				store.EXPECT().IncrementMfaChallengeAttempts(gomock.Any(), gomock.Eq(challenge.ID)).Times(1).Return(challenge, nil)
+				store.EXPECT().DeleteMfaChallenge(gomock.Any(), gomock.Eq(challenge.ID)).Times(1).Return(nil)
+				store.EXPECT().GetUserTotp(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "UnknownChallenge",
+			code: currentTOTPCode,
+			buildStubs: func(store *mockdb.MockStore, challenge db.MfaChallenge, userTotp db.UserTotp) {
+				store.EXPECT().IncrementMfaChallengeAttempts(gomock.Any(), gomock.Eq(challenge.ID)).Times(1).Return(db.MfaChallenge{}, sql.ErrNoRows)
+				store.EXPECT().GetUserTotp(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			server := newTestServerWithStore(t, store)
+			userTotp := newTestUserTotp(t, server, user)
+
+			secret, err := server.totpCipher.Decrypt(userTotp.EncryptedSecret)
+			require.NoError(t, err)
+
+			challenge := db.MfaChallenge{
+				ID:        uuid.New(),
+				Username:  user.Username,
+				Attempts:  1,
+				ExpiresAt: time.Now().Add(time.Minute),
+			}
+			tc.buildStubs(store, challenge, userTotp)
+
+			body := verifyLoginMFARequest{MFAToken: challenge.ID, Code: tc.code(t, secret)}
+			recorder := doPublicRequest(t, server, http.MethodPost, "/users/login/mfa", body)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestConfirmTotp(t *testing.T) {
+	user, _ := randomUser(t, roleCustomer)
+
+	testCases := []struct {
+		name       string
+		code       func(t *testing.T, secret string) string
+		buildStubs func(store *mockdb.MockStore, userTotp db.UserTotp)
+		status     int
+	}{
+		{
+			name: "OK",
+			code: currentTOTPCode,
+			buildStubs: func(store *mockdb.MockStore, userTotp db.UserTotp) {
+				store.EXPECT().GetUserTotp(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(userTotp, nil)
+				store.EXPECT().EnableUserTotp(gomock.Any(), gomock.Any()).Times(1).Return(userTotp, nil)
+			},
+			status: http.StatusNoContent,
+		},
+		{
+			name: "InvalidCode",
+			code: func(t *testing.T, secret string) string {
+				return "000000"
+			},
+			buildStubs: func(store *mockdb.MockStore, userTotp db.UserTotp) {
+				store.EXPECT().GetUserTotp(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(userTotp, nil)
+				store.EXPECT().EnableUserTotp(gomock.Any(), gomock.Any()).Times(0)
+			},
+			status: http.StatusUnauthorized,
+		},
+		{
+			name: "NotEnrolled",
+			code: currentTOTPCode,
+			buildStubs: func(store *mockdb.MockStore, userTotp db.UserTotp) {
+				store.EXPECT().GetUserTotp(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(db.UserTotp{}, sql.ErrNoRows)
+				store.EXPECT().EnableUserTotp(gomock.Any(), gomock.Any()).Times(0)
+			},
+			status: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			server := newTestServerWithStore(t, store)
+			userTotp := newTestUserTotp(t, server, user)
+			userTotp.IsEnabled = false
+
+			secret, err := server.totpCipher.Decrypt(userTotp.EncryptedSecret)
+			require.NoError(t, err)
+
+			expectAuthenticated(store, user)
+			tc.buildStubs(store, userTotp)
+
+			body := confirmTotpRequest{Code: tc.code(t, secret)}
+			recorder := doRequest(t, server, user, http.MethodPost, "/users/totp/confirm", body)
+			require.Equal(t, tc.status, recorder.Code)
+		})
+	}
+}
+
+func TestEnrollTotpAlreadyEnabled(t *testing.T) {
+	user, _ := randomUser(t, roleCustomer)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	server := newTestServerWithStore(t, store)
+
+	expectAuthenticated(store, user)
+	store.EXPECT().GetUserTotp(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(newTestUserTotp(t, server, user), nil)
+	store.EXPECT().EnrollTotpTx(gomock.Any(), gomock.Any()).Times(0)
+
+	recorder := doRequest(t, server, user, http.MethodPost, "/users/totp", nil)
+	require.Equal(t, http.StatusForbidden, recorder.Code)
+}
+
+func TestDisableTotp(t *testing.T) {
+	user, password := randomUser(t, roleCustomer)
+
+	testCases := []struct {
+		name       string
+		password   string
+		buildStubs func(store *mockdb.MockStore)
+		status     int
+	}{
+		{
+			name:     "OK",
+			password: password,
+			buildStubs: func(store *mockdb.MockStore) {
+				expectAuthenticated(store, user)
+				store.EXPECT().DisableTotpTx(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(nil)
+			},
+			status: http.StatusNoContent,
+		},
+		{
+			name:     "WrongPassword",
+			password: "wrong-password",
+			buildStubs: func(store *mockdb.MockStore) {
+				expectAuthenticated(store, user)
+				store.EXPECT().DisableTotpTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			status: http.StatusUnauthorized,
+		},
+		{
+			name:     "UserNotFound",
+			password: password,
+			buildStubs: func(store *mockdb.MockStore) {
+				// The user is deleted between authentication and the lookup
+				// in the handler.
+				store.EXPECT().IsTokenRevoked(gomock.Any(), gomock.Any()).AnyTimes().Return(false, nil)
+				gomock.InOrder(
+					store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil),
+					store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(db.User{}, sql.ErrNoRows),
+				)
+				store.EXPECT().DisableTotpTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			status: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			server := newTestServerWithStore(t, store)
+			tc.buildStubs(store)
+
+			body := disableTotpRequest{Password: tc.password}
+			recorder := doRequest(t, server, user, http.MethodDelete, "/users/totp", body)
+			require.Equal(t, tc.status, recorder.Code)
+		})
+	}
+}
+
+// newTestUserTotp returns an enabled TOTP enrollment for user, with a
+// secret encrypted by the server's cipher.
+func newTestUserTotp(t *testing.T, server *Server, user db.User) db.UserTotp {
+	secret, err := totp.GenerateSecret()
+	require.NoError(t, err)
+
+	encryptedSecret, err := server.totpCipher.Encrypt(secret)
+	require.NoError(t, err)
+
+	return db.UserTotp{
+		Username:        user.Username,
+		EncryptedSecret: encryptedSecret,
+		IsEnabled:       true,
+	}
+}
+
+func currentTOTPCode(t *testing.T, secret string) string {
+	code, err := totp.GenerateCode(secret, time.Now())
+	require.NoError(t, err)
+	return code
+}
+
+// expectMFALoginCompleted stubs what follows an accepted second factor: the
+// challenge is consumed, the login throttle reset and a session opened.
+func expectMFALoginCompleted(store *mockdb.MockStore, user db.User, challenge db.MfaChallenge) {
+	store.EXPECT().DeleteMfaChallenge(gomock.Any(), gomock.Eq(challenge.ID)).Times(1).Return(nil)
+	store.EXPECT().DeleteLoginThrottle(gomock.Any(), gomock.Any()).Times(1).Return(nil)
+	store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+	store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(1).Return(db.Session{}, nil)
+}
@@ -2,20 +2,61 @@ package api
 
 import (
 	"database/sql"
+	"encoding/json"
 	"errors"
-	"fmt"
 	"net/http"
+	"time"
 
 	db "github.com/ferueda/simplebank-go/db/sqlc"
+	"github.com/ferueda/simplebank-go/exchange"
 	"github.com/ferueda/simplebank-go/token"
 	"github.com/gin-gonic/gin"
 )
 
+var errAmountTooSmall = errors.New("amount is too small to convert")
+
+type transferResponse struct {
+	ID            int64     `json:"id"`
+	FromAccountID int64     `json:"from_account_id"`
+	ToAccountID   int64     `json:"to_account_id"`
+	Amount        int64     `json:"amount"`
+	ToAmount      int64     `json:"to_amount"`
+	ExchangeRate  string    `json:"exchange_rate"`
+	Spread        string    `json:"spread"`
+	ReversalOf    *int64    `json:"reversal_of"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+func newTransferResponse(transfer db.Transfer) transferResponse {
+	rsp := transferResponse{
+		ID:            transfer.ID,
+		FromAccountID: transfer.FromAccountID,
+		ToAccountID:   transfer.ToAccountID,
+		Amount:        transfer.Amount,
+		ToAmount:      transfer.ToAmount,
+		ExchangeRate:  transfer.ExchangeRate,
+		Spread:        transfer.Spread,
+		CreatedAt:     transfer.CreatedAt,
+	}
+	if transfer.ReversalOf.Valid {
+		rsp.ReversalOf = &transfer.ReversalOf.Int64
+	}
+	return rsp
+}
+
+func newTransferResponses(transfers []db.Transfer) []transferResponse {
+	data := make([]transferResponse, len(transfers))
+	for i, t := range transfers {
+		data[i] = newTransferResponse(t)
+	}
+	return data
+}
+
 type transferRequest struct {
 	FromAccountId int64  `json:"from_account_id" binding:"required,min=1"`
 	ToAccountId   int64  `json:"to_account_id" binding:"required,min=1"`
 	Amount        int64  `json:"amount" binding:"required,gt=0"`
-	Currency      string `json:"currency" binding:"required,oneof=CAD USD"`
+	Currency      string `json:"currency" binding:"required"`
 }
 
 func (s *Server) createTransfer(ctx *gin.Context) {
@@ -25,40 +66,183 @@ func (s *Server) createTransfer(ctx *gin.Context) {
 		return
 	}
 
-	fromAcc, isValid := s.validateAccount(ctx, req.FromAccountId, req.Currency)
-	if !isValid {
+	if !s.checkCurrency(ctx, req.Currency) {
 		return
 	}
 
 	authPayload := ctx.MustGet(authPayloadKey).(*token.Payload)
-	if fromAcc.Owner != authPayload.Username {
-		err := errors.New("wrong origin account")
-		ctx.JSON(http.StatusForbidden, errorResponse(err))
+
+	idempotency, err := s.idempotencyParams(ctx, authPayload.Username, req)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
 		return
 	}
 
-	if !s.validateFunds(ctx, req.FromAccountId, req.Amount) {
+	if idempotency != nil && s.replayIdempotentRequest(ctx, *idempotency, s.replayTransfer(ctx)) {
 		return
 	}
 
-	_, isValid = s.validateAccount(ctx, req.ToAccountId, req.Currency)
-	if !isValid {
+	fromAcc, ok := s.authorizeAccount(ctx, req.FromAccountId, accountWrite)
+	if !ok {
+		return
+	}
+
+	if fromAcc.Currency != req.Currency {
+		ctx.JSON(http.StatusBadRequest, errorResponse(db.ErrCurrencyMismatch))
+		return
+	}
+
+	if !s.checkTransferPolicy(ctx, authPayload.Username) {
 		return
 	}
 
 	arg := db.TransferTxParams{
-		FromAccountID: req.FromAccountId,
-		ToAccountID:   req.ToAccountId,
-		Amount:        req.Amount,
+		FromAccountID:        req.FromAccountId,
+		ToAccountID:          req.ToAccountId,
+		Amount:               req.Amount,
+		Currency:             req.Currency,
+		AllowFrozenRecipient: s.config.FrozenAccountsAcceptCredits,
+		Idempotency:          idempotency,
+	}
+
+	if !s.convertTransfer(ctx, &arg) {
+		return
 	}
 
 	transfer, err := s.store.TransferTx(ctx, arg)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		// A concurrent request with the same key committed first; answer
+		// with its result rather than moving the money a second time.
+		if err == db.ErrIdempotencyKeyInUse && s.replayIdempotentRequest(ctx, *idempotency, s.replayTransfer(ctx)) {
+			return
+		}
+
+		switch err {
+		case db.ErrInsufficientFunds, db.ErrCurrencyMismatch:
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		case db.ErrAccountNotActive, db.ErrAccountFrozen:
+			ctx.JSON(http.StatusForbidden, errorResponse(err))
+		case db.ErrIdempotencyKeyInUse:
+			ctx.JSON(http.StatusUnprocessableEntity, errorResponse(err))
+		case sql.ErrNoRows:
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+		default:
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		}
 		return
 	}
 
-	ctx.JSON(http.StatusCreated, transfer)
+	ctx.JSON(http.StatusCreated, s.newTransferTxResponse(ctx, transfer))
+}
+
+// replayTransfer answers a retried transfer with the result stored under its
+// idempotency key. Only transfers that went through are stored.
+func (s *Server) replayTransfer(ctx *gin.Context) func(response json.RawMessage) {
+	return func(response json.RawMessage) {
+		var result db.TransferTxResult
+		if err := json.Unmarshal(response, &result); err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+
+		ctx.JSON(http.StatusCreated, s.newTransferTxResponse(ctx, result))
+	}
+}
+
+// checkTransferPolicy applies the server's rules on who may send money. When
+// it returns false the error response has already been written.
+func (s *Server) checkTransferPolicy(ctx *gin.Context, username string) bool {
+	if !s.config.TransferRequiresVerifiedEmail {
+		return true
+	}
+
+	user, err := s.store.GetUser(ctx, username)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return false
+	}
+
+	if !user.IsEmailVerified {
+		ctx.JSON(http.StatusForbidden, errorResponse(errEmailNotVerified))
+		return false
+	}
+
+	return true
+}
+
+// convertTransfer fills in the credit side of a transfer to an account held
+// in another currency, at the current rate less the configured spread. When
+// it returns false the error response has already been written.
+func (s *Server) convertTransfer(ctx *gin.Context, arg *db.TransferTxParams) bool {
+	toAcc, err := s.store.GetAccount(ctx, arg.ToAccountID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return false
+		}
+
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return false
+	}
+
+	if toAcc.Currency == arg.Currency {
+		return true
+	}
+
+	quote, err := exchange.NewQuote(ctx, s.rates, arg.Currency, toAcc.Currency, s.spread)
+	if err != nil {
+		if errors.Is(err, exchange.ErrRateNotFound) {
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+			return false
+		}
+
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return false
+	}
+
+	fromMinorUnits, ok := s.transferMinorUnits(ctx, arg.Currency)
+	if !ok {
+		return false
+	}
+
+	toMinorUnits, ok := s.transferMinorUnits(ctx, toAcc.Currency)
+	if !ok {
+		return false
+	}
+
+	arg.ToCurrency = toAcc.Currency
+	arg.ToAmount, err = quote.ConvertMinorUnits(arg.Amount, fromMinorUnits, toMinorUnits)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return false
+	}
+
+	arg.ExchangeRate = quote.RateString()
+	arg.Spread = quote.SpreadString()
+
+	if arg.ToAmount <= 0 {
+		ctx.JSON(http.StatusBadRequest, errorResponse(errAmountTooSmall))
+		return false
+	}
+
+	return true
+}
+
+// transferMinorUnits looks up the exponent used to scale a converted amount.
+// When it returns false the error response has already been written.
+func (s *Server) transferMinorUnits(ctx *gin.Context, currency string) (int32, bool) {
+	minorUnits, err := s.currencies.minorUnits(ctx, currency)
+	if err != nil {
+		if errors.Is(err, errUnknownCurrency) {
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+			return 0, false
+		}
+
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return 0, false
+	}
+
+	return minorUnits, true
 }
 
 type getTransferRequest struct {
@@ -78,9 +262,132 @@ func (s *Server) getTransfer(ctx *gin.Context) {
 			ctx.JSON(http.StatusNotFound, errorResponse(err))
 			return
 		}
+
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if !s.authorizeTransfer(ctx, transfer) {
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newTransferResponse(transfer))
+}
+
+type reverseTransferRequest struct {
+	Amount int64 `json:"amount" binding:"omitempty,gt=0"`
+}
+
+// reverseTransfer refunds a transfer to its sender, taking the money back
+// from the recipient. Only the recipient can reverse a transfer. Without an
+// amount, all that is left of the transfer is refunded.
+func (s *Server) reverseTransfer(ctx *gin.Context) {
+	var uri getTransferRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	// The body is optional.
+	var req reverseTransferRequest
+	if ctx.Request.ContentLength != 0 {
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+			return
+		}
+	}
+
+	authPayload := ctx.MustGet(authPayloadKey).(*token.Payload)
+
+	arg := db.ReverseTransferTxParams{
+		TransferID:           uri.ID,
+		Amount:               req.Amount,
+		AllowFrozenRecipient: s.config.FrozenAccountsAcceptCredits,
+	}
+
+	idempotency, err := s.idempotencyParams(ctx, authPayload.Username, arg)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if idempotency != nil && s.replayIdempotentRequest(ctx, *idempotency, s.replayTransfer(ctx)) {
+		return
+	}
+	arg.Idempotency = idempotency
+
+	transfer, err := s.store.GetTransfer(ctx, uri.ID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if _, ok := s.authorizeAccount(ctx, transfer.ToAccountID, accountWrite); !ok {
+		return
+	}
+
+	if !s.checkTransferPolicy(ctx, authPayload.Username) {
+		return
+	}
+
+	result, err := s.store.ReverseTransferTx(ctx, arg)
+	if err != nil {
+		if err == db.ErrIdempotencyKeyInUse && s.replayIdempotentRequest(ctx, *idempotency, s.replayTransfer(ctx)) {
+			return
+		}
+
+		switch err {
+		case db.ErrInsufficientFunds, db.ErrReversalExceedsTransfer, db.ErrReversalTooSmall:
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		case db.ErrAccountNotActive, db.ErrAccountFrozen, db.ErrTransferIsReversal, db.ErrTransferReversed:
+			ctx.JSON(http.StatusForbidden, errorResponse(err))
+		case db.ErrIdempotencyKeyInUse:
+			ctx.JSON(http.StatusUnprocessableEntity, errorResponse(err))
+		case sql.ErrNoRows:
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+		default:
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, s.newTransferTxResponse(ctx, result))
+}
+
+func (s *Server) listTransferReversals(ctx *gin.Context) {
+	var req getTransferRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	transfer, err := s.store.GetTransfer(ctx, req.ID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
 	}
 
-	ctx.JSON(http.StatusOK, transfer)
+	if !s.authorizeTransfer(ctx, transfer) {
+		return
+	}
+
+	reversals, err := s.store.ListTransferReversals(ctx, sql.NullInt64{Int64: transfer.ID, Valid: true})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"data": newTransferResponses(reversals)})
 }
 
 type listTransfersRequest struct {
@@ -102,6 +409,18 @@ func (s *Server) listTransfers(ctx *gin.Context) {
 		return
 	}
 
+	// Both filters match independently, so the caller must be allowed to
+	// see every account they name.
+	for _, accountID := range []int64{req.FromAccountId, req.ToAccountId} {
+		if accountID == 0 {
+			continue
+		}
+
+		if _, ok := s.authorizeAccount(ctx, accountID, accountRead); !ok {
+			return
+		}
+	}
+
 	switch {
 	case req.Limit <= 0:
 		req.Limit = 20
@@ -137,48 +456,6 @@ func (s *Server) listTransfers(ctx *gin.Context) {
 			"count":  len(transfers),
 			"offset": req.Offset,
 		},
-		"data": transfers,
+		"data": newTransferResponses(transfers),
 	})
 }
-
-func (s *Server) validateAccount(ctx *gin.Context, accountId int64, currency string) (db.Account, bool) {
-	account, err := s.store.GetAccount(ctx, accountId)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			ctx.JSON(http.StatusNotFound, errorResponse(err))
-			return account, false
-		}
-
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
-		return account, false
-	}
-
-	if account.Currency != currency {
-		err := fmt.Errorf("account [%d] currency mismatch: transaction must be in %s", accountId, account.Currency)
-		ctx.JSON(http.StatusBadRequest, errorResponse(err))
-		return account, false
-	}
-
-	return account, true
-}
-
-func (s *Server) validateFunds(ctx *gin.Context, accountId, amount int64) bool {
-	account, err := s.store.GetAccount(ctx, accountId)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			ctx.JSON(http.StatusNotFound, errorResponse(err))
-			return false
-		}
-
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
-		return false
-	}
-
-	if account.Balance < amount {
-		err := fmt.Errorf("not enough funds in account [%d]", accountId)
-		ctx.JSON(http.StatusBadRequest, errorResponse(err))
-		return false
-	}
-
-	return true
-}
@@ -0,0 +1,378 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mockdb "github.com/ferueda/simplebank-go/db/mock"
+	db "github.com/ferueda/simplebank-go/db/sqlc"
+	"github.com/ferueda/simplebank-go/token"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetTransferOwnership(t *testing.T) {
+	server := newTestServer(t)
+	sender := createTestUser(t, roleCustomer)
+	recipient := createTestUser(t, roleCustomer)
+	other := createTestUser(t, roleCustomer)
+	fromAcc := createTestAccount(t, sender)
+	toAcc := createTestAccount(t, recipient)
+
+	transfer, err := testStore.CreateTransfer(context.Background(), db.CreateTransferParams{
+		FromAccountID: fromAcc.ID,
+		ToAccountID:   toAcc.ID,
+		Amount:        10,
+		ToAmount:      10,
+		ExchangeRate:  "1",
+		Spread:        "0",
+	})
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name       string
+		user       db.User
+		transferID int64
+		status     int
+	}{
+		{name: "Sender", user: sender, transferID: transfer.ID, status: http.StatusOK},
+		{name: "Recipient", user: recipient, transferID: transfer.ID, status: http.StatusOK},
+		{name: "OtherUser", user: other, transferID: transfer.ID, status: http.StatusForbidden},
+		{name: "NotFound", user: sender, transferID: missingID, status: http.StatusNotFound},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			url := fmt.Sprintf("/transfers/%d", tc.transferID)
+			recorder := doRequest(t, server, tc.user, http.MethodGet, url, nil)
+			require.Equal(t, tc.status, recorder.Code)
+		})
+	}
+}
+
+func TestListTransfersOwnership(t *testing.T) {
+	server := newTestServer(t)
+	owner := createTestUser(t, roleCustomer)
+	other := createTestUser(t, roleCustomer)
+	ownAcc := createTestAccount(t, owner)
+	otherAcc := createTestAccount(t, other)
+
+	testCases := []struct {
+		name   string
+		query  string
+		status int
+	}{
+		{name: "OwnAccount", query: fmt.Sprintf("from=%d", ownAcc.ID), status: http.StatusOK},
+		{name: "OtherAccount", query: fmt.Sprintf("to=%d", otherAcc.ID), status: http.StatusForbidden},
+		{name: "MixedAccounts", query: fmt.Sprintf("from=%d&to=%d", ownAcc.ID, otherAcc.ID), status: http.StatusForbidden},
+		{name: "NotFound", query: fmt.Sprintf("from=%d", missingID), status: http.StatusNotFound},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := doRequest(t, server, owner, http.MethodGet, "/transfers?"+tc.query, nil)
+			require.Equal(t, tc.status, recorder.Code)
+		})
+	}
+}
+
+func TestCreateTransferOwnership(t *testing.T) {
+	server := newTestServer(t)
+	owner := createTestUser(t, roleCustomer)
+	other := createTestUser(t, roleCustomer)
+	ownAcc := createTestAccount(t, owner)
+	otherAcc := createTestAccount(t, other)
+
+	body := transferRequest{
+		FromAccountId: otherAcc.ID,
+		ToAccountId:   ownAcc.ID,
+		Amount:        10,
+		Currency:      "CAD",
+	}
+
+	recorder := doRequest(t, server, owner, http.MethodPost, "/transfers", body)
+	require.Equal(t, http.StatusForbidden, recorder.Code)
+
+	account, err := testStore.GetAccount(context.Background(), otherAcc.ID)
+	require.NoError(t, err)
+	require.Equal(t, otherAcc.Balance, account.Balance)
+}
+
+func TestCreateTransferChecks(t *testing.T) {
+	server := newTestServer(t)
+	owner := createTestUser(t, roleCustomer)
+	recipient := createTestUser(t, roleCustomer)
+	fromAcc := createTestAccount(t, owner)
+	toAcc := createTestAccount(t, recipient)
+
+	testCases := []struct {
+		name   string
+		body   transferRequest
+		status int
+	}{
+		{
+			name:   "OK",
+			body:   transferRequest{FromAccountId: fromAcc.ID, ToAccountId: toAcc.ID, Amount: 10, Currency: "CAD"},
+			status: http.StatusCreated,
+		},
+		{
+			name:   "InsufficientFunds",
+			body:   transferRequest{FromAccountId: fromAcc.ID, ToAccountId: toAcc.ID, Amount: fromAcc.Balance, Currency: "CAD"},
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "CurrencyMismatch",
+			body:   transferRequest{FromAccountId: fromAcc.ID, ToAccountId: toAcc.ID, Amount: 10, Currency: "USD"},
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "RecipientNotFound",
+			body:   transferRequest{FromAccountId: fromAcc.ID, ToAccountId: missingID, Amount: 10, Currency: "CAD"},
+			status: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := doRequest(t, server, owner, http.MethodPost, "/transfers", tc.body)
+			require.Equal(t, tc.status, recorder.Code)
+		})
+	}
+}
+
+func TestCreateTransferIdempotency(t *testing.T) {
+	server := newTestServer(t)
+	owner := createTestUser(t, roleCustomer)
+	recipient := createTestUser(t, roleCustomer)
+	fromAcc := createTestAccount(t, owner)
+	toAcc := createTestAccount(t, recipient)
+
+	key := randomString(16)
+	body := transferRequest{FromAccountId: fromAcc.ID, ToAccountId: toAcc.ID, Amount: 10, Currency: "CAD"}
+
+	sendTransfer := func(body transferRequest) *httptest.ResponseRecorder {
+		accessToken, _, err := server.tokenMaker.CreateToken(owner.Username, owner.Role, token.TokenTypeAccess, time.Minute)
+		require.NoError(t, err)
+
+		data, err := json.Marshal(body)
+		require.NoError(t, err)
+
+		req, err := http.NewRequest(http.MethodPost, "/transfers", bytes.NewReader(data))
+		require.NoError(t, err)
+		req.Header.Set(authHeaderKey, fmt.Sprintf("%s %s", authTypeBearer, accessToken))
+		req.Header.Set(idempotencyKeyHeader, key)
+
+		recorder := httptest.NewRecorder()
+		server.router.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	first := sendTransfer(body)
+	require.Equal(t, http.StatusCreated, first.Code)
+
+	retry := sendTransfer(body)
+	require.Equal(t, http.StatusCreated, retry.Code)
+	require.JSONEq(t, first.Body.String(), retry.Body.String())
+
+	account, err := testStore.GetAccount(context.Background(), fromAcc.ID)
+	require.NoError(t, err)
+	require.Equal(t, fromAcc.Balance-10, account.Balance)
+
+	body.Amount = 20
+	conflict := sendTransfer(body)
+	require.Equal(t, http.StatusUnprocessableEntity, conflict.Code)
+}
+
+func TestCreateTransferCrossCurrency(t *testing.T) {
+	server := newTestServer(t)
+	owner := createTestUser(t, roleCustomer)
+	recipient := createTestUser(t, roleCustomer)
+	fromAcc := createTestAccount(t, owner)
+
+	toAcc, err := testStore.CreateAccount(context.Background(), db.CreateAccountParams{
+		Owner:    recipient.Username,
+		Balance:  0,
+		Currency: "USD",
+	})
+	require.NoError(t, err)
+
+	body := transferRequest{FromAccountId: fromAcc.ID, ToAccountId: toAcc.ID, Amount: 100, Currency: "CAD"}
+	recorder := doRequest(t, server, owner, http.MethodPost, "/transfers", body)
+	require.Equal(t, http.StatusCreated, recorder.Code)
+
+	var result db.TransferTxResult
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &result))
+
+	// 100 * 0.75 less the 1% spread, rounded down.
+	require.Equal(t, int64(100), result.Transfer.Amount)
+	require.Equal(t, int64(74), result.Transfer.ToAmount)
+	require.Equal(t, "0.7500000000", result.Transfer.ExchangeRate)
+	require.Equal(t, "0.0100000000", result.Transfer.Spread)
+	require.Equal(t, int64(-100), result.FromEntry.Amount)
+	require.Equal(t, int64(74), result.ToEntry.Amount)
+	require.Equal(t, fromAcc.Balance-100, result.FromAccount.Balance)
+	require.Equal(t, int64(74), result.ToAccount.Balance)
+
+	// JPY has no minor unit: 1.00 CAD is 110 JPY, less the spread.
+	jpyAcc, err := testStore.CreateAccount(context.Background(), db.CreateAccountParams{
+		Owner:    recipient.Username,
+		Balance:  0,
+		Currency: "JPY",
+	})
+	require.NoError(t, err)
+
+	body = transferRequest{FromAccountId: fromAcc.ID, ToAccountId: jpyAcc.ID, Amount: 100, Currency: "CAD"}
+	recorder = doRequest(t, server, owner, http.MethodPost, "/transfers", body)
+	require.Equal(t, http.StatusCreated, recorder.Code)
+
+	var display struct {
+		DisplayAmount   string `json:"display_amount"`
+		DisplayToAmount string `json:"display_to_amount"`
+	}
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &display))
+	require.Equal(t, "1.00", display.DisplayAmount)
+	require.Equal(t, "108", display.DisplayToAmount)
+
+	// No rate is known for this pair.
+	eurAcc, err := testStore.CreateAccount(context.Background(), db.CreateAccountParams{
+		Owner:    recipient.Username,
+		Balance:  0,
+		Currency: "EUR",
+	})
+	require.NoError(t, err)
+
+	body = transferRequest{FromAccountId: fromAcc.ID, ToAccountId: eurAcc.ID, Amount: 100, Currency: "CAD"}
+	recorder = doRequest(t, server, owner, http.MethodPost, "/transfers", body)
+	require.Equal(t, http.StatusBadRequest, recorder.Code)
+
+	// Too little to be worth a cent once converted.
+	body = transferRequest{FromAccountId: fromAcc.ID, ToAccountId: toAcc.ID, Amount: 1, Currency: "CAD"}
+	recorder = doRequest(t, server, owner, http.MethodPost, "/transfers", body)
+	require.Equal(t, http.StatusBadRequest, recorder.Code)
+}
+
+func TestCreateTransferConversionUnits(t *testing.T) {
+	user, _ := randomUser(t, roleCustomer)
+	fromAcc := db.Account{ID: 1, Owner: user.Username, Balance: 1_000, Currency: "CAD", Status: db.AccountStatusActive}
+	toAcc := db.Account{ID: 2, Owner: randomString(8), Balance: 0, Currency: "USD", Status: db.AccountStatusActive}
+
+	testCases := []struct {
+		name       string
+		currencies []db.Currency
+		buildStubs func(store *mockdb.MockStore)
+		status     int
+	}{
+		{
+			name: "KnownCurrencies",
+			currencies: []db.Currency{
+				{Code: "CAD", MinorUnits: 2, Enabled: true},
+				{Code: "USD", MinorUnits: 2, Enabled: true},
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					TransferTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.TransferTxParams) (db.TransferTxResult, error) {
+						require.Equal(t, int64(74), arg.ToAmount)
+						return db.TransferTxResult{FromAccount: fromAcc, ToAccount: toAcc}, nil
+					})
+			},
+			status: http.StatusCreated,
+		},
+		{
+			// The scale of USD is not guessed when it is missing from the
+			// registry.
+			name: "UnknownToCurrency",
+			currencies: []db.Currency{
+				{Code: "CAD", MinorUnits: 2, Enabled: true},
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			status: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			expectAuthenticated(store, user)
+			store.EXPECT().ListCurrencies(gomock.Any()).AnyTimes().Return(tc.currencies, nil)
+			store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(fromAcc.ID)).Times(1).Return(fromAcc, nil)
+			store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(toAcc.ID)).Times(1).Return(toAcc, nil)
+			tc.buildStubs(store)
+
+			server := newTestServerWithStore(t, store)
+			body := transferRequest{FromAccountId: fromAcc.ID, ToAccountId: toAcc.ID, Amount: 100, Currency: "CAD"}
+			recorder := doRequest(t, server, user, http.MethodPost, "/transfers", body)
+			require.Equal(t, tc.status, recorder.Code)
+		})
+	}
+}
+
+func TestReverseTransfer(t *testing.T) {
+	server := newTestServer(t)
+	sender := createTestUser(t, roleCustomer)
+	recipient := createTestUser(t, roleCustomer)
+	fromAcc := createTestAccount(t, sender)
+	toAcc := createTestAccount(t, recipient)
+
+	original, err := testStore.TransferTx(context.Background(), db.TransferTxParams{
+		FromAccountID: fromAcc.ID,
+		ToAccountID:   toAcc.ID,
+		Amount:        100,
+		Currency:      "CAD",
+	})
+	require.NoError(t, err)
+
+	url := fmt.Sprintf("/transfers/%d/reverse", original.Transfer.ID)
+
+	testCases := []struct {
+		name   string
+		user   db.User
+		url    string
+		body   interface{}
+		status int
+	}{
+		{name: "Sender", user: sender, url: url, status: http.StatusForbidden},
+		{name: "NotFound", user: recipient, url: fmt.Sprintf("/transfers/%d/reverse", missingID), status: http.StatusNotFound},
+		{name: "InvalidAmount", user: recipient, url: url, body: reverseTransferRequest{Amount: -1}, status: http.StatusBadRequest},
+		{name: "TooMuch", user: recipient, url: url, body: reverseTransferRequest{Amount: 101}, status: http.StatusBadRequest},
+		{name: "Partial", user: recipient, url: url, body: reverseTransferRequest{Amount: 40}, status: http.StatusCreated},
+		{name: "Rest", user: recipient, url: url, status: http.StatusCreated},
+		{name: "Again", user: recipient, url: url, status: http.StatusForbidden},
+	}
+
+	// The cases run in order, each one starting from where the last left it.
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := doRequest(t, server, tc.user, http.MethodPost, tc.url, tc.body)
+			require.Equal(t, tc.status, recorder.Code)
+		})
+	}
+
+	recorder := doRequest(t, server, sender, http.MethodGet, fmt.Sprintf("/transfers/%d/reversals", original.Transfer.ID), nil)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var rsp struct {
+		Data []transferResponse `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+	require.Len(t, rsp.Data, 2)
+	require.Equal(t, int64(40), rsp.Data[0].Amount)
+	require.Equal(t, int64(60), rsp.Data[1].Amount)
+	require.Equal(t, original.Transfer.ID, *rsp.Data[1].ReversalOf)
+
+	account, err := testStore.GetAccount(context.Background(), fromAcc.ID)
+	require.NoError(t, err)
+	require.Equal(t, fromAcc.Balance, account.Balance)
+}
@@ -2,11 +2,15 @@ package api
 
 import (
 	"database/sql"
+	"errors"
+	"log"
 	"net/http"
 	"time"
 
 	db "github.com/ferueda/simplebank-go/db/sqlc"
+	"github.com/ferueda/simplebank-go/token"
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/lib/pq"
 )
 
@@ -18,10 +22,12 @@ type createUserRequest struct {
 }
 
 type userResponse struct {
-	Username  string    `json:"username"`
-	FullName  string    `json:"full_name"`
-	Email     string    `json:"email"`
-	CreatedAt time.Time `json:"created_at"`
+	Username        string    `json:"username"`
+	FullName        string    `json:"full_name"`
+	Email           string    `json:"email"`
+	Role            string    `json:"role"`
+	IsEmailVerified bool      `json:"is_email_verified"`
+	CreatedAt       time.Time `json:"created_at"`
 }
 
 func (s *Server) createUser(ctx *gin.Context) {
@@ -37,14 +43,24 @@ func (s *Server) createUser(ctx *gin.Context) {
 		return
 	}
 
-	arg := db.CreateUserParams{
-		Username:       req.Username,
-		HashedPassword: hashedPass,
-		FullName:       req.FullName,
-		Email:          req.Email,
+	verifyToken, hashedVerifyToken, err := newOneTimeToken()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
 	}
 
-	user, err := s.store.CreateUser(ctx, arg)
+	arg := db.CreateUserTxParams{
+		CreateUserParams: db.CreateUserParams{
+			Username:       req.Username,
+			HashedPassword: hashedPass,
+			FullName:       req.FullName,
+			Email:          req.Email,
+		},
+		HashedVerifyToken:    hashedVerifyToken,
+		VerifyTokenExpiresAt: time.Now().Add(s.config.EmailVerificationTokenDuration),
+	}
+
+	user, err := s.store.CreateUserTx(ctx, arg)
 	if err != nil {
 		if pqErr, ok := err.(*pq.Error); ok {
 			if pqErr.Code.Name() == "unique_violation" {
@@ -57,16 +73,24 @@ func (s *Server) createUser(ctx *gin.Context) {
 		return
 	}
 
+	// The user is committed by now, so a failed send is logged rather than
+	// reported as a failed sign-up.
+	if err = s.sendVerifyEmail(ctx, user, verifyToken); err != nil {
+		log.Printf("cannot send verification email to %s: %v", user.Username, err)
+	}
+
 	resp := newUserResponse(user)
 	ctx.JSON(http.StatusCreated, resp)
 }
 
 func newUserResponse(user db.User) userResponse {
 	return userResponse{
-		Username:  user.Username,
-		FullName:  user.FullName,
-		Email:     user.Email,
-		CreatedAt: user.CreatedAt,
+		Username:        user.Username,
+		FullName:        user.FullName,
+		Email:           user.Email,
+		Role:            user.Role,
+		IsEmailVerified: user.IsEmailVerified,
+		CreatedAt:       user.CreatedAt,
 	}
 }
 
@@ -76,8 +100,12 @@ type loginUserRequest struct {
 }
 
 type loginUserResponse struct {
-	AccessToken string       `json:"access_token"`
-	User        userResponse `json:"user"`
+	SessionID             uuid.UUID    `json:"session_id"`
+	AccessToken           string       `json:"access_token"`
+	AccessTokenExpiresAt  time.Time    `json:"access_token_expires_at"`
+	RefreshToken          string       `json:"refresh_token"`
+	RefreshTokenExpiresAt time.Time    `json:"refresh_token_expires_at"`
+	User                  userResponse `json:"user"`
 }
 
 func (s *Server) loginUser(ctx *gin.Context) {
@@ -87,9 +115,18 @@ func (s *Server) loginUser(ctx *gin.Context) {
 		return
 	}
 
+	if !s.checkLoginThrottle(ctx, req.Username) {
+		return
+	}
+
 	user, err := s.store.GetUser(ctx, req.Username)
 	if err != nil {
 		if err == sql.ErrNoRows {
+			if err := s.recordLoginFailure(ctx, req.Username); err != nil {
+				ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+				return
+			}
+
 			ctx.JSON(http.StatusNotFound, errorResponse(err))
 			return
 		}
@@ -99,20 +136,378 @@ func (s *Server) loginUser(ctx *gin.Context) {
 	}
 
 	if err = db.ValidateHashedPassword(req.Password, user.HashedPassword); err != nil {
+		if err := s.recordLoginFailure(ctx, req.Username); err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		return
+	}
+
+	userTotp, err := s.store.GetUserTotp(ctx, user.Username)
+	if err != nil && err != sql.ErrNoRows {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if err == nil && userTotp.IsEnabled {
+		challenge, err := s.store.CreateMfaChallenge(ctx, db.CreateMfaChallengeParams{
+			ID:        uuid.New(),
+			Username:  user.Username,
+			ExpiresAt: time.Now().Add(s.config.MFAChallengeDuration),
+		})
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+
+		// The throttle is only reset once the second factor is verified too.
+		ctx.JSON(http.StatusOK, mfaChallengeResponse{
+			MFARequired:       true,
+			MFAToken:          challenge.ID,
+			MFATokenExpiresAt: challenge.ExpiresAt,
+		})
+		return
+	}
+
+	if err = s.resetLoginThrottle(ctx, user.Username); err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	resp, err := s.createUserSession(ctx, user)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, resp)
+}
+
+// createUserSession issues an access token and a refresh token backed by a
+// new session for an authenticated user.
+func (s *Server) createUserSession(ctx *gin.Context, user db.User) (loginUserResponse, error) {
+	var resp loginUserResponse
+
+	accessToken, accessPayload, err := s.tokenMaker.CreateToken(user.Username, user.Role, token.TokenTypeAccess, s.config.AccessTokenDuration)
+	if err != nil {
+		return resp, err
+	}
+
+	refreshToken, refreshPayload, err := s.tokenMaker.CreateToken(user.Username, user.Role, token.TokenTypeRefresh, s.config.RefreshTokenDuration)
+	if err != nil {
+		return resp, err
+	}
+
+	session, err := s.store.CreateSession(ctx, db.CreateSessionParams{
+		ID:           refreshPayload.ID,
+		Username:     user.Username,
+		RefreshToken: refreshToken,
+		UserAgent:    ctx.Request.UserAgent(),
+		ClientIp:     ctx.ClientIP(),
+		IsBlocked:    false,
+		ExpiresAt:    refreshPayload.ExpiredAt,
+	})
+	if err != nil {
+		return resp, err
+	}
+
+	resp = loginUserResponse{
+		SessionID:             session.ID,
+		AccessToken:           accessToken,
+		AccessTokenExpiresAt:  accessPayload.ExpiredAt,
+		RefreshToken:          refreshToken,
+		RefreshTokenExpiresAt: refreshPayload.ExpiredAt,
+		User:                  newUserResponse(user),
+	}
+
+	return resp, nil
+}
+
+type logoutUserRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+func (s *Server) logoutUser(ctx *gin.Context) {
+	// The body is optional.
+	var req logoutUserRequest
+	if ctx.Request.ContentLength != 0 {
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+			return
+		}
+	}
+
+	authPayload := ctx.MustGet(authPayloadKey).(*token.Payload)
+
+	arg := db.RevokeTokenTxParams{
+		TokenID:   authPayload.ID,
+		Username:  authPayload.Username,
+		ExpiresAt: authPayload.ExpiredAt,
+	}
+
+	if req.RefreshToken != "" {
+		refreshPayload, err := s.tokenMaker.VerifyToken(req.RefreshToken)
+		if err != nil {
+			ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+			return
+		}
+
+		if refreshPayload.Type != token.TokenTypeRefresh {
+			err := errors.New("token is not a refresh token")
+			ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+			return
+		}
+
+		if refreshPayload.Username != authPayload.Username {
+			err := errors.New("refresh token belongs to a different user")
+			ctx.JSON(http.StatusForbidden, errorResponse(err))
+			return
+		}
+
+		arg.SessionID = uuid.NullUUID{UUID: refreshPayload.ID, Valid: true}
+	}
+
+	err := s.store.RevokeTokenTx(ctx, arg)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+type updateUserPasswordRequest struct {
+	OldPassword string `json:"old_password" binding:"required,min=6"`
+	NewPassword string `json:"new_password" binding:"required,min=6,nefield=OldPassword"`
+}
+
+func (s *Server) updateUserPassword(ctx *gin.Context) {
+	var req updateUserPasswordRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authPayloadKey).(*token.Payload)
+
+	user, err := s.store.GetUser(ctx, authPayload.Username)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if err = db.ValidateHashedPassword(req.OldPassword, user.HashedPassword); err != nil {
 		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
 		return
 	}
 
-	accessToken, err := s.tokenMaker.CreateToken(user.Username, time.Hour*1)
+	hashedPass, err := db.HashPassword(req.NewPassword)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
 
-	resp := loginUserResponse{
-		AccessToken: accessToken,
-		User:        newUserResponse(user),
+	arg := db.UpdateUserPasswordParams{
+		Username:       user.Username,
+		HashedPassword: hashedPass,
+	}
+
+	user, err = s.store.UpdateUserPassword(ctx, arg)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
 	}
 
+	resp := newUserResponse(user)
+	ctx.JSON(http.StatusOK, resp)
+}
+
+func (s *Server) getCurrentUser(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authPayloadKey).(*token.Payload)
+
+	user, err := s.store.GetUser(ctx, authPayload.Username)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	resp := newUserResponse(user)
+	ctx.JSON(http.StatusOK, resp)
+}
+
+type updateCurrentUserRequest struct {
+	FullName *string `json:"full_name" binding:"omitempty,min=1"`
+	Email    *string `json:"email" binding:"omitempty,email"`
+}
+
+func (s *Server) updateCurrentUser(ctx *gin.Context) {
+	var req updateCurrentUserRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if req.FullName == nil && req.Email == nil {
+		err := errors.New("at least one of full_name or email must be provided")
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	verifyToken, hashedVerifyToken, err := newOneTimeToken()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authPayloadKey).(*token.Payload)
+
+	arg := db.UpdateUserTxParams{
+		UpdateUserParams: db.UpdateUserParams{
+			Username: authPayload.Username,
+		},
+		HashedVerifyToken:    hashedVerifyToken,
+		VerifyTokenExpiresAt: time.Now().Add(s.config.EmailVerificationTokenDuration),
+	}
+
+	if req.FullName != nil {
+		arg.FullName = sql.NullString{String: *req.FullName, Valid: true}
+	}
+
+	if req.Email != nil {
+		arg.Email = sql.NullString{String: *req.Email, Valid: true}
+	}
+
+	result, err := s.store.UpdateUserTx(ctx, arg)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+
+		if pqErr, ok := err.(*pq.Error); ok {
+			if pqErr.Code.Name() == "unique_violation" {
+				ctx.JSON(http.StatusForbidden, errorResponse(err))
+				return
+			}
+		}
+
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	// As at sign-up, the update is committed by now and a failed send is
+	// only logged.
+	if result.EmailChanged {
+		if err = s.sendVerifyEmail(ctx, result.User, verifyToken); err != nil {
+			log.Printf("cannot send verification email to %s: %v", result.User.Username, err)
+		}
+	}
+
+	resp := newUserResponse(result.User)
+	ctx.JSON(http.StatusOK, resp)
+}
+
+type deleteCurrentUserRequest struct {
+	Password string `json:"password" binding:"required"`
+}
+
+// deleteCurrentUser closes the caller's profile. Personal data is anonymized
+// but the ledger is kept, so every account must be emptied first.
+func (s *Server) deleteCurrentUser(ctx *gin.Context) {
+	var req deleteCurrentUserRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authPayloadKey).(*token.Payload)
+
+	user, err := s.store.GetUser(ctx, authPayload.Username)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if err = db.ValidateHashedPassword(req.Password, user.HashedPassword); err != nil {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		return
+	}
+
+	_, err = s.store.DeleteUserTx(ctx, user.Username)
+	if err != nil {
+		switch err {
+		case db.ErrAccountHasBalance, db.ErrAccountFrozen:
+			ctx.JSON(http.StatusForbidden, errorResponse(err))
+		case sql.ErrNoRows:
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+		default:
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		}
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+type updateUserRoleUri struct {
+	Username string `uri:"username" binding:"required,alphanum"`
+}
+
+type updateUserRoleRequest struct {
+	Role string `json:"role" binding:"required,oneof=customer banker admin"`
+}
+
+func (s *Server) updateUserRole(ctx *gin.Context) {
+	var uri updateUserRoleUri
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	var req updateUserRoleRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	arg := db.UpdateUserRoleParams{
+		Username: uri.Username,
+		Role:     req.Role,
+	}
+
+	user, err := s.store.UpdateUserRole(ctx, arg)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	resp := newUserResponse(user)
 	ctx.JSON(http.StatusOK, resp)
 }
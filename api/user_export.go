@@ -0,0 +1,209 @@
+package api
+
+import (
+	"archive/zip"
+	"bytes"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	db "github.com/ferueda/simplebank-go/db/sqlc"
+	"github.com/ferueda/simplebank-go/token"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	exportFormatJSON = "json"
+	exportFormatZip  = "zip"
+)
+
+type exportUserRequest struct {
+	Format string `form:"format" binding:"omitempty,oneof=json zip"`
+}
+
+type exportUserResponse struct {
+	ExportedAt time.Time          `json:"exported_at"`
+	User       userResponse       `json:"user"`
+	Accounts   []db.Account       `json:"accounts"`
+	Entries    []db.Entry         `json:"entries"`
+	Transfers  []transferResponse `json:"transfers"`
+}
+
+// exportUser returns everything we hold about the caller. The default zip
+// archive bundles the JSON document with one CSV file per record type.
+func (s *Server) exportUser(ctx *gin.Context) {
+	var req exportUserRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authPayloadKey).(*token.Payload)
+
+	export, err := s.store.ExportUserTx(ctx, authPayload.Username)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	resp := exportUserResponse{
+		ExportedAt: time.Now(),
+		User:       newUserResponse(export.User),
+		Accounts:   export.Accounts,
+		Entries:    export.Entries,
+		Transfers:  newTransferResponses(export.Transfers),
+	}
+
+	if req.Format == exportFormatJSON {
+		ctx.JSON(http.StatusOK, resp)
+		return
+	}
+
+	archive, err := newExportArchive(resp)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.Header("Content-Disposition", `attachment; filename="simplebank-export-`+export.User.Username+`.zip"`)
+	ctx.Data(http.StatusOK, "application/zip", archive)
+}
+
+func newExportArchive(export exportUserResponse) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+
+	w, err := zw.Create("export.json")
+	if err != nil {
+		return nil, err
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err = enc.Encode(export); err != nil {
+		return nil, err
+	}
+
+	user := export.User
+	files := []struct {
+		name   string
+		header []string
+		rows   [][]string
+	}{
+		{
+			name:   "profile.csv",
+			header: []string{"username", "full_name", "email", "role", "is_email_verified", "created_at"},
+			rows: [][]string{{
+				user.Username,
+				user.FullName,
+				user.Email,
+				user.Role,
+				strconv.FormatBool(user.IsEmailVerified),
+				formatExportTime(user.CreatedAt),
+			}},
+		},
+		{
+			name:   "accounts.csv",
+			header: []string{"id", "owner", "balance", "held_amount", "available_balance", "currency", "status", "created_at"},
+			rows:   accountRows(export.Accounts),
+		},
+		{
+			name:   "entries.csv",
+			header: []string{"id", "account_id", "amount", "created_at"},
+			rows:   entryRows(export.Entries),
+		},
+		{
+			name:   "transfers.csv",
+			header: []string{"id", "from_account_id", "to_account_id", "amount", "to_amount", "exchange_rate", "spread", "reversal_of", "created_at"},
+			rows:   transferRows(export.Transfers),
+		},
+	}
+
+	for _, f := range files {
+		w, err := zw.Create(f.name)
+		if err != nil {
+			return nil, err
+		}
+
+		cw := csv.NewWriter(w)
+		if err = cw.Write(f.header); err != nil {
+			return nil, err
+		}
+		if err = cw.WriteAll(f.rows); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = zw.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func accountRows(accounts []db.Account) [][]string {
+	rows := make([][]string, len(accounts))
+	for i, a := range accounts {
+		rows[i] = []string{
+			strconv.FormatInt(a.ID, 10),
+			a.Owner,
+			strconv.FormatInt(a.Balance, 10),
+			strconv.FormatInt(a.HeldAmount, 10),
+			strconv.FormatInt(a.AvailableBalance, 10),
+			a.Currency,
+			a.Status,
+			formatExportTime(a.CreatedAt),
+		}
+	}
+	return rows
+}
+
+func entryRows(entries []db.Entry) [][]string {
+	rows := make([][]string, len(entries))
+	for i, e := range entries {
+		rows[i] = []string{
+			strconv.FormatInt(e.ID, 10),
+			strconv.FormatInt(e.AccountID, 10),
+			strconv.FormatInt(e.Amount, 10),
+			formatExportTime(e.CreatedAt),
+		}
+	}
+	return rows
+}
+
+func transferRows(transfers []transferResponse) [][]string {
+	rows := make([][]string, len(transfers))
+	for i, t := range transfers {
+		rows[i] = []string{
+			strconv.FormatInt(t.ID, 10),
+			strconv.FormatInt(t.FromAccountID, 10),
+			strconv.FormatInt(t.ToAccountID, 10),
+			strconv.FormatInt(t.Amount, 10),
+			strconv.FormatInt(t.ToAmount, 10),
+			t.ExchangeRate,
+			t.Spread,
+			formatOptionalInt(t.ReversalOf),
+			formatExportTime(t.CreatedAt),
+		}
+	}
+	return rows
+}
+
+func formatOptionalInt(n *int64) string {
+	if n == nil {
+		return ""
+	}
+	return strconv.FormatInt(*n, 10)
+}
+
+func formatExportTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,117 @@
+package api
+
+import (
+	"archive/zip"
+	"bytes"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	mockdb "github.com/ferueda/simplebank-go/db/mock"
+	db "github.com/ferueda/simplebank-go/db/sqlc"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportUser(t *testing.T) {
+	user, _ := randomUser(t, roleCustomer)
+	account := db.Account{
+		ID:               1,
+		Owner:            user.Username,
+		Balance:          1_000,
+		Currency:         "CAD",
+		Status:           db.AccountStatusFrozen,
+		HeldAmount:       250,
+		AvailableBalance: 750,
+	}
+	export := db.UserExport{
+		User:     user,
+		Accounts: []db.Account{account},
+		Entries:  []db.Entry{{ID: 1, AccountID: account.ID, Amount: 1_000}},
+	}
+
+	t.Run("JSON", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		store := mockdb.NewMockStore(ctrl)
+		expectAuthenticated(store, user)
+		store.EXPECT().ExportUserTx(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(export, nil)
+
+		server := newTestServerWithStore(t, store)
+		recorder := doRequest(t, server, user, http.MethodGet, "/users/me/export?format=json", nil)
+		require.Equal(t, http.StatusOK, recorder.Code)
+
+		var rsp exportUserResponse
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+		require.Equal(t, user.Username, rsp.User.Username)
+		require.Equal(t, []db.Account{account}, rsp.Accounts)
+		require.Len(t, rsp.Entries, 1)
+	})
+
+	t.Run("Zip", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		store := mockdb.NewMockStore(ctrl)
+		expectAuthenticated(store, user)
+		store.EXPECT().ExportUserTx(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(export, nil)
+
+		server := newTestServerWithStore(t, store)
+		recorder := doRequest(t, server, user, http.MethodGet, "/users/me/export", nil)
+		require.Equal(t, http.StatusOK, recorder.Code)
+		require.Equal(t, "application/zip", recorder.Header().Get("Content-Type"))
+
+		body := recorder.Body.Bytes()
+		zr, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+		require.NoError(t, err)
+
+		files := make(map[string]*zip.File)
+		for _, f := range zr.File {
+			files[f.Name] = f
+		}
+		require.Contains(t, files, "export.json")
+		require.Contains(t, files, "profile.csv")
+		require.Contains(t, files, "entries.csv")
+		require.Contains(t, files, "transfers.csv")
+
+		// The accounts file carries the same fields as the JSON document.
+		r, err := files["accounts.csv"].Open()
+		require.NoError(t, err)
+		defer r.Close()
+
+		records, err := csv.NewReader(r).ReadAll()
+		require.NoError(t, err)
+		require.Len(t, records, 2)
+		require.Equal(t, []string{"id", "owner", "balance", "held_amount", "available_balance", "currency", "status", "created_at"}, records[0])
+		require.Equal(t, []string{"1", user.Username, "1000", "250", "750", "CAD", db.AccountStatusFrozen}, records[1][:7])
+	})
+
+	t.Run("InvalidFormat", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		store := mockdb.NewMockStore(ctrl)
+		expectAuthenticated(store, user)
+		store.EXPECT().ExportUserTx(gomock.Any(), gomock.Any()).Times(0)
+
+		server := newTestServerWithStore(t, store)
+		recorder := doRequest(t, server, user, http.MethodGet, "/users/me/export?format=xml", nil)
+		require.Equal(t, http.StatusBadRequest, recorder.Code)
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		store := mockdb.NewMockStore(ctrl)
+		expectAuthenticated(store, user)
+		store.EXPECT().ExportUserTx(gomock.Any(), gomock.Any()).Times(1).Return(db.UserExport{}, sql.ErrNoRows)
+
+		server := newTestServerWithStore(t, store)
+		recorder := doRequest(t, server, user, http.MethodGet, "/users/me/export", nil)
+		require.Equal(t, http.StatusNotFound, recorder.Code)
+	})
+}
@@ -0,0 +1,652 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mockdb "github.com/ferueda/simplebank-go/db/mock"
+	db "github.com/ferueda/simplebank-go/db/sqlc"
+	"github.com/ferueda/simplebank-go/mail"
+	"github.com/ferueda/simplebank-go/token"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoginUser(t *testing.T) {
+	user, password := randomUser(t, roleCustomer)
+
+	testCases := []struct {
+		name          string
+		body          loginUserRequest
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, server *Server, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: loginUserRequest{Username: user.Username, Password: password},
+			buildStubs: func(store *mockdb.MockStore) {
+				expectLoginAllowed(store)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().DeleteLoginThrottle(gomock.Any(), gomock.Any()).Times(1).Return(nil)
+				store.EXPECT().GetUserTotp(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(db.UserTotp{}, sql.ErrNoRows)
+				store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(1).Return(db.Session{}, nil)
+			},
+			checkResponse: func(t *testing.T, server *Server, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp loginUserResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.Equal(t, user.Username, rsp.User.Username)
+
+				accessPayload, err := server.tokenMaker.VerifyToken(rsp.AccessToken)
+				require.NoError(t, err)
+				require.Equal(t, token.TokenTypeAccess, accessPayload.Type)
+
+				refreshPayload, err := server.tokenMaker.VerifyToken(rsp.RefreshToken)
+				require.NoError(t, err)
+				require.Equal(t, token.TokenTypeRefresh, refreshPayload.Type)
+			},
+		},
+		{
+			name: "UserNotFound",
+			body: loginUserRequest{Username: user.Username, Password: password},
+			buildStubs: func(store *mockdb.MockStore) {
+				expectLoginAllowed(store)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(db.User{}, sql.ErrNoRows)
+				expectLoginFailure(store)
+				store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, server *Server, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name: "IncorrectPassword",
+			body: loginUserRequest{Username: user.Username, Password: randomString(8)},
+			buildStubs: func(store *mockdb.MockStore) {
+				expectLoginAllowed(store)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				expectLoginFailure(store)
+				store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, server *Server, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidUsername",
+			body: loginUserRequest{Username: "invalid-user#1", Password: password},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, server *Server, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServerWithStore(t, store)
+			recorder := doPublicRequest(t, server, http.MethodPost, "/users/login", tc.body)
+			tc.checkResponse(t, server, recorder)
+		})
+	}
+}
+
+// expectLoginAllowed stubs the throttle lookups of a login attempt that is
+// not being held back.
+func expectLoginAllowed(store *mockdb.MockStore) {
+	store.EXPECT().GetLoginThrottle(gomock.Any(), gomock.Any()).Times(2).Return(db.LoginThrottle{}, sql.ErrNoRows)
+}
+
+// expectLoginFailure stubs recording a failed attempt against the username
+// and the client IP.
+func expectLoginFailure(store *mockdb.MockStore) {
+	store.EXPECT().RecordLoginFailure(gomock.Any(), gomock.Any()).Times(2).Return(db.LoginThrottle{FailedAttempts: 1}, nil)
+	store.EXPECT().UpdateLoginThrottleLockedUntil(gomock.Any(), gomock.Any()).Times(2).Return(db.LoginThrottle{}, nil)
+}
+
+func TestLogoutUser(t *testing.T) {
+	user, _ := randomUser(t, roleCustomer)
+	other, _ := randomUser(t, roleCustomer)
+
+	testCases := []struct {
+		name       string
+		body       func(t *testing.T, tokenMaker token.Maker) interface{}
+		buildStubs func(store *mockdb.MockStore, accessPayload *token.Payload)
+		status     int
+	}{
+		{
+			name: "AccessTokenOnly",
+			body: func(t *testing.T, tokenMaker token.Maker) interface{} {
+				return nil
+			},
+			buildStubs: func(store *mockdb.MockStore, accessPayload *token.Payload) {
+				store.EXPECT().
+					RevokeTokenTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.RevokeTokenTxParams) error {
+						require.Equal(t, accessPayload.ID, arg.TokenID)
+						require.Equal(t, user.Username, arg.Username)
+						require.WithinDuration(t, accessPayload.ExpiredAt, arg.ExpiresAt, time.Second)
+						require.False(t, arg.SessionID.Valid)
+						return nil
+					})
+			},
+			status: http.StatusNoContent,
+		},
+		{
+			name: "WithRefreshToken",
+			body: func(t *testing.T, tokenMaker token.Maker) interface{} {
+				refreshToken, _, err := tokenMaker.CreateToken(user.Username, user.Role, token.TokenTypeRefresh, time.Hour)
+				require.NoError(t, err)
+				return logoutUserRequest{RefreshToken: refreshToken}
+			},
+			buildStubs: func(store *mockdb.MockStore, accessPayload *token.Payload) {
+				store.EXPECT().
+					RevokeTokenTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.RevokeTokenTxParams) error {
+						require.Equal(t, accessPayload.ID, arg.TokenID)
+						require.True(t, arg.SessionID.Valid)
+						return nil
+					})
+			},
+			status: http.StatusNoContent,
+		},
+		{
+			name: "AccessTokenAsRefreshToken",
+			body: func(t *testing.T, tokenMaker token.Maker) interface{} {
+				accessToken, _, err := tokenMaker.CreateToken(user.Username, user.Role, token.TokenTypeAccess, time.Minute)
+				require.NoError(t, err)
+				return logoutUserRequest{RefreshToken: accessToken}
+			},
+			buildStubs: func(store *mockdb.MockStore, accessPayload *token.Payload) {
+				store.EXPECT().RevokeTokenTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			status: http.StatusUnauthorized,
+		},
+		{
+			name: "OtherUsersRefreshToken",
+			body: func(t *testing.T, tokenMaker token.Maker) interface{} {
+				refreshToken, _, err := tokenMaker.CreateToken(other.Username, other.Role, token.TokenTypeRefresh, time.Hour)
+				require.NoError(t, err)
+				return logoutUserRequest{RefreshToken: refreshToken}
+			},
+			buildStubs: func(store *mockdb.MockStore, accessPayload *token.Payload) {
+				store.EXPECT().RevokeTokenTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			status: http.StatusForbidden,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			server := newTestServerWithStore(t, store)
+
+			accessToken, accessPayload, err := server.tokenMaker.CreateToken(user.Username, user.Role, token.TokenTypeAccess, time.Minute)
+			require.NoError(t, err)
+
+			expectAuthenticated(store, user)
+			tc.buildStubs(store, accessPayload)
+
+			recorder := doRequestWithToken(t, server, accessToken, http.MethodPost, "/users/logout", tc.body(t, server.tokenMaker))
+			require.Equal(t, tc.status, recorder.Code)
+		})
+	}
+}
+
+func TestLogoutRejectsTokens(t *testing.T) {
+	server := newTestServer(t)
+	user := createTestUser(t, roleCustomer)
+
+	ginCtx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	ginCtx.Request = httptest.NewRequest(http.MethodPost, "/users/login", nil)
+	login, err := server.createUserSession(ginCtx, user)
+	require.NoError(t, err)
+
+	body := logoutUserRequest{RefreshToken: login.RefreshToken}
+	recorder := doRequestWithToken(t, server, login.AccessToken, http.MethodPost, "/users/logout", body)
+	require.Equal(t, http.StatusNoContent, recorder.Code)
+
+	recorder = doRequestWithToken(t, server, login.AccessToken, http.MethodGet, "/users/me", nil)
+	require.Equal(t, http.StatusUnauthorized, recorder.Code)
+
+	recorder = doRequestWithToken(t, server, login.RefreshToken, http.MethodGet, "/users/me", nil)
+	require.Equal(t, http.StatusUnauthorized, recorder.Code)
+
+	renew := renewAccessTokenRequest{RefreshToken: login.RefreshToken}
+	recorder = doPublicRequest(t, server, http.MethodPost, "/tokens/renew_access", renew)
+	require.Equal(t, http.StatusUnauthorized, recorder.Code)
+}
+
+func TestLogoutTwice(t *testing.T) {
+	server := newTestServer(t)
+	user := createTestUser(t, roleCustomer)
+
+	ginCtx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	ginCtx.Request = httptest.NewRequest(http.MethodPost, "/users/login", nil)
+	login, err := server.createUserSession(ginCtx, user)
+	require.NoError(t, err)
+
+	// A second access token, as renewing the session would hand out.
+	renewed, _, err := server.tokenMaker.CreateToken(user.Username, user.Role, token.TokenTypeAccess, time.Minute)
+	require.NoError(t, err)
+
+	body := logoutUserRequest{RefreshToken: login.RefreshToken}
+	recorder := doRequestWithToken(t, server, login.AccessToken, http.MethodPost, "/users/logout", body)
+	require.Equal(t, http.StatusNoContent, recorder.Code)
+
+	recorder = doRequestWithToken(t, server, renewed, http.MethodPost, "/users/logout", body)
+	require.Equal(t, http.StatusNoContent, recorder.Code)
+}
+
+// expectAuthenticated stubs the checks authMiddleware makes on a bearer
+// token of user.
+func expectAuthenticated(store *mockdb.MockStore, user db.User) {
+	store.EXPECT().IsTokenRevoked(gomock.Any(), gomock.Any()).AnyTimes().Return(false, nil)
+	store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).AnyTimes().Return(user, nil)
+}
+
+func TestUpdateUserPassword(t *testing.T) {
+	user, password := randomUser(t, roleCustomer)
+	newPassword := randomString(8)
+
+	testCases := []struct {
+		name       string
+		body       updateUserPasswordRequest
+		buildStubs func(store *mockdb.MockStore)
+		status     int
+	}{
+		{
+			name: "OK",
+			body: updateUserPasswordRequest{OldPassword: password, NewPassword: newPassword},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					UpdateUserPassword(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.UpdateUserPasswordParams) (db.User, error) {
+						require.Equal(t, user.Username, arg.Username)
+						require.NoError(t, db.ValidateHashedPassword(newPassword, arg.HashedPassword))
+						return user, nil
+					})
+			},
+			status: http.StatusOK,
+		},
+		{
+			name: "IncorrectOldPassword",
+			body: updateUserPasswordRequest{OldPassword: randomString(8), NewPassword: newPassword},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().UpdateUserPassword(gomock.Any(), gomock.Any()).Times(0)
+			},
+			status: http.StatusUnauthorized,
+		},
+		{
+			name: "SamePassword",
+			body: updateUserPasswordRequest{OldPassword: password, NewPassword: password},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().UpdateUserPassword(gomock.Any(), gomock.Any()).Times(0)
+			},
+			status: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			expectAuthenticated(store, user)
+			tc.buildStubs(store)
+
+			server := newTestServerWithStore(t, store)
+			recorder := doRequest(t, server, user, http.MethodPatch, "/users/password", tc.body)
+			require.Equal(t, tc.status, recorder.Code)
+		})
+	}
+}
+
+func TestUpdateUserRole(t *testing.T) {
+	admin, _ := randomUser(t, roleAdmin)
+	banker, _ := randomUser(t, roleBanker)
+	user, _ := randomUser(t, roleCustomer)
+
+	testCases := []struct {
+		name       string
+		caller     db.User
+		body       updateUserRoleRequest
+		buildStubs func(store *mockdb.MockStore)
+		status     int
+	}{
+		{
+			name:   "OK",
+			caller: admin,
+			body:   updateUserRoleRequest{Role: roleBanker},
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.UpdateUserRoleParams{Username: user.Username, Role: roleBanker}
+				updated := user
+				updated.Role = roleBanker
+				store.EXPECT().UpdateUserRole(gomock.Any(), gomock.Eq(arg)).Times(1).Return(updated, nil)
+			},
+			status: http.StatusOK,
+		},
+		{
+			name:   "NotAdmin",
+			caller: banker,
+			body:   updateUserRoleRequest{Role: roleAdmin},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().UpdateUserRole(gomock.Any(), gomock.Any()).Times(0)
+			},
+			status: http.StatusForbidden,
+		},
+		{
+			name:   "UnknownRole",
+			caller: admin,
+			body:   updateUserRoleRequest{Role: "owner"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().UpdateUserRole(gomock.Any(), gomock.Any()).Times(0)
+			},
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "UserNotFound",
+			caller: admin,
+			body:   updateUserRoleRequest{Role: roleBanker},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().UpdateUserRole(gomock.Any(), gomock.Any()).Times(1).Return(db.User{}, sql.ErrNoRows)
+			},
+			status: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			expectAuthenticated(store, tc.caller)
+			tc.buildStubs(store)
+
+			server := newTestServerWithStore(t, store)
+			url := fmt.Sprintf("/users/%s/role", user.Username)
+			recorder := doRequest(t, server, tc.caller, http.MethodPatch, url, tc.body)
+			require.Equal(t, tc.status, recorder.Code)
+		})
+	}
+}
+
+func TestCreateUser(t *testing.T) {
+	user, password := randomUser(t, roleCustomer)
+
+	testCases := []struct {
+		name       string
+		body       createUserRequest
+		buildStubs func(store *mockdb.MockStore, hashedVerifyToken *string)
+		status     int
+		sent       int
+	}{
+		{
+			name: "OK",
+			body: createUserRequest{Username: user.Username, Password: password, FullName: user.FullName, Email: user.Email},
+			buildStubs: func(store *mockdb.MockStore, hashedVerifyToken *string) {
+				store.EXPECT().
+					CreateUserTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.CreateUserTxParams) (db.User, error) {
+						require.Equal(t, user.Username, arg.Username)
+						require.NoError(t, db.ValidateHashedPassword(password, arg.HashedPassword))
+						*hashedVerifyToken = arg.HashedVerifyToken
+						return user, nil
+					})
+			},
+			status: http.StatusCreated,
+			sent:   1,
+		},
+		{
+			name: "DuplicateUsername",
+			body: createUserRequest{Username: user.Username, Password: password, FullName: user.FullName, Email: user.Email},
+			buildStubs: func(store *mockdb.MockStore, hashedVerifyToken *string) {
+				store.EXPECT().CreateUserTx(gomock.Any(), gomock.Any()).Times(1).Return(db.User{}, &pq.Error{Code: "23505"})
+			},
+			status: http.StatusForbidden,
+		},
+		{
+			name: "InternalError",
+			body: createUserRequest{Username: user.Username, Password: password, FullName: user.FullName, Email: user.Email},
+			buildStubs: func(store *mockdb.MockStore, hashedVerifyToken *string) {
+				store.EXPECT().CreateUserTx(gomock.Any(), gomock.Any()).Times(1).Return(db.User{}, sql.ErrConnDone)
+			},
+			status: http.StatusInternalServerError,
+		},
+		{
+			name: "InvalidEmail",
+			body: createUserRequest{Username: user.Username, Password: password, FullName: user.FullName, Email: "invalid-email"},
+			buildStubs: func(store *mockdb.MockStore, hashedVerifyToken *string) {
+				store.EXPECT().CreateUserTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			status: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			var hashedVerifyToken string
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store, &hashedVerifyToken)
+
+			server := newTestServerWithStore(t, store)
+			recorder := doPublicRequest(t, server, http.MethodPost, "/users", tc.body)
+			require.Equal(t, tc.status, recorder.Code)
+
+			// The verification email only goes out once the user exists.
+			messages := server.mailer.(*mail.MemorySender).Messages()
+			require.Len(t, messages, tc.sent)
+			if tc.sent > 0 {
+				require.Equal(t, []string{user.Email}, messages[0].To)
+				require.Equal(t, hashedVerifyToken, hashOneTimeToken(mailedToken(t, messages[0])))
+			}
+		})
+	}
+}
+
+func TestGetCurrentUser(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	user, _ := randomUser(t, roleCustomer)
+	store := mockdb.NewMockStore(ctrl)
+	expectAuthenticated(store, user)
+
+	server := newTestServerWithStore(t, store)
+	recorder := doRequest(t, server, user, http.MethodGet, "/users/me", nil)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var rsp userResponse
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+	require.Equal(t, user.Username, rsp.Username)
+	require.Equal(t, user.Email, rsp.Email)
+}
+
+func TestUpdateCurrentUser(t *testing.T) {
+	user, _ := randomUser(t, roleCustomer)
+	newEmail := randomString(6) + "@" + randomString(4) + ".com"
+
+	testCases := []struct {
+		name       string
+		body       gin.H
+		buildStubs func(store *mockdb.MockStore, hashedVerifyToken *string)
+		status     int
+		sent       int
+	}{
+		{
+			name: "FullName",
+			body: gin.H{"full_name": "New Name"},
+			buildStubs: func(store *mockdb.MockStore, hashedVerifyToken *string) {
+				store.EXPECT().
+					UpdateUserTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.UpdateUserTxParams) (db.UpdateUserTxResult, error) {
+						require.Equal(t, user.Username, arg.Username)
+						require.Equal(t, sql.NullString{String: "New Name", Valid: true}, arg.FullName)
+						require.False(t, arg.Email.Valid)
+
+						updated := user
+						updated.FullName = arg.FullName.String
+						return db.UpdateUserTxResult{User: updated}, nil
+					})
+			},
+			status: http.StatusOK,
+		},
+		{
+			name: "Email",
+			body: gin.H{"email": newEmail},
+			buildStubs: func(store *mockdb.MockStore, hashedVerifyToken *string) {
+				store.EXPECT().
+					UpdateUserTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.UpdateUserTxParams) (db.UpdateUserTxResult, error) {
+						require.Equal(t, sql.NullString{String: newEmail, Valid: true}, arg.Email)
+						*hashedVerifyToken = arg.HashedVerifyToken
+
+						updated := user
+						updated.Email = newEmail
+						updated.IsEmailVerified = false
+						return db.UpdateUserTxResult{User: updated, EmailChanged: true}, nil
+					})
+			},
+			status: http.StatusOK,
+			sent:   1,
+		},
+		{
+			name: "DuplicateEmail",
+			body: gin.H{"email": newEmail},
+			buildStubs: func(store *mockdb.MockStore, hashedVerifyToken *string) {
+				store.EXPECT().UpdateUserTx(gomock.Any(), gomock.Any()).Times(1).Return(db.UpdateUserTxResult{}, &pq.Error{Code: "23505"})
+			},
+			status: http.StatusForbidden,
+		},
+		{
+			name: "InvalidEmail",
+			body: gin.H{"email": "invalid-email"},
+			buildStubs: func(store *mockdb.MockStore, hashedVerifyToken *string) {
+				store.EXPECT().UpdateUserTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			status: http.StatusBadRequest,
+		},
+		{
+			name: "NoFields",
+			body: gin.H{},
+			buildStubs: func(store *mockdb.MockStore, hashedVerifyToken *string) {
+				store.EXPECT().UpdateUserTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			status: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			var hashedVerifyToken string
+			store := mockdb.NewMockStore(ctrl)
+			expectAuthenticated(store, user)
+			tc.buildStubs(store, &hashedVerifyToken)
+
+			server := newTestServerWithStore(t, store)
+			recorder := doRequest(t, server, user, http.MethodPatch, "/users/me", tc.body)
+			require.Equal(t, tc.status, recorder.Code)
+
+			// Only a committed email change sends a new verification token.
+			messages := server.mailer.(*mail.MemorySender).Messages()
+			require.Len(t, messages, tc.sent)
+			if tc.sent > 0 {
+				require.Equal(t, []string{newEmail}, messages[0].To)
+				require.Equal(t, hashedVerifyToken, hashOneTimeToken(mailedToken(t, messages[0])))
+			}
+		})
+	}
+}
+
+func TestDeleteCurrentUser(t *testing.T) {
+	user, password := randomUser(t, roleCustomer)
+
+	testCases := []struct {
+		name       string
+		body       deleteCurrentUserRequest
+		buildStubs func(store *mockdb.MockStore)
+		status     int
+	}{
+		{
+			name: "OK",
+			body: deleteCurrentUserRequest{Password: password},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().DeleteUserTx(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+			},
+			status: http.StatusNoContent,
+		},
+		{
+			name: "WrongPassword",
+			body: deleteCurrentUserRequest{Password: randomString(8)},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().DeleteUserTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			status: http.StatusUnauthorized,
+		},
+		{
+			name: "AccountHasBalance",
+			body: deleteCurrentUserRequest{Password: password},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().DeleteUserTx(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(db.User{}, db.ErrAccountHasBalance)
+			},
+			status: http.StatusForbidden,
+		},
+		{
+			name: "MissingPassword",
+			body: deleteCurrentUserRequest{},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().DeleteUserTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			status: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			expectAuthenticated(store, user)
+			tc.buildStubs(store)
+
+			server := newTestServerWithStore(t, store)
+			recorder := doRequest(t, server, user, http.MethodDelete, "/users/me", tc.body)
+			require.Equal(t, tc.status, recorder.Code)
+		})
+	}
+}
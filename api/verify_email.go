@@ -0,0 +1,56 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	db "github.com/ferueda/simplebank-go/db/sqlc"
+	"github.com/ferueda/simplebank-go/mail"
+	"github.com/gin-gonic/gin"
+)
+
+var errEmailNotVerified = errors.New("email address has not been verified")
+
+func (s *Server) sendVerifyEmail(ctx *gin.Context, user db.User, verifyToken string) error {
+	link := s.config.EmailVerificationURL + "?token=" + url.QueryEscape(verifyToken)
+	msg := mail.Message{
+		To:      []string{user.Email},
+		Subject: "Verify your SimpleBank email address",
+		Body: fmt.Sprintf(
+			"Hello %s,\n\nPlease confirm your email address by opening the link below. It expires in %s.\n\n%s\n",
+			user.FullName, s.config.EmailVerificationTokenDuration, link,
+		),
+	}
+
+	return s.mailer.Send(ctx, msg)
+}
+
+type verifyEmailRequest struct {
+	Token string `form:"token" binding:"required"`
+}
+
+func (s *Server) verifyEmail(ctx *gin.Context) {
+	var req verifyEmailRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	user, err := s.store.VerifyEmailTx(ctx, hashOneTimeToken(req.Token))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			err := errors.New("verification token is invalid or has expired")
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+			return
+		}
+
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	resp := newUserResponse(user)
+	ctx.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,121 @@
+package api
+
+import (
+	"database/sql"
+	"net/http"
+	"net/url"
+	"testing"
+
+	mockdb "github.com/ferueda/simplebank-go/db/mock"
+	db "github.com/ferueda/simplebank-go/db/sqlc"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyEmail(t *testing.T) {
+	user, _ := randomUser(t, roleCustomer)
+	verifyToken, hashedToken, err := newOneTimeToken()
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name       string
+		query      string
+		buildStubs func(store *mockdb.MockStore)
+		status     int
+	}{
+		{
+			name:  "OK",
+			query: "?token=" + url.QueryEscape(verifyToken),
+			buildStubs: func(store *mockdb.MockStore) {
+				verified := user
+				verified.IsEmailVerified = true
+				store.EXPECT().VerifyEmailTx(gomock.Any(), gomock.Eq(hashedToken)).Times(1).Return(verified, nil)
+			},
+			status: http.StatusOK,
+		},
+		{
+			name:  "InvalidToken",
+			query: "?token=" + randomString(43),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().VerifyEmailTx(gomock.Any(), gomock.Any()).Times(1).Return(db.User{}, sql.ErrNoRows)
+			},
+			status: http.StatusBadRequest,
+		},
+		{
+			name:  "MissingToken",
+			query: "",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().VerifyEmailTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			status: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServerWithStore(t, store)
+			recorder := doPublicRequest(t, server, http.MethodGet, "/users/verify_email"+tc.query, nil)
+			require.Equal(t, tc.status, recorder.Code)
+		})
+	}
+}
+
+func TestTransferRequiresVerifiedEmail(t *testing.T) {
+	user, _ := randomUser(t, roleCustomer)
+	fromAcc := db.Account{ID: 1, Owner: user.Username, Balance: 1_000, Currency: "CAD", Status: db.AccountStatusActive}
+	toAcc := db.Account{ID: 2, Owner: randomString(8), Balance: 1_000, Currency: "CAD", Status: db.AccountStatusActive}
+
+	testCases := []struct {
+		name       string
+		verified   bool
+		buildStubs func(store *mockdb.MockStore)
+		status     int
+	}{
+		{
+			name:     "Verified",
+			verified: true,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(toAcc.ID)).Times(1).Return(toAcc, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(1).Return(db.TransferTxResult{FromAccount: fromAcc, ToAccount: toAcc}, nil)
+			},
+			status: http.StatusCreated,
+		},
+		{
+			name:     "NotVerified",
+			verified: false,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			status: http.StatusForbidden,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			caller := user
+			caller.IsEmailVerified = tc.verified
+
+			store := mockdb.NewMockStore(ctrl)
+			expectAuthenticated(store, caller)
+			expectCurrencies(store)
+			store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(fromAcc.ID)).Times(1).Return(fromAcc, nil)
+			tc.buildStubs(store)
+
+			server := newTestServerWithStore(t, store)
+			server.config.TransferRequiresVerifiedEmail = true
+
+			body := transferRequest{FromAccountId: fromAcc.ID, ToAccountId: toAcc.ID, Amount: 10, Currency: "CAD"}
+			recorder := doRequest(t, server, caller, http.MethodPost, "/transfers", body)
+			require.Equal(t, tc.status, recorder.Code)
+		})
+	}
+}
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS "owner_currency_key";
+ALTER TABLE "accounts" ADD CONSTRAINT "owner_currency_key" UNIQUE ("owner", "currency");
+CREATE UNIQUE INDEX "accounts_owner_currency_idx" ON "accounts" ("owner", "currency");
+
+ALTER TABLE "accounts" DROP COLUMN IF EXISTS "status";
@@ -0,0 +1,11 @@
+ALTER TABLE "accounts" ADD COLUMN "status" varchar NOT NULL DEFAULT 'active';
+
+ALTER TABLE "accounts" ADD CONSTRAINT "accounts_status_check" CHECK ("status" IN ('active', 'frozen', 'closed'));
+
+-- A closed account no longer blocks its owner from opening a new one in the
+-- same currency.
+DROP INDEX IF EXISTS "accounts_owner_currency_idx";
+ALTER TABLE "accounts" DROP CONSTRAINT IF EXISTS "owner_currency_key";
+CREATE UNIQUE INDEX "owner_currency_key" ON "accounts" ("owner", "currency") WHERE "status" <> 'closed';
+
+COMMENT ON COLUMN "accounts"."status" IS 'active, frozen or closed';
@@ -0,0 +1,11 @@
+ALTER TABLE "transfers" ADD COLUMN "to_amount" bigint;
+UPDATE "transfers" SET "to_amount" = "amount";
+ALTER TABLE "transfers" ALTER COLUMN "to_amount" SET NOT NULL;
+
+ALTER TABLE "transfers" ADD COLUMN "exchange_rate" numeric NOT NULL DEFAULT 1;
+ALTER TABLE "transfers" ADD COLUMN "spread" numeric NOT NULL DEFAULT 0;
+
+COMMENT ON COLUMN "transfers"."amount" IS 'debited from the source account, in its currency';
+COMMENT ON COLUMN "transfers"."to_amount" IS 'credited to the destination account, in its currency';
+COMMENT ON COLUMN "transfers"."exchange_rate" IS 'units of the destination currency bought by one unit of the source currency';
+COMMENT ON COLUMN "transfers"."spread" IS 'fraction of the converted amount kept as a fee';
@@ -0,0 +1,28 @@
+CREATE TABLE "currencies" (
+  "code" varchar(3) PRIMARY KEY,
+  "name" varchar NOT NULL,
+  "minor_units" integer NOT NULL,
+  "enabled" boolean NOT NULL DEFAULT false,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+ALTER TABLE "currencies" ADD CONSTRAINT "currencies_minor_units_check" CHECK ("minor_units" BETWEEN 0 AND 4);
+
+INSERT INTO "currencies" ("code", "name", "minor_units", "enabled") VALUES
+  ('CAD', 'Canadian Dollar', 2, true),
+  ('USD', 'US Dollar', 2, true),
+  ('EUR', 'Euro', 2, false),
+  ('GBP', 'Pound Sterling', 2, false),
+  ('JPY', 'Yen', 0, false);
+
+-- Keep any currency already held by an account, disabled, so the foreign
+-- key below can be added.
+INSERT INTO "currencies" ("code", "name", "minor_units", "enabled")
+SELECT DISTINCT "currency", "currency", 2, false FROM "accounts"
+ON CONFLICT ("code") DO NOTHING;
+
+ALTER TABLE "accounts" ADD FOREIGN KEY ("currency") REFERENCES "currencies" ("code");
+
+COMMENT ON COLUMN "currencies"."code" IS 'ISO 4217 alphabetic code';
+COMMENT ON COLUMN "currencies"."minor_units" IS 'ISO 4217 exponent, the number of decimal places of the minor unit';
+COMMENT ON COLUMN "currencies"."enabled" IS 'whether new accounts and transfers may use it';
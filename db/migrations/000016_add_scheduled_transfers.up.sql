@@ -0,0 +1,52 @@
+CREATE TABLE "scheduled_transfers" (
+  "id" bigserial PRIMARY KEY,
+  "owner" varchar NOT NULL,
+  "from_account_id" bigint NOT NULL,
+  "to_account_id" bigint NOT NULL,
+  "amount" bigint NOT NULL,
+  "currency" varchar NOT NULL,
+  "recurrence" varchar NOT NULL DEFAULT 'once',
+  "day_of_month" integer,
+  "next_run_at" timestamptz NOT NULL,
+  "retry_at" timestamptz,
+  "status" varchar NOT NULL DEFAULT 'active',
+  "failure_count" integer NOT NULL DEFAULT 0,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  "updated_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE TABLE "scheduled_transfer_runs" (
+  "id" bigserial PRIMARY KEY,
+  "scheduled_transfer_id" bigint NOT NULL,
+  "scheduled_for" timestamptz NOT NULL,
+  "transfer_id" bigint,
+  "status" varchar NOT NULL,
+  "error" varchar NOT NULL DEFAULT '',
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+ALTER TABLE "scheduled_transfers" ADD FOREIGN KEY ("owner") REFERENCES "users" ("username");
+ALTER TABLE "scheduled_transfers" ADD FOREIGN KEY ("from_account_id") REFERENCES "accounts" ("id");
+ALTER TABLE "scheduled_transfers" ADD FOREIGN KEY ("to_account_id") REFERENCES "accounts" ("id");
+ALTER TABLE "scheduled_transfer_runs" ADD FOREIGN KEY ("scheduled_transfer_id") REFERENCES "scheduled_transfers" ("id");
+ALTER TABLE "scheduled_transfer_runs" ADD FOREIGN KEY ("transfer_id") REFERENCES "transfers" ("id");
+
+ALTER TABLE "scheduled_transfers" ADD CONSTRAINT "scheduled_transfers_recurrence_check" CHECK ("recurrence" IN ('once', 'daily', 'weekly', 'monthly'));
+ALTER TABLE "scheduled_transfers" ADD CONSTRAINT "scheduled_transfers_monthly_check" CHECK (("recurrence" = 'monthly') = ("day_of_month" IS NOT NULL));
+ALTER TABLE "scheduled_transfers" ADD CONSTRAINT "scheduled_transfers_day_of_month_check" CHECK ("day_of_month" BETWEEN 1 AND 31);
+ALTER TABLE "scheduled_transfers" ADD CONSTRAINT "scheduled_transfers_status_check" CHECK ("status" IN ('active', 'paused', 'completed', 'cancelled'));
+ALTER TABLE "scheduled_transfers" ADD CONSTRAINT "scheduled_transfers_amount_check" CHECK ("amount" > 0);
+ALTER TABLE "scheduled_transfer_runs" ADD CONSTRAINT "scheduled_transfer_runs_status_check" CHECK ("status" IN ('succeeded', 'failed'));
+
+CREATE INDEX ON "scheduled_transfers" ("owner");
+CREATE INDEX ON "scheduled_transfers" (COALESCE("retry_at", "next_run_at")) WHERE "status" = 'active';
+CREATE INDEX ON "scheduled_transfer_runs" ("scheduled_transfer_id");
+
+COMMENT ON COLUMN "scheduled_transfers"."recurrence" IS 'once, daily, weekly or monthly';
+COMMENT ON COLUMN "scheduled_transfers"."day_of_month" IS 'day monthly transfers run on, moved to the last day of shorter months';
+COMMENT ON COLUMN "scheduled_transfers"."next_run_at" IS 'next occurrence, kept while failed runs of it are retried';
+COMMENT ON COLUMN "scheduled_transfers"."retry_at" IS 'when a failed run of the next occurrence is retried';
+COMMENT ON COLUMN "scheduled_transfers"."status" IS 'active, paused, completed or cancelled';
+COMMENT ON COLUMN "scheduled_transfers"."failure_count" IS 'consecutive failed runs';
+COMMENT ON COLUMN "scheduled_transfer_runs"."scheduled_for" IS 'next_run_at of the scheduled transfer when it ran';
+COMMENT ON COLUMN "scheduled_transfer_runs"."status" IS 'succeeded or failed';
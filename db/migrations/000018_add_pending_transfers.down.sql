@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS pending_transfers;
+
+ALTER TABLE "accounts" DROP COLUMN IF EXISTS "available_balance";
+ALTER TABLE "accounts" DROP COLUMN IF EXISTS "held_amount";
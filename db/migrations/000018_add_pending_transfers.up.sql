@@ -0,0 +1,36 @@
+ALTER TABLE "accounts" ADD COLUMN "held_amount" bigint NOT NULL DEFAULT 0;
+ALTER TABLE "accounts" ADD COLUMN "available_balance" bigint GENERATED ALWAYS AS ("balance" - "held_amount") STORED;
+
+ALTER TABLE "accounts" ADD CONSTRAINT "accounts_held_amount_check" CHECK ("held_amount" >= 0);
+
+CREATE TABLE "pending_transfers" (
+  "id" bigserial PRIMARY KEY,
+  "from_account_id" bigint NOT NULL,
+  "to_account_id" bigint NOT NULL,
+  "amount" bigint NOT NULL,
+  "currency" varchar NOT NULL,
+  "captured_amount" bigint NOT NULL DEFAULT 0,
+  "transfer_id" bigint,
+  "status" varchar NOT NULL DEFAULT 'pending',
+  "expires_at" timestamptz NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  "updated_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+ALTER TABLE "pending_transfers" ADD FOREIGN KEY ("from_account_id") REFERENCES "accounts" ("id");
+ALTER TABLE "pending_transfers" ADD FOREIGN KEY ("to_account_id") REFERENCES "accounts" ("id");
+ALTER TABLE "pending_transfers" ADD FOREIGN KEY ("transfer_id") REFERENCES "transfers" ("id");
+
+ALTER TABLE "pending_transfers" ADD CONSTRAINT "pending_transfers_amount_check" CHECK ("amount" > 0);
+ALTER TABLE "pending_transfers" ADD CONSTRAINT "pending_transfers_captured_amount_check" CHECK ("captured_amount" BETWEEN 0 AND "amount");
+ALTER TABLE "pending_transfers" ADD CONSTRAINT "pending_transfers_status_check" CHECK ("status" IN ('pending', 'captured', 'voided', 'expired'));
+
+CREATE INDEX ON "pending_transfers" ("from_account_id");
+CREATE INDEX ON "pending_transfers" ("to_account_id");
+CREATE INDEX ON "pending_transfers" ("expires_at") WHERE "status" = 'pending';
+
+COMMENT ON COLUMN "accounts"."held_amount" IS 'reserved by pending transfers, still part of the balance';
+COMMENT ON COLUMN "accounts"."available_balance" IS 'balance less held_amount, what transfers may spend';
+COMMENT ON COLUMN "pending_transfers"."amount" IS 'held on the source account until captured, voided or expired';
+COMMENT ON COLUMN "pending_transfers"."captured_amount" IS 'moved by the capture; the rest of the hold is released';
+COMMENT ON COLUMN "pending_transfers"."status" IS 'pending, captured, voided or expired';
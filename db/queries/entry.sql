@@ -17,6 +17,9 @@ ORDER BY id
 LIMIT $2
 OFFSET $3;
 
--- name: DeleteEntry :exec
-DELETE FROM entries
-WHERE account_id = $1;
\ No newline at end of file
+-- name: ListEntriesByOwner :many
+SELECT * FROM entries
+WHERE account_id IN (
+  SELECT id FROM accounts WHERE owner = $1
+)
+ORDER BY id;
@@ -0,0 +1,31 @@
+-- name: GetLoginThrottle :one
+SELECT * FROM login_throttles
+WHERE scope = $1 AND subject = $2 LIMIT 1;
+
+-- name: RecordLoginFailure :one
+INSERT INTO login_throttles (
+  scope,
+  subject,
+  failed_attempts,
+  last_failed_at
+) VALUES (
+  sqlc.arg(scope), sqlc.arg(subject), 1, now()
+)
+ON CONFLICT (scope, subject) DO UPDATE
+SET
+  failed_attempts = CASE
+    WHEN login_throttles.last_failed_at < sqlc.arg(reset_before) THEN 1
+    ELSE login_throttles.failed_attempts + 1
+  END,
+  last_failed_at = now()
+RETURNING *;
+
+-- name: UpdateLoginThrottleLockedUntil :one
+UPDATE login_throttles
+SET locked_until = $3
+WHERE scope = $1 AND subject = $2
+RETURNING *;
+
+-- name: DeleteLoginThrottle :exec
+DELETE FROM login_throttles
+WHERE scope = $1 AND subject = $2;
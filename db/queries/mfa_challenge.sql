@@ -0,0 +1,18 @@
+-- name: CreateMfaChallenge :one
+INSERT INTO mfa_challenges (
+  id,
+  username,
+  expires_at
+) VALUES (
+  $1, $2, $3
+) RETURNING *;
+
+-- name: IncrementMfaChallengeAttempts :one
+UPDATE mfa_challenges
+SET attempts = attempts + 1
+WHERE id = $1
+RETURNING *;
+
+-- name: DeleteMfaChallenge :exec
+DELETE FROM mfa_challenges
+WHERE id = $1;
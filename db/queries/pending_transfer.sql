@@ -0,0 +1,43 @@
+-- name: CreatePendingTransfer :one
+INSERT INTO pending_transfers (
+  from_account_id,
+  to_account_id,
+  amount,
+  currency,
+  expires_at
+) VALUES (
+  $1, $2, $3, $4, $5
+) RETURNING *;
+
+-- name: GetPendingTransfer :one
+SELECT * FROM pending_transfers
+WHERE id = $1 LIMIT 1;
+
+-- name: GetPendingTransferForUpdate :one
+SELECT * FROM pending_transfers
+WHERE id = $1 LIMIT 1
+FOR NO KEY UPDATE;
+
+-- name: GetExpiredPendingTransferForUpdate :one
+SELECT * FROM pending_transfers
+WHERE status = 'pending' AND expires_at <= sqlc.arg(now)::timestamptz
+ORDER BY expires_at
+LIMIT 1
+FOR UPDATE SKIP LOCKED;
+
+-- name: ListPendingTransfers :many
+SELECT * FROM pending_transfers
+WHERE from_account_id = $1
+ORDER BY id
+LIMIT $2
+OFFSET $3;
+
+-- name: UpdatePendingTransfer :one
+UPDATE pending_transfers
+SET
+  captured_amount = $2,
+  transfer_id = $3,
+  status = $4,
+  updated_at = now()
+WHERE id = $1
+RETURNING *;
@@ -0,0 +1,54 @@
+-- name: CreateScheduledTransfer :one
+INSERT INTO scheduled_transfers (
+  owner,
+  from_account_id,
+  to_account_id,
+  amount,
+  currency,
+  recurrence,
+  day_of_month,
+  next_run_at
+) VALUES (
+  $1, $2, $3, $4, $5, $6, $7, $8
+) RETURNING *;
+
+-- name: GetScheduledTransfer :one
+SELECT * FROM scheduled_transfers
+WHERE id = $1 LIMIT 1;
+
+-- name: GetScheduledTransferForUpdate :one
+SELECT * FROM scheduled_transfers
+WHERE id = $1 LIMIT 1
+FOR NO KEY UPDATE;
+
+-- name: GetDueScheduledTransferForUpdate :one
+SELECT * FROM scheduled_transfers
+WHERE status = 'active' AND COALESCE(retry_at, next_run_at) <= sqlc.arg(now)::timestamptz
+ORDER BY COALESCE(retry_at, next_run_at)
+LIMIT 1
+FOR UPDATE SKIP LOCKED;
+
+-- name: ListScheduledTransfers :many
+SELECT * FROM scheduled_transfers
+WHERE owner = $1
+ORDER BY id
+LIMIT $2
+OFFSET $3;
+
+-- name: UpdateScheduledTransfer :one
+UPDATE scheduled_transfers
+SET
+  next_run_at = $2,
+  retry_at = $3,
+  status = $4,
+  failure_count = $5,
+  updated_at = now()
+WHERE id = $1
+RETURNING *;
+
+-- name: CancelUserScheduledTransfers :exec
+UPDATE scheduled_transfers
+SET
+  status = 'cancelled',
+  updated_at = now()
+WHERE owner = $1 AND status IN ('active', 'paused');
@@ -0,0 +1,54 @@
+-- name: UpsertUserTotp :one
+INSERT INTO user_totps (
+  username,
+  encrypted_secret
+) VALUES (
+  $1, $2
+)
+ON CONFLICT (username) DO UPDATE
+SET
+  encrypted_secret = EXCLUDED.encrypted_secret,
+  is_enabled = false,
+  last_used_step = 0,
+  created_at = now()
+RETURNING *;
+
+-- name: GetUserTotp :one
+SELECT * FROM user_totps
+WHERE username = $1 LIMIT 1;
+
+-- name: EnableUserTotp :one
+UPDATE user_totps
+SET
+  is_enabled = true,
+  last_used_step = $2
+WHERE username = $1
+RETURNING *;
+
+-- name: UpdateUserTotpLastUsedStep :one
+UPDATE user_totps
+SET last_used_step = sqlc.arg(step)
+WHERE username = sqlc.arg(username) AND last_used_step < sqlc.arg(step)
+RETURNING *;
+
+-- name: DeleteUserTotp :exec
+DELETE FROM user_totps
+WHERE username = $1;
+
+-- name: CreateTotpRecoveryCode :one
+INSERT INTO totp_recovery_codes (
+  username,
+  hashed_code
+) VALUES (
+  $1, $2
+) RETURNING *;
+
+-- name: UseTotpRecoveryCode :one
+UPDATE totp_recovery_codes
+SET used_at = now()
+WHERE username = $1 AND hashed_code = $2 AND used_at IS NULL
+RETURNING *;
+
+-- name: DeleteTotpRecoveryCodes :exec
+DELETE FROM totp_recovery_codes
+WHERE username = $1;
@@ -2,15 +2,31 @@
 INSERT INTO transfers (
   from_account_id,
   to_account_id,
-  amount
+  amount,
+  to_amount,
+  exchange_rate,
+  spread,
+  reversal_of
 ) VALUES (
-  $1, $2, $3
+  $1, $2, $3, $4, $5, $6, $7
 ) RETURNING *;
 
 -- name: GetTransfer :one
 SELECT * FROM transfers
 WHERE id = $1 LIMIT 1;
 
+-- name: GetTransferForUpdate :one
+SELECT * FROM transfers
+WHERE id = $1 LIMIT 1
+FOR NO KEY UPDATE;
+
+-- name: GetTransferReversalTotals :one
+SELECT
+    COALESCE(SUM(amount), 0)::bigint AS amount,
+    COALESCE(SUM(to_amount), 0)::bigint AS to_amount
+FROM transfers
+WHERE reversal_of = $1;
+
 -- name: ListTransfers :many
 SELECT * FROM transfers
 WHERE 
@@ -20,8 +36,14 @@ ORDER BY id
 LIMIT $3
 OFFSET $4;
 
--- name: DeleteTransfer :exec
-DELETE FROM transfers
-WHERE 
-    from_account_id = $1 OR
-    to_account_id = $1;
\ No newline at end of file
+-- name: ListTransfersByOwner :many
+SELECT * FROM transfers
+WHERE
+    from_account_id IN (SELECT id FROM accounts WHERE owner = $1) OR
+    to_account_id IN (SELECT id FROM accounts WHERE owner = $1)
+ORDER BY id;
+
+-- name: ListTransferReversals :many
+SELECT * FROM transfers
+WHERE reversal_of = $1
+ORDER BY id;
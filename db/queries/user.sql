@@ -10,4 +10,51 @@ INSERT INTO users (
 
 -- name: GetUser :one
 SELECT * FROM users
-WHERE username = $1 LIMIT 1;
\ No newline at end of file
+WHERE username = $1 LIMIT 1;
+
+-- name: UpdateUser :one
+UPDATE users
+SET
+  full_name = COALESCE(sqlc.narg(full_name), full_name),
+  email = COALESCE(sqlc.narg(email), email),
+  is_email_verified = CASE
+    WHEN sqlc.narg(email) IS NULL OR sqlc.narg(email) = email THEN is_email_verified
+    ELSE false
+  END
+WHERE username = sqlc.arg(username)
+RETURNING *;
+
+-- name: UpdateUserPassword :one
+UPDATE users
+SET
+  hashed_password = $2,
+  password_changed_at = now()
+WHERE username = $1
+RETURNING *;
+
+-- name: UpdateUserRole :one
+UPDATE users
+SET role = $2
+WHERE username = $1
+RETURNING *;
+
+-- name: GetUserByEmail :one
+SELECT * FROM users
+WHERE email = $1 LIMIT 1;
+
+-- name: VerifyUserEmail :one
+UPDATE users
+SET is_email_verified = true
+WHERE username = $1 AND email = $2
+RETURNING *;
+
+-- name: AnonymizeUser :one
+UPDATE users
+SET
+  hashed_password = '',
+  full_name = '',
+  email = $2,
+  is_email_verified = false,
+  deleted_at = now()
+WHERE username = $1 AND deleted_at IS NULL
+RETURNING *;
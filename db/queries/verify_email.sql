@@ -0,0 +1,24 @@
+-- name: CreateVerifyEmail :one
+INSERT INTO verify_emails (
+  username,
+  email,
+  hashed_token,
+  expires_at
+) VALUES (
+  $1, $2, $3, $4
+) RETURNING *;
+
+-- name: GetVerifyEmailForUpdate :one
+SELECT * FROM verify_emails
+WHERE hashed_token = $1 AND used_at IS NULL AND expires_at > now()
+LIMIT 1
+FOR UPDATE;
+
+-- name: UseVerifyEmail :exec
+UPDATE verify_emails
+SET used_at = now()
+WHERE id = $1;
+
+-- name: DeleteVerifyEmails :exec
+DELETE FROM verify_emails
+WHERE username = $1;
@@ -0,0 +1,50 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateAccountStatusChange(t *testing.T) {
+	createRandomAccountStatusChange(t, createRandomAccount(t))
+}
+
+func TestListAccountStatusChanges(t *testing.T) {
+	account := createRandomAccount(t)
+	for i := 0; i < 3; i++ {
+		createRandomAccountStatusChange(t, account)
+	}
+
+	changes, err := testQueries.ListAccountStatusChanges(context.Background(), account.ID)
+	require.NoError(t, err)
+	require.Len(t, changes, 3)
+
+	for _, change := range changes {
+		require.Equal(t, account.ID, change.AccountID)
+	}
+}
+
+func createRandomAccountStatusChange(t *testing.T, account Account) AccountStatusChange {
+	arg := CreateAccountStatusChangeParams{
+		AccountID:  account.ID,
+		FromStatus: AccountStatusActive,
+		ToStatus:   AccountStatusFrozen,
+		Reason:     randomString(12),
+		ChangedBy:  account.Owner,
+	}
+
+	change, err := testQueries.CreateAccountStatusChange(context.Background(), arg)
+
+	require.NoError(t, err)
+	require.NotZero(t, change.ID)
+	require.Equal(t, arg.AccountID, change.AccountID)
+	require.Equal(t, arg.FromStatus, change.FromStatus)
+	require.Equal(t, arg.ToStatus, change.ToStatus)
+	require.Equal(t, arg.Reason, change.Reason)
+	require.Equal(t, arg.ChangedBy, change.ChangedBy)
+	require.NotZero(t, change.CreatedAt)
+
+	return change
+}
@@ -2,7 +2,6 @@ package db
 
 import (
 	"context"
-	"database/sql"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -63,17 +62,23 @@ func TestAddAccountBalance(t *testing.T) {
 	require.Equal(t, createdAcc.Currency, updatedAcc.Currency)
 }
 
-func TestDeleteAccount(t *testing.T) {
-	createdAcc := createRandomAccount(t)
-	err := testQueries.DeleteAccount(context.Background(), createdAcc.ID)
+func TestAddAccountHold(t *testing.T) {
+	account := createRandomAccount(t)
 
-	require.NoError(t, err)
+	arg := AddAccountHoldParams{
+		ID:     account.ID,
+		Amount: 500,
+	}
 
-	queriedAcc, err := testQueries.GetAccount(context.Background(), createdAcc.ID)
+	held, err := testQueries.AddAccountHold(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, account.Balance, held.Balance)
+	require.Equal(t, arg.Amount, held.HeldAmount)
+	require.Equal(t, account.Balance-arg.Amount, held.AvailableBalance)
 
+	// More cannot be released than is held.
+	_, err = testQueries.AddAccountHold(context.Background(), AddAccountHoldParams{ID: account.ID, Amount: -501})
 	require.Error(t, err)
-	require.EqualError(t, err, sql.ErrNoRows.Error())
-	require.Empty(t, queriedAcc)
 }
 
 func TestListAccounts(t *testing.T) {
@@ -97,11 +102,33 @@ func TestListAccounts(t *testing.T) {
 	}
 }
 
+func TestListAccountsByOwner(t *testing.T) {
+	account := createRandomAccount(t)
+
+	accounts, err := testQueries.ListAccountsByOwner(context.Background(), account.Owner)
+	require.NoError(t, err)
+	require.Len(t, accounts, 1)
+	require.Equal(t, account, accounts[0])
+}
+
+func TestUpdateAccountStatus(t *testing.T) {
+	account1 := createRandomAccount(t)
+	require.Equal(t, AccountStatusActive, account1.Status)
+
+	account2, err := testQueries.UpdateAccountStatus(context.Background(), UpdateAccountStatusParams{
+		ID:     account1.ID,
+		Status: AccountStatusFrozen,
+	})
+	require.NoError(t, err)
+	require.Equal(t, account1.ID, account2.ID)
+	require.Equal(t, AccountStatusFrozen, account2.Status)
+}
+
 func createRandomAccount(t *testing.T) Account {
 	user := createRandomUser(t)
 	arg := CreateAccountParams{
 		Owner:    user.Username,
-		Balance:  randomInt(0, 1_000_000),
+		Balance:  randomInt(1_000, 1_000_000),
 		Currency: "CAD",
 	}
 
@@ -112,6 +139,8 @@ func createRandomAccount(t *testing.T) Account {
 	require.Equal(t, arg.Owner, account.Owner)
 	require.Equal(t, arg.Balance, account.Balance)
 	require.Equal(t, arg.Currency, account.Currency)
+	require.Zero(t, account.HeldAmount)
+	require.Equal(t, arg.Balance, account.AvailableBalance)
 	require.NotZero(t, account.ID)
 	require.NotZero(t, account.CreatedAt)
 
@@ -0,0 +1,97 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateApiKey(t *testing.T) {
+	createRandomApiKey(t, createRandomUser(t))
+}
+
+func TestGetApiKeyByPrefix(t *testing.T) {
+	created := createRandomApiKey(t, createRandomUser(t))
+
+	queried, err := testQueries.GetApiKeyByPrefix(context.Background(), created.Prefix)
+	require.NoError(t, err)
+	require.Equal(t, created.ID, queried.ID)
+	require.Equal(t, created.Username, queried.Username)
+	require.Equal(t, created.HashedSecret, queried.HashedSecret)
+	require.Equal(t, created.Scopes, queried.Scopes)
+	require.Equal(t, created.ExpiresAt, queried.ExpiresAt)
+}
+
+func TestListApiKeys(t *testing.T) {
+	user := createRandomUser(t)
+	for i := 0; i < 3; i++ {
+		createRandomApiKey(t, user)
+	}
+
+	apiKeys, err := testQueries.ListApiKeys(context.Background(), user.Username)
+	require.NoError(t, err)
+	require.Len(t, apiKeys, 3)
+
+	for _, apiKey := range apiKeys {
+		require.Equal(t, user.Username, apiKey.Username)
+	}
+}
+
+func TestRevokeApiKey(t *testing.T) {
+	created := createRandomApiKey(t, createRandomUser(t))
+
+	arg := RevokeApiKeyParams{
+		ID:       created.ID,
+		Username: created.Username,
+	}
+
+	revoked, err := testQueries.RevokeApiKey(context.Background(), arg)
+	require.NoError(t, err)
+	require.True(t, revoked.RevokedAt.Valid)
+	require.WithinDuration(t, time.Now(), revoked.RevokedAt.Time, time.Second)
+
+	_, err = testQueries.RevokeApiKey(context.Background(), arg)
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+}
+
+func TestUpdateApiKeyLastUsed(t *testing.T) {
+	created := createRandomApiKey(t, createRandomUser(t))
+
+	err := testQueries.UpdateApiKeyLastUsed(context.Background(), created.ID)
+	require.NoError(t, err)
+
+	queried, err := testQueries.GetApiKeyByPrefix(context.Background(), created.Prefix)
+	require.NoError(t, err)
+	require.True(t, queried.LastUsedAt.Valid)
+	require.WithinDuration(t, time.Now(), queried.LastUsedAt.Time, time.Second)
+}
+
+func createRandomApiKey(t *testing.T, user User) ApiKey {
+	arg := CreateApiKeyParams{
+		Username:     user.Username,
+		Name:         randomString(8),
+		Prefix:       randomString(12),
+		HashedSecret: randomString(64),
+		Scopes:       []string{"read"},
+		ExpiresAt:    sql.NullTime{Time: time.Now().Add(time.Hour), Valid: true},
+	}
+
+	apiKey, err := testQueries.CreateApiKey(context.Background(), arg)
+
+	require.NoError(t, err)
+	require.NotZero(t, apiKey.ID)
+	require.Equal(t, arg.Username, apiKey.Username)
+	require.Equal(t, arg.Name, apiKey.Name)
+	require.Equal(t, arg.Prefix, apiKey.Prefix)
+	require.Equal(t, arg.HashedSecret, apiKey.HashedSecret)
+	require.Equal(t, arg.Scopes, apiKey.Scopes)
+	require.WithinDuration(t, arg.ExpiresAt.Time, apiKey.ExpiresAt.Time, time.Second)
+	require.False(t, apiKey.LastUsedAt.Valid)
+	require.False(t, apiKey.RevokedAt.Valid)
+	require.NotZero(t, apiKey.CreatedAt)
+
+	return apiKey
+}
@@ -0,0 +1,67 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomCurrency(t *testing.T) Currency {
+	arg := CreateCurrencyParams{
+		// Seeded codes are upper case, so a lower case one never clashes.
+		Code:       randomString(3),
+		Name:       randomString(8),
+		MinorUnits: int32(randomInt(0, 4)),
+		Enabled:    false,
+	}
+
+	currency, err := testQueries.CreateCurrency(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.Code, currency.Code)
+	require.Equal(t, arg.Name, currency.Name)
+	require.Equal(t, arg.MinorUnits, currency.MinorUnits)
+	require.Equal(t, arg.Enabled, currency.Enabled)
+	require.NotZero(t, currency.CreatedAt)
+
+	return currency
+}
+
+func TestCreateCurrency(t *testing.T) {
+	createRandomCurrency(t)
+}
+
+func TestGetCurrency(t *testing.T) {
+	cad, err := testQueries.GetCurrency(context.Background(), "CAD")
+	require.NoError(t, err)
+	require.Equal(t, int32(2), cad.MinorUnits)
+	require.True(t, cad.Enabled)
+
+	jpy, err := testQueries.GetCurrency(context.Background(), "JPY")
+	require.NoError(t, err)
+	require.Equal(t, int32(0), jpy.MinorUnits)
+}
+
+func TestListCurrencies(t *testing.T) {
+	currencies, err := testQueries.ListCurrencies(context.Background())
+	require.NoError(t, err)
+
+	codes := make(map[string]bool, len(currencies))
+	for _, c := range currencies {
+		codes[c.Code] = true
+	}
+	require.True(t, codes["CAD"])
+	require.True(t, codes["USD"])
+}
+
+func TestUpdateCurrencyEnabled(t *testing.T) {
+	currency := createRandomCurrency(t)
+
+	updated, err := testQueries.UpdateCurrencyEnabled(context.Background(), UpdateCurrencyEnabledParams{
+		Code:    currency.Code,
+		Enabled: true,
+	})
+	require.NoError(t, err)
+	require.True(t, updated.Enabled)
+	require.Equal(t, currency.MinorUnits, updated.MinorUnits)
+}
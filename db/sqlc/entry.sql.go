@@ -33,16 +33,6 @@ func (q *Queries) CreateEntry(ctx context.Context, arg CreateEntryParams) (Entry
 	return i, err
 }
 
-const deleteEntry = `-- name: DeleteEntry :exec
-DELETE FROM entries
-WHERE account_id = $1
-`
-
-func (q *Queries) DeleteEntry(ctx context.Context, accountID int64) error {
-	_, err := q.db.ExecContext(ctx, deleteEntry, accountID)
-	return err
-}
-
 const getEntry = `-- name: GetEntry :one
 SELECT id, account_id, amount, created_at FROM entries
 WHERE id = $1 LIMIT 1
@@ -101,3 +91,39 @@ func (q *Queries) ListEntries(ctx context.Context, arg ListEntriesParams) ([]Ent
 	}
 	return items, nil
 }
+
+const listEntriesByOwner = `-- name: ListEntriesByOwner :many
+SELECT id, account_id, amount, created_at FROM entries
+WHERE account_id IN (
+  SELECT id FROM accounts WHERE owner = $1
+)
+ORDER BY id
+`
+
+func (q *Queries) ListEntriesByOwner(ctx context.Context, owner string) ([]Entry, error) {
+	rows, err := q.db.QueryContext(ctx, listEntriesByOwner, owner)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Entry{}
+	for rows.Next() {
+		var i Entry
+		if err := rows.Scan(
+			&i.ID,
+			&i.AccountID,
+			&i.Amount,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -2,7 +2,6 @@ package db
 
 import (
 	"context"
-	"database/sql"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -43,15 +42,19 @@ func TestListEntries(t *testing.T) {
 	require.Equal(t, entry.ID, entries[0].ID)
 }
 
-func TestDeleteEntry(t *testing.T) {
-	entry := createRandomEntry(t, createRandomAccount(t))
-	err := testQueries.DeleteEntry(context.Background(), entry.AccountID)
+func TestListEntriesByOwner(t *testing.T) {
+	account := createRandomAccount(t)
+	for i := 0; i < 3; i++ {
+		createRandomEntry(t, account)
+	}
+
+	entries, err := testQueries.ListEntriesByOwner(context.Background(), account.Owner)
 	require.NoError(t, err)
+	require.Len(t, entries, 3)
 
-	queriedEntry, err := testQueries.GetEntry(context.Background(), entry.ID)
-	require.Error(t, err)
-	require.EqualError(t, err, sql.ErrNoRows.Error())
-	require.Empty(t, queriedEntry)
+	for _, entry := range entries {
+		require.Equal(t, account.ID, entry.AccountID)
+	}
 }
 
 func createRandomEntry(t *testing.T, acc Account) Entry {
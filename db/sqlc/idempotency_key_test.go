@@ -0,0 +1,95 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomIdempotencyKey(t *testing.T, user User, expiresAt time.Time) IdempotencyKey {
+	arg := CreateIdempotencyKeyParams{
+		Username:    user.Username,
+		Key:         randomString(16),
+		RequestHash: randomString(64),
+		Response:    json.RawMessage(`{"id":1}`),
+		ExpiresAt:   expiresAt,
+	}
+
+	key, err := testQueries.CreateIdempotencyKey(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.Username, key.Username)
+	require.Equal(t, arg.Key, key.Key)
+	require.Equal(t, arg.RequestHash, key.RequestHash)
+	require.JSONEq(t, string(arg.Response), string(key.Response))
+	require.WithinDuration(t, arg.ExpiresAt, key.ExpiresAt, time.Second)
+	require.NotZero(t, key.CreatedAt)
+
+	return key
+}
+
+func TestCreateIdempotencyKey(t *testing.T) {
+	user := createRandomUser(t)
+	created := createRandomIdempotencyKey(t, user, time.Now().Add(time.Hour))
+
+	// A live key cannot be overwritten.
+	_, err := testQueries.CreateIdempotencyKey(context.Background(), CreateIdempotencyKeyParams{
+		Username:    user.Username,
+		Key:         created.Key,
+		RequestHash: randomString(64),
+		Response:    json.RawMessage(`{}`),
+		ExpiresAt:   time.Now().Add(time.Hour),
+	})
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+
+	// The same key is independent for another user.
+	_, err = testQueries.CreateIdempotencyKey(context.Background(), CreateIdempotencyKeyParams{
+		Username:    createRandomUser(t).Username,
+		Key:         created.Key,
+		RequestHash: randomString(64),
+		Response:    json.RawMessage(`{}`),
+		ExpiresAt:   time.Now().Add(time.Hour),
+	})
+	require.NoError(t, err)
+}
+
+func TestCreateIdempotencyKeyExpired(t *testing.T) {
+	user := createRandomUser(t)
+	expired := createRandomIdempotencyKey(t, user, time.Now().Add(-time.Minute))
+
+	arg := CreateIdempotencyKeyParams{
+		Username:    user.Username,
+		Key:         expired.Key,
+		RequestHash: randomString(64),
+		Response:    json.RawMessage(`{}`),
+		ExpiresAt:   time.Now().Add(time.Hour),
+	}
+
+	key, err := testQueries.CreateIdempotencyKey(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.RequestHash, key.RequestHash)
+	require.True(t, key.ExpiresAt.After(time.Now()))
+}
+
+func TestGetIdempotencyKey(t *testing.T) {
+	user := createRandomUser(t)
+	created := createRandomIdempotencyKey(t, user, time.Now().Add(time.Hour))
+
+	queried, err := testQueries.GetIdempotencyKey(context.Background(), GetIdempotencyKeyParams{
+		Username: user.Username,
+		Key:      created.Key,
+	})
+	require.NoError(t, err)
+	require.Equal(t, created.RequestHash, queried.RequestHash)
+	require.JSONEq(t, string(created.Response), string(queried.Response))
+
+	expired := createRandomIdempotencyKey(t, user, time.Now().Add(-time.Minute))
+	_, err = testQueries.GetIdempotencyKey(context.Background(), GetIdempotencyKeyParams{
+		Username: user.Username,
+		Key:      expired.Key,
+	})
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+}
@@ -0,0 +1,90 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordLoginFailure(t *testing.T) {
+	scope := "username"
+	subject := randomString(8)
+
+	for i := int32(1); i <= 3; i++ {
+		throttle := recordTestLoginFailure(t, scope, subject, time.Now().Add(-time.Hour))
+		require.Equal(t, i, throttle.FailedAttempts)
+	}
+
+	// Failures older than reset_before no longer count.
+	throttle := recordTestLoginFailure(t, scope, subject, time.Now().Add(time.Minute))
+	require.Equal(t, int32(1), throttle.FailedAttempts)
+}
+
+func TestGetLoginThrottle(t *testing.T) {
+	created := recordTestLoginFailure(t, "client_ip", randomString(8), time.Now().Add(-time.Hour))
+
+	queried, err := testQueries.GetLoginThrottle(context.Background(), GetLoginThrottleParams{
+		Scope:   created.Scope,
+		Subject: created.Subject,
+	})
+
+	require.NoError(t, err)
+	require.Equal(t, created.Scope, queried.Scope)
+	require.Equal(t, created.Subject, queried.Subject)
+	require.Equal(t, created.FailedAttempts, queried.FailedAttempts)
+	require.Equal(t, created.LastFailedAt, queried.LastFailedAt)
+	require.Equal(t, created.LockedUntil, queried.LockedUntil)
+}
+
+func TestUpdateLoginThrottleLockedUntil(t *testing.T) {
+	created := recordTestLoginFailure(t, "username", randomString(8), time.Now().Add(-time.Hour))
+
+	arg := UpdateLoginThrottleLockedUntilParams{
+		Scope:       created.Scope,
+		Subject:     created.Subject,
+		LockedUntil: time.Now().Add(time.Minute),
+	}
+
+	updated, err := testQueries.UpdateLoginThrottleLockedUntil(context.Background(), arg)
+
+	require.NoError(t, err)
+	require.WithinDuration(t, arg.LockedUntil, updated.LockedUntil, time.Second)
+	require.Equal(t, created.FailedAttempts, updated.FailedAttempts)
+}
+
+func TestDeleteLoginThrottle(t *testing.T) {
+	created := recordTestLoginFailure(t, "username", randomString(8), time.Now().Add(-time.Hour))
+
+	err := testQueries.DeleteLoginThrottle(context.Background(), DeleteLoginThrottleParams{
+		Scope:   created.Scope,
+		Subject: created.Subject,
+	})
+	require.NoError(t, err)
+
+	queried, err := testQueries.GetLoginThrottle(context.Background(), GetLoginThrottleParams{
+		Scope:   created.Scope,
+		Subject: created.Subject,
+	})
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+	require.Empty(t, queried)
+}
+
+func recordTestLoginFailure(t *testing.T, scope, subject string, resetBefore time.Time) LoginThrottle {
+	arg := RecordLoginFailureParams{
+		Scope:       scope,
+		Subject:     subject,
+		ResetBefore: resetBefore,
+	}
+
+	throttle, err := testQueries.RecordLoginFailure(context.Background(), arg)
+
+	require.NoError(t, err)
+	require.Equal(t, arg.Scope, throttle.Scope)
+	require.Equal(t, arg.Subject, throttle.Subject)
+	require.WithinDuration(t, time.Now(), throttle.LastFailedAt, time.Second)
+
+	return throttle
+}
@@ -0,0 +1,54 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateMfaChallenge(t *testing.T) {
+	createRandomMfaChallenge(t)
+}
+
+func TestIncrementMfaChallengeAttempts(t *testing.T) {
+	challenge := createRandomMfaChallenge(t)
+
+	updatedChallenge, err := testQueries.IncrementMfaChallengeAttempts(context.Background(), challenge.ID)
+	require.NoError(t, err)
+	require.Equal(t, challenge.ID, updatedChallenge.ID)
+	require.Equal(t, challenge.Attempts+1, updatedChallenge.Attempts)
+}
+
+func TestDeleteMfaChallenge(t *testing.T) {
+	challenge := createRandomMfaChallenge(t)
+
+	err := testQueries.DeleteMfaChallenge(context.Background(), challenge.ID)
+	require.NoError(t, err)
+
+	_, err = testQueries.IncrementMfaChallengeAttempts(context.Background(), challenge.ID)
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+}
+
+func createRandomMfaChallenge(t *testing.T) MfaChallenge {
+	user := createRandomUser(t)
+	arg := CreateMfaChallengeParams{
+		ID:        uuid.New(),
+		Username:  user.Username,
+		ExpiresAt: time.Now().Add(time.Minute),
+	}
+
+	challenge, err := testQueries.CreateMfaChallenge(context.Background(), arg)
+
+	require.NoError(t, err)
+	require.Equal(t, arg.ID, challenge.ID)
+	require.Equal(t, arg.Username, challenge.Username)
+	require.Zero(t, challenge.Attempts)
+	require.WithinDuration(t, arg.ExpiresAt, challenge.ExpiresAt, time.Second)
+	require.NotZero(t, challenge.CreatedAt)
+
+	return challenge
+}
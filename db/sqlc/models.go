@@ -3,7 +3,11 @@
 package db
 
 import (
+	"database/sql"
+	"encoding/json"
 	"time"
+
+	"github.com/google/uuid"
 )
 
 type Account struct {
@@ -12,6 +16,49 @@ type Account struct {
 	Balance   int64     `json:"balance"`
 	Currency  string    `json:"currency"`
 	CreatedAt time.Time `json:"created_at"`
+	// active, frozen or closed
+	Status string `json:"status"`
+	// reserved by pending transfers, still part of the balance
+	HeldAmount int64 `json:"held_amount"`
+	// balance less held_amount, what transfers may spend
+	AvailableBalance int64 `json:"available_balance"`
+}
+
+type AccountStatusChange struct {
+	ID         int64  `json:"id"`
+	AccountID  int64  `json:"account_id"`
+	FromStatus string `json:"from_status"`
+	ToStatus   string `json:"to_status"`
+	Reason     string `json:"reason"`
+	// username of the user who made the change
+	ChangedBy string    `json:"changed_by"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type ApiKey struct {
+	ID       int64  `json:"id"`
+	Username string `json:"username"`
+	Name     string `json:"name"`
+	// public identifier sent in front of the secret
+	Prefix       string `json:"prefix"`
+	HashedSecret string `json:"hashed_secret"`
+	// read, write
+	Scopes     []string     `json:"scopes"`
+	LastUsedAt sql.NullTime `json:"last_used_at"`
+	ExpiresAt  sql.NullTime `json:"expires_at"`
+	RevokedAt  sql.NullTime `json:"revoked_at"`
+	CreatedAt  time.Time    `json:"created_at"`
+}
+
+type Currency struct {
+	// ISO 4217 alphabetic code
+	Code string `json:"code"`
+	Name string `json:"name"`
+	// ISO 4217 exponent, the number of decimal places of the minor unit
+	MinorUnits int32 `json:"minor_units"`
+	// whether new accounts and transfers may use it
+	Enabled   bool      `json:"enabled"`
+	CreatedAt time.Time `json:"created_at"`
 }
 
 type Entry struct {
@@ -22,13 +69,135 @@ type Entry struct {
 	CreatedAt time.Time `json:"created_at"`
 }
 
+type IdempotencyKey struct {
+	Username string `json:"username"`
+	Key      string `json:"key"`
+	// sha256 of the request body the key was first used with
+	RequestHash string          `json:"request_hash"`
+	Response    json.RawMessage `json:"response"`
+	ExpiresAt   time.Time       `json:"expires_at"`
+	CreatedAt   time.Time       `json:"created_at"`
+}
+
+type LoginThrottle struct {
+	// username or client_ip
+	Scope          string    `json:"scope"`
+	Subject        string    `json:"subject"`
+	FailedAttempts int32     `json:"failed_attempts"`
+	LastFailedAt   time.Time `json:"last_failed_at"`
+	LockedUntil    time.Time `json:"locked_until"`
+}
+
+type MfaChallenge struct {
+	ID        uuid.UUID `json:"id"`
+	Username  string    `json:"username"`
+	Attempts  int32     `json:"attempts"`
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type PasswordResetToken struct {
+	ID          int64        `json:"id"`
+	Username    string       `json:"username"`
+	HashedToken string       `json:"hashed_token"`
+	ExpiresAt   time.Time    `json:"expires_at"`
+	UsedAt      sql.NullTime `json:"used_at"`
+	CreatedAt   time.Time    `json:"created_at"`
+}
+
+type PendingTransfer struct {
+	ID            int64 `json:"id"`
+	FromAccountID int64 `json:"from_account_id"`
+	ToAccountID   int64 `json:"to_account_id"`
+	// held on the source account until captured, voided or expired
+	Amount   int64  `json:"amount"`
+	Currency string `json:"currency"`
+	// moved by the capture; the rest of the hold is released
+	CapturedAmount int64         `json:"captured_amount"`
+	TransferID     sql.NullInt64 `json:"transfer_id"`
+	// pending, captured, voided or expired
+	Status    string    `json:"status"`
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type RevokedToken struct {
+	ID        uuid.UUID `json:"id"`
+	Username  string    `json:"username"`
+	ExpiresAt time.Time `json:"expires_at"`
+	RevokedAt time.Time `json:"revoked_at"`
+}
+
+type ScheduledTransfer struct {
+	ID            int64  `json:"id"`
+	Owner         string `json:"owner"`
+	FromAccountID int64  `json:"from_account_id"`
+	ToAccountID   int64  `json:"to_account_id"`
+	Amount        int64  `json:"amount"`
+	Currency      string `json:"currency"`
+	// once, daily, weekly or monthly
+	Recurrence string `json:"recurrence"`
+	// day monthly transfers run on, moved to the last day of shorter months
+	DayOfMonth sql.NullInt32 `json:"day_of_month"`
+	// next occurrence, kept while failed runs of it are retried
+	NextRunAt time.Time `json:"next_run_at"`
+	// when a failed run of the next occurrence is retried
+	RetryAt sql.NullTime `json:"retry_at"`
+	// active, paused, completed or cancelled
+	Status string `json:"status"`
+	// consecutive failed runs
+	FailureCount int32     `json:"failure_count"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+type ScheduledTransferRun struct {
+	ID                  int64 `json:"id"`
+	ScheduledTransferID int64 `json:"scheduled_transfer_id"`
+	// next_run_at of the scheduled transfer when it ran
+	ScheduledFor time.Time     `json:"scheduled_for"`
+	TransferID   sql.NullInt64 `json:"transfer_id"`
+	// succeeded or failed
+	Status    string    `json:"status"`
+	Error     string    `json:"error"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type Session struct {
+	ID           uuid.UUID `json:"id"`
+	Username     string    `json:"username"`
+	RefreshToken string    `json:"refresh_token"`
+	UserAgent    string    `json:"user_agent"`
+	ClientIp     string    `json:"client_ip"`
+	IsBlocked    bool      `json:"is_blocked"`
+	ExpiresAt    time.Time `json:"expires_at"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+type TotpRecoveryCode struct {
+	ID         int64        `json:"id"`
+	Username   string       `json:"username"`
+	HashedCode string       `json:"hashed_code"`
+	UsedAt     sql.NullTime `json:"used_at"`
+	CreatedAt  time.Time    `json:"created_at"`
+}
+
 type Transfer struct {
 	ID            int64 `json:"id"`
 	FromAccountID int64 `json:"from_account_id"`
 	ToAccountID   int64 `json:"to_account_id"`
-	// must be positive
+	// debited from the source account, in its currency
 	Amount    int64     `json:"amount"`
 	CreatedAt time.Time `json:"created_at"`
+	// credited to the destination account, in its currency
+	ToAmount int64 `json:"to_amount"`
+	// units of the destination currency bought by one unit of the source currency
+	ExchangeRate string `json:"exchange_rate"`
+	// fraction of the converted amount kept as a fee
+	Spread string `json:"spread"`
+	// transfer this one refunds, in whole or in part
+	ReversalOf sql.NullInt64 `json:"reversal_of"`
 }
 
 type User struct {
@@ -38,4 +207,28 @@ type User struct {
 	FullName          string    `json:"full_name"`
 	Email             string    `json:"email"`
 	CreatedAt         time.Time `json:"created_at"`
+	Role              string    `json:"role"`
+	IsEmailVerified   bool      `json:"is_email_verified"`
+	// set when the user closed their profile and personal data was anonymized
+	DeletedAt sql.NullTime `json:"deleted_at"`
+}
+
+type UserTotp struct {
+	Username string `json:"username"`
+	// AES-GCM encrypted base32 secret
+	EncryptedSecret []byte `json:"encrypted_secret"`
+	IsEnabled       bool   `json:"is_enabled"`
+	// time step of the last accepted code, to prevent replay
+	LastUsedStep int64     `json:"last_used_step"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+type VerifyEmail struct {
+	ID          int64        `json:"id"`
+	Username    string       `json:"username"`
+	Email       string       `json:"email"`
+	HashedToken string       `json:"hashed_token"`
+	ExpiresAt   time.Time    `json:"expires_at"`
+	UsedAt      sql.NullTime `json:"used_at"`
+	CreatedAt   time.Time    `json:"created_at"`
 }
@@ -0,0 +1,64 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreatePasswordResetToken(t *testing.T) {
+	createRandomPasswordResetToken(t, time.Now().Add(time.Hour))
+}
+
+func TestGetPasswordResetTokenForUpdate(t *testing.T) {
+	created := createRandomPasswordResetToken(t, time.Now().Add(time.Hour))
+
+	queried, err := testQueries.GetPasswordResetTokenForUpdate(context.Background(), created.HashedToken)
+	require.NoError(t, err)
+	require.Equal(t, created.ID, queried.ID)
+	require.Equal(t, created.Username, queried.Username)
+	require.Equal(t, created.HashedToken, queried.HashedToken)
+	require.WithinDuration(t, created.ExpiresAt, queried.ExpiresAt, time.Second)
+}
+
+func TestGetExpiredPasswordResetToken(t *testing.T) {
+	created := createRandomPasswordResetToken(t, time.Now().Add(-time.Minute))
+
+	_, err := testQueries.GetPasswordResetTokenForUpdate(context.Background(), created.HashedToken)
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+}
+
+func TestUsePasswordResetTokens(t *testing.T) {
+	created := createRandomPasswordResetToken(t, time.Now().Add(time.Hour))
+
+	err := testQueries.UsePasswordResetTokens(context.Background(), created.Username)
+	require.NoError(t, err)
+
+	_, err = testQueries.GetPasswordResetTokenForUpdate(context.Background(), created.HashedToken)
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+}
+
+func createRandomPasswordResetToken(t *testing.T, expiresAt time.Time) PasswordResetToken {
+	user := createRandomUser(t)
+
+	arg := CreatePasswordResetTokenParams{
+		Username:    user.Username,
+		HashedToken: randomString(64),
+		ExpiresAt:   expiresAt,
+	}
+
+	resetToken, err := testQueries.CreatePasswordResetToken(context.Background(), arg)
+
+	require.NoError(t, err)
+	require.NotZero(t, resetToken.ID)
+	require.Equal(t, arg.Username, resetToken.Username)
+	require.Equal(t, arg.HashedToken, resetToken.HashedToken)
+	require.WithinDuration(t, arg.ExpiresAt, resetToken.ExpiresAt, time.Second)
+	require.False(t, resetToken.UsedAt.Valid)
+	require.NotZero(t, resetToken.CreatedAt)
+
+	return resetToken
+}
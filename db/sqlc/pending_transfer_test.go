@@ -0,0 +1,92 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomPendingTransfer(t *testing.T, from, to Account, expiresAt time.Time) PendingTransfer {
+	arg := CreatePendingTransferParams{
+		FromAccountID: from.ID,
+		ToAccountID:   to.ID,
+		Amount:        randomInt(1, 100),
+		Currency:      from.Currency,
+		ExpiresAt:     expiresAt,
+	}
+
+	pending, err := testQueries.CreatePendingTransfer(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.FromAccountID, pending.FromAccountID)
+	require.Equal(t, arg.ToAccountID, pending.ToAccountID)
+	require.Equal(t, arg.Amount, pending.Amount)
+	require.Equal(t, arg.Currency, pending.Currency)
+	require.WithinDuration(t, arg.ExpiresAt, pending.ExpiresAt, time.Second)
+	require.Zero(t, pending.CapturedAmount)
+	require.False(t, pending.TransferID.Valid)
+	require.Equal(t, PendingTransferStatusPending, pending.Status)
+
+	return pending
+}
+
+func TestCreatePendingTransfer(t *testing.T) {
+	createRandomPendingTransfer(t, createRandomAccount(t), createRandomAccount(t), time.Now().Add(time.Hour))
+}
+
+func TestGetPendingTransfer(t *testing.T) {
+	pending1 := createRandomPendingTransfer(t, createRandomAccount(t), createRandomAccount(t), time.Now().Add(time.Hour))
+
+	pending2, err := testQueries.GetPendingTransfer(context.Background(), pending1.ID)
+	require.NoError(t, err)
+	require.Equal(t, pending1, pending2)
+
+	_, err = testQueries.GetPendingTransfer(context.Background(), pending1.ID+1_000_000_000)
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+}
+
+func TestListPendingTransfers(t *testing.T) {
+	from := createRandomAccount(t)
+	to := createRandomAccount(t)
+
+	for i := 0; i < 3; i++ {
+		createRandomPendingTransfer(t, from, to, time.Now().Add(time.Hour))
+	}
+
+	// Pending transfers are listed by their source account only.
+	createRandomPendingTransfer(t, to, from, time.Now().Add(time.Hour))
+
+	pending, err := testQueries.ListPendingTransfers(context.Background(), ListPendingTransfersParams{
+		FromAccountID: from.ID,
+		Limit:         5,
+		Offset:        0,
+	})
+	require.NoError(t, err)
+	require.Len(t, pending, 3)
+
+	for _, p := range pending {
+		require.Equal(t, from.ID, p.FromAccountID)
+	}
+}
+
+func TestUpdatePendingTransfer(t *testing.T) {
+	pending := createRandomPendingTransfer(t, createRandomAccount(t), createRandomAccount(t), time.Now().Add(time.Hour))
+
+	arg := UpdatePendingTransferParams{
+		ID:             pending.ID,
+		CapturedAmount: pending.Amount,
+		Status:         PendingTransferStatusCaptured,
+	}
+
+	updated, err := testQueries.UpdatePendingTransfer(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.CapturedAmount, updated.CapturedAmount)
+	require.Equal(t, arg.Status, updated.Status)
+
+	// A capture cannot move more than was held.
+	arg.CapturedAmount = pending.Amount + 1
+	_, err = testQueries.UpdatePendingTransfer(context.Background(), arg)
+	require.Error(t, err)
+}
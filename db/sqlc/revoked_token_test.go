@@ -0,0 +1,62 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateRevokedToken(t *testing.T) {
+	createRandomRevokedToken(t, time.Now().Add(time.Hour))
+}
+
+func TestIsTokenRevoked(t *testing.T) {
+	revokedToken := createRandomRevokedToken(t, time.Now().Add(time.Hour))
+
+	revoked, err := testQueries.IsTokenRevoked(context.Background(), revokedToken.ID)
+	require.NoError(t, err)
+	require.True(t, revoked)
+
+	revoked, err = testQueries.IsTokenRevoked(context.Background(), uuid.New())
+	require.NoError(t, err)
+	require.False(t, revoked)
+}
+
+func TestDeleteExpiredRevokedTokens(t *testing.T) {
+	expiredToken := createRandomRevokedToken(t, time.Now().Add(-time.Minute))
+	activeToken := createRandomRevokedToken(t, time.Now().Add(time.Hour))
+
+	err := testQueries.DeleteExpiredRevokedTokens(context.Background())
+	require.NoError(t, err)
+
+	revoked, err := testQueries.IsTokenRevoked(context.Background(), expiredToken.ID)
+	require.NoError(t, err)
+	require.False(t, revoked)
+
+	revoked, err = testQueries.IsTokenRevoked(context.Background(), activeToken.ID)
+	require.NoError(t, err)
+	require.True(t, revoked)
+}
+
+func createRandomRevokedToken(t *testing.T, expiresAt time.Time) RevokedToken {
+	user := createRandomUser(t)
+	arg := CreateRevokedTokenParams{
+		ID:        uuid.New(),
+		Username:  user.Username,
+		ExpiresAt: expiresAt,
+	}
+
+	revokedToken, err := testQueries.CreateRevokedToken(context.Background(), arg)
+
+	require.NoError(t, err)
+	require.NotEmpty(t, revokedToken)
+	require.Equal(t, arg.ID, revokedToken.ID)
+	require.Equal(t, arg.Username, revokedToken.Username)
+	require.WithinDuration(t, arg.ExpiresAt, revokedToken.ExpiresAt, time.Second)
+	require.NotZero(t, revokedToken.RevokedAt)
+
+	return revokedToken
+}
@@ -0,0 +1,149 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomScheduledTransfer(t *testing.T, from, to Account, recurrence string, nextRunAt time.Time) ScheduledTransfer {
+	arg := CreateScheduledTransferParams{
+		Owner:         from.Owner,
+		FromAccountID: from.ID,
+		ToAccountID:   to.ID,
+		Amount:        randomInt(1, 100),
+		Currency:      from.Currency,
+		Recurrence:    recurrence,
+		NextRunAt:     nextRunAt,
+	}
+	if recurrence == RecurrenceMonthly {
+		arg.DayOfMonth = sql.NullInt32{Int32: int32(nextRunAt.Day()), Valid: true}
+	}
+
+	scheduled, err := testQueries.CreateScheduledTransfer(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.Owner, scheduled.Owner)
+	require.Equal(t, arg.FromAccountID, scheduled.FromAccountID)
+	require.Equal(t, arg.ToAccountID, scheduled.ToAccountID)
+	require.Equal(t, arg.Amount, scheduled.Amount)
+	require.Equal(t, arg.Currency, scheduled.Currency)
+	require.Equal(t, arg.Recurrence, scheduled.Recurrence)
+	require.Equal(t, arg.DayOfMonth, scheduled.DayOfMonth)
+	require.WithinDuration(t, arg.NextRunAt, scheduled.NextRunAt, time.Second)
+	require.False(t, scheduled.RetryAt.Valid)
+	require.Equal(t, ScheduledTransferStatusActive, scheduled.Status)
+	require.Zero(t, scheduled.FailureCount)
+
+	return scheduled
+}
+
+func TestCreateScheduledTransfer(t *testing.T) {
+	createRandomScheduledTransfer(t, createRandomAccount(t), createRandomAccount(t), RecurrenceOnce, time.Now().Add(time.Hour))
+
+	// Monthly transfers need a day of the month, and only they take one.
+	from, to := createRandomAccount(t), createRandomAccount(t)
+	_, err := testQueries.CreateScheduledTransfer(context.Background(), CreateScheduledTransferParams{
+		Owner:         from.Owner,
+		FromAccountID: from.ID,
+		ToAccountID:   to.ID,
+		Amount:        10,
+		Currency:      from.Currency,
+		Recurrence:    RecurrenceMonthly,
+		NextRunAt:     time.Now().Add(time.Hour),
+	})
+	require.Error(t, err)
+}
+
+func TestGetScheduledTransfer(t *testing.T) {
+	created := createRandomScheduledTransfer(t, createRandomAccount(t), createRandomAccount(t), RecurrenceMonthly, time.Now().Add(time.Hour))
+
+	queried, err := testQueries.GetScheduledTransfer(context.Background(), created.ID)
+	require.NoError(t, err)
+	require.Equal(t, created.ID, queried.ID)
+	require.Equal(t, created.DayOfMonth, queried.DayOfMonth)
+	require.Equal(t, created.NextRunAt, queried.NextRunAt)
+}
+
+func TestListScheduledTransfers(t *testing.T) {
+	from := createRandomAccount(t)
+	for i := 0; i < 3; i++ {
+		createRandomScheduledTransfer(t, from, createRandomAccount(t), RecurrenceDaily, time.Now().Add(time.Hour))
+	}
+
+	scheduled, err := testQueries.ListScheduledTransfers(context.Background(), ListScheduledTransfersParams{
+		Owner:  from.Owner,
+		Limit:  5,
+		Offset: 0,
+	})
+	require.NoError(t, err)
+	require.Len(t, scheduled, 3)
+
+	for _, st := range scheduled {
+		require.Equal(t, from.Owner, st.Owner)
+	}
+}
+
+func TestUpdateScheduledTransfer(t *testing.T) {
+	created := createRandomScheduledTransfer(t, createRandomAccount(t), createRandomAccount(t), RecurrenceDaily, time.Now().Add(time.Hour))
+
+	arg := UpdateScheduledTransferParams{
+		ID:           created.ID,
+		NextRunAt:    created.NextRunAt.AddDate(0, 0, 1),
+		RetryAt:      sql.NullTime{Time: time.Now().Add(time.Minute), Valid: true},
+		Status:       ScheduledTransferStatusPaused,
+		FailureCount: 2,
+	}
+
+	updated, err := testQueries.UpdateScheduledTransfer(context.Background(), arg)
+	require.NoError(t, err)
+	require.WithinDuration(t, arg.NextRunAt, updated.NextRunAt, time.Second)
+	require.True(t, updated.RetryAt.Valid)
+	require.Equal(t, arg.Status, updated.Status)
+	require.Equal(t, arg.FailureCount, updated.FailureCount)
+}
+
+func TestCancelUserScheduledTransfers(t *testing.T) {
+	from := createRandomAccount(t)
+	created := createRandomScheduledTransfer(t, from, createRandomAccount(t), RecurrenceWeekly, time.Now().Add(time.Hour))
+
+	err := testQueries.CancelUserScheduledTransfers(context.Background(), from.Owner)
+	require.NoError(t, err)
+
+	queried, err := testQueries.GetScheduledTransfer(context.Background(), created.ID)
+	require.NoError(t, err)
+	require.Equal(t, ScheduledTransferStatusCancelled, queried.Status)
+}
+
+func TestScheduledTransferRuns(t *testing.T) {
+	from, to := createRandomAccount(t), createRandomAccount(t)
+	scheduled := createRandomScheduledTransfer(t, from, to, RecurrenceDaily, time.Now().Add(time.Hour))
+	transfer := createRandomTransfer(t, from, to)
+
+	succeeded, err := testQueries.CreateScheduledTransferRun(context.Background(), CreateScheduledTransferRunParams{
+		ScheduledTransferID: scheduled.ID,
+		ScheduledFor:        scheduled.NextRunAt,
+		TransferID:          sql.NullInt64{Int64: transfer.ID, Valid: true},
+		Status:              ScheduledTransferRunSucceeded,
+	})
+	require.NoError(t, err)
+	require.Equal(t, transfer.ID, succeeded.TransferID.Int64)
+
+	failed, err := testQueries.CreateScheduledTransferRun(context.Background(), CreateScheduledTransferRunParams{
+		ScheduledTransferID: scheduled.ID,
+		ScheduledFor:        scheduled.NextRunAt,
+		Status:              ScheduledTransferRunFailed,
+		Error:               ErrInsufficientFunds.Error(),
+	})
+	require.NoError(t, err)
+	require.False(t, failed.TransferID.Valid)
+
+	runs, err := testQueries.ListScheduledTransferRuns(context.Background(), scheduled.ID)
+	require.NoError(t, err)
+	require.Len(t, runs, 2)
+	require.Equal(t, succeeded.ID, runs[0].ID)
+	require.Equal(t, failed.ID, runs[1].ID)
+	require.Equal(t, ErrInsufficientFunds.Error(), runs[1].Error)
+}
@@ -0,0 +1,68 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateSession(t *testing.T) {
+	createRandomSession(t)
+}
+
+func TestGetSession(t *testing.T) {
+	createdSession := createRandomSession(t)
+	queriedSession, err := testQueries.GetSession(context.Background(), createdSession.ID)
+
+	require.NoError(t, err)
+	require.NotEmpty(t, queriedSession)
+	require.Equal(t, createdSession.ID, queriedSession.ID)
+	require.Equal(t, createdSession.Username, queriedSession.Username)
+	require.Equal(t, createdSession.RefreshToken, queriedSession.RefreshToken)
+	require.Equal(t, createdSession.UserAgent, queriedSession.UserAgent)
+	require.Equal(t, createdSession.ClientIp, queriedSession.ClientIp)
+	require.Equal(t, createdSession.IsBlocked, queriedSession.IsBlocked)
+	require.WithinDuration(t, createdSession.ExpiresAt, queriedSession.ExpiresAt, time.Second)
+	require.Equal(t, createdSession.CreatedAt, queriedSession.CreatedAt)
+}
+
+func TestBlockSession(t *testing.T) {
+	createdSession := createRandomSession(t)
+	blockedSession, err := testQueries.BlockSession(context.Background(), createdSession.ID)
+
+	require.NoError(t, err)
+	require.NotEmpty(t, blockedSession)
+	require.Equal(t, createdSession.ID, blockedSession.ID)
+	require.True(t, blockedSession.IsBlocked)
+}
+
+func createRandomSession(t *testing.T) Session {
+	user := createRandomUser(t)
+	arg := CreateSessionParams{
+		ID:           uuid.New(),
+		Username:     user.Username,
+		RefreshToken: randomString(32),
+		UserAgent:    randomString(10),
+		ClientIp:     "127.0.0.1",
+		IsBlocked:    false,
+		ExpiresAt:    time.Now().Add(time.Hour),
+	}
+
+	session, err := testQueries.CreateSession(context.Background(), arg)
+
+	require.NoError(t, err)
+	require.NotEmpty(t, session)
+	require.Equal(t, arg.ID, session.ID)
+	require.Equal(t, arg.Username, session.Username)
+	require.Equal(t, arg.RefreshToken, session.RefreshToken)
+	require.Equal(t, arg.UserAgent, session.UserAgent)
+	require.Equal(t, arg.ClientIp, session.ClientIp)
+	require.False(t, session.IsBlocked)
+	require.WithinDuration(t, arg.ExpiresAt, session.ExpiresAt, time.Second)
+	require.NotZero(t, session.CreatedAt)
+
+	return session
+}
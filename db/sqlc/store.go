@@ -3,20 +3,203 @@ package db
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"math/big"
+	"time"
 
+	"github.com/google/uuid"
 	"golang.org/x/crypto/bcrypt"
 )
 
-type Store struct {
+const (
+	AccountStatusActive = "active"
+	AccountStatusFrozen = "frozen"
+	AccountStatusClosed = "closed"
+)
+
+const (
+	RecurrenceOnce    = "once"
+	RecurrenceDaily   = "daily"
+	RecurrenceWeekly  = "weekly"
+	RecurrenceMonthly = "monthly"
+)
+
+const (
+	ScheduledTransferStatusActive    = "active"
+	ScheduledTransferStatusPaused    = "paused"
+	ScheduledTransferStatusCompleted = "completed"
+	ScheduledTransferStatusCancelled = "cancelled"
+)
+
+const (
+	ScheduledTransferRunSucceeded = "succeeded"
+	ScheduledTransferRunFailed    = "failed"
+)
+
+const (
+	PendingTransferStatusPending  = "pending"
+	PendingTransferStatusCaptured = "captured"
+	PendingTransferStatusVoided   = "voided"
+	PendingTransferStatusExpired  = "expired"
+)
+
+var (
+	// ErrAccountHasBalance is returned when closing an account, or a user,
+	// that still holds funds.
+	ErrAccountHasBalance = errors.New("account balance must be zero")
+	// ErrAccountNotActive is returned when moving money to or from, or
+	// closing, an account that is not active.
+	ErrAccountNotActive = errors.New("account is not active")
+	// ErrAccountFrozen is returned when debiting a frozen account, or
+	// crediting one when that is not allowed.
+	ErrAccountFrozen = errors.New("account is frozen")
+	// ErrInsufficientFunds is returned when the available balance of the
+	// source account of a transfer, or of a hold, is less than the amount.
+	ErrInsufficientFunds = errors.New("insufficient funds")
+	// ErrCurrencyMismatch is returned when either account of a transfer is
+	// not held in the transfer currency.
+	ErrCurrencyMismatch = errors.New("account currency does not match transfer currency")
+	// ErrInvalidStatusChange is returned when an account or scheduled
+	// transfer cannot move from its current status to the requested one.
+	ErrInvalidStatusChange = errors.New("invalid status change")
+	// ErrIdempotencyKeyInUse is returned by TransferTx when another request
+	// recorded the same, unexpired, idempotency key first.
+	ErrIdempotencyKeyInUse = errors.New("idempotency key already used")
+	// ErrTransferIsReversal is returned when reversing a reversal.
+	ErrTransferIsReversal = errors.New("a reversal cannot be reversed")
+	// ErrTransferReversed is returned when reversing a transfer that has
+	// already been refunded in full.
+	ErrTransferReversed = errors.New("transfer has already been reversed")
+	// ErrReversalExceedsTransfer is returned when a reversal asks for more
+	// than is left to refund of a transfer.
+	ErrReversalExceedsTransfer = errors.New("reversal amount exceeds what is left of the transfer")
+	// ErrReversalTooSmall is returned when a partial reversal of a
+	// cross-currency transfer would debit nothing once converted.
+	ErrReversalTooSmall = errors.New("reversal amount is too small to convert")
+	// ErrTransferNotPending is returned when capturing or voiding a pending
+	// transfer that has already been captured, voided or expired.
+	ErrTransferNotPending = errors.New("transfer is no longer pending")
+	// ErrHoldExpired is returned when capturing a pending transfer past its
+	// expiry, before its hold has been released.
+	ErrHoldExpired = errors.New("hold has expired")
+	// ErrCaptureExceedsHold is returned when capturing more than was held.
+	ErrCaptureExceedsHold = errors.New("capture amount exceeds the held amount")
+)
+
+// statusChanges lists the statuses an account may be moved to by
+// ChangeAccountStatusTx, keyed by its current status. Closing goes through
+// CloseAccountTx.
+var statusChanges = map[string]string{
+	AccountStatusActive: AccountStatusFrozen,
+	AccountStatusFrozen: AccountStatusActive,
+}
+
+// scheduledTransferStatusChanges lists the statuses a scheduled transfer may
+// be moved to by ChangeScheduledTransferStatusTx, keyed by its current
+// status. Completed and cancelled transfers are final.
+var scheduledTransferStatusChanges = map[string][]string{
+	ScheduledTransferStatusActive: {ScheduledTransferStatusPaused, ScheduledTransferStatusCancelled},
+	ScheduledTransferStatusPaused: {ScheduledTransferStatusActive, ScheduledTransferStatusCancelled},
+}
+
+// Store provides all functions to execute db queries and transactions.
+type Store interface {
+	Querier
+	ChangeAccountStatusTx(ctx context.Context, arg ChangeAccountStatusTxParams) (Account, error)
+	CloseAccountTx(ctx context.Context, accountId int64) (Account, error)
+	TransferTx(ctx context.Context, arg TransferTxParams) (TransferTxResult, error)
+	ReverseTransferTx(ctx context.Context, arg ReverseTransferTxParams) (TransferTxResult, error)
+	CreatePendingTransferTx(ctx context.Context, arg CreatePendingTransferTxParams) (PendingTransferTxResult, error)
+	CapturePendingTransferTx(ctx context.Context, arg CapturePendingTransferTxParams) (CapturePendingTransferTxResult, error)
+	VoidPendingTransferTx(ctx context.Context, id int64) (PendingTransferTxResult, error)
+	ExpirePendingTransferTx(ctx context.Context, now time.Time) (PendingTransferTxResult, error)
+	ChangeScheduledTransferStatusTx(ctx context.Context, id int64, status string) (ScheduledTransfer, error)
+	RunDueScheduledTransferTx(ctx context.Context, arg RunDueScheduledTransferTxParams) (ScheduledTransferRun, error)
+	FailScheduledTransferTx(ctx context.Context, arg FailScheduledTransferTxParams) (ScheduledTransferRun, error)
+	RevokeTokenTx(ctx context.Context, arg RevokeTokenTxParams) error
+	EnrollTotpTx(ctx context.Context, arg EnrollTotpTxParams) (UserTotp, error)
+	DisableTotpTx(ctx context.Context, username string) error
+	ResetPasswordTx(ctx context.Context, arg ResetPasswordTxParams) (User, error)
+	CreateUserTx(ctx context.Context, arg CreateUserTxParams) (User, error)
+	UpdateUserTx(ctx context.Context, arg UpdateUserTxParams) (UpdateUserTxResult, error)
+	VerifyEmailTx(ctx context.Context, hashedToken string) (User, error)
+	ExportUserTx(ctx context.Context, username string) (UserExport, error)
+	DeleteUserTx(ctx context.Context, username string) (User, error)
+}
+
+// SQLStore provides all functions to execute SQL queries and transactions.
+type SQLStore struct {
 	*Queries
 	db *sql.DB
 }
 
 type TransferTxParams struct {
-	FromAccountID int64 `json:"from_account_id"`
-	ToAccountID   int64 `json:"to_account_id"`
-	Amount        int64 `json:"amount"`
+	FromAccountID int64  `json:"from_account_id"`
+	ToAccountID   int64  `json:"to_account_id"`
+	Amount        int64  `json:"amount"`
+	Currency      string `json:"currency"`
+	// ToCurrency, ToAmount, ExchangeRate and Spread describe what is credited
+	// by a cross-currency transfer. They are left empty when both accounts
+	// hold Currency, in which case Amount is credited as is.
+	ToCurrency   string `json:"to_currency"`
+	ToAmount     int64  `json:"to_amount"`
+	ExchangeRate string `json:"exchange_rate"`
+	Spread       string `json:"spread"`
+	// AllowFrozenRecipient lets money be credited to a frozen account.
+	// Debits from frozen accounts are always refused.
+	AllowFrozenRecipient bool `json:"allow_frozen_recipient"`
+	// Idempotency, when set, records the key and the result of the transfer
+	// in the same transaction, so a retried request can be answered with
+	// the original result instead of moving money twice.
+	Idempotency *IdempotencyParams `json:"-"`
+}
+
+type ReverseTransferTxParams struct {
+	TransferID int64 `json:"transfer_id"`
+	// Amount is what to refund to the original sender, in the currency of
+	// their account. Zero refunds all that is left.
+	Amount               int64 `json:"amount"`
+	AllowFrozenRecipient bool  `json:"allow_frozen_recipient"`
+	// Idempotency works as it does for TransferTx.
+	Idempotency *IdempotencyParams `json:"-"`
+}
+
+type CreatePendingTransferTxParams struct {
+	FromAccountID int64     `json:"from_account_id"`
+	ToAccountID   int64     `json:"to_account_id"`
+	Amount        int64     `json:"amount"`
+	Currency      string    `json:"currency"`
+	ExpiresAt     time.Time `json:"expires_at"`
+	// AllowFrozenRecipient lets a hold be placed for a frozen recipient; it
+	// should match what the capture will allow.
+	AllowFrozenRecipient bool `json:"allow_frozen_recipient"`
+}
+
+type PendingTransferTxResult struct {
+	PendingTransfer PendingTransfer `json:"pending_transfer"`
+	FromAccount     Account         `json:"from_account"`
+}
+
+type CapturePendingTransferTxParams struct {
+	ID int64 `json:"id"`
+	// Amount is what to move, at most what was held. Zero captures it all.
+	// The rest of the hold is released either way.
+	Amount               int64 `json:"amount"`
+	AllowFrozenRecipient bool  `json:"allow_frozen_recipient"`
+}
+
+type CapturePendingTransferTxResult struct {
+	PendingTransfer PendingTransfer  `json:"pending_transfer"`
+	Transfer        TransferTxResult `json:"transfer"`
+}
+
+type IdempotencyParams struct {
+	Username    string    `json:"username"`
+	Key         string    `json:"key"`
+	RequestHash string    `json:"request_hash"`
+	ExpiresAt   time.Time `json:"expires_at"`
 }
 
 type TransferTxResult struct {
@@ -27,28 +210,151 @@ type TransferTxResult struct {
 	ToEntry     Entry    `json:"to_entry"`
 }
 
-func NewStore(db *sql.DB) *Store {
-	return &Store{
+type RevokeTokenTxParams struct {
+	TokenID   uuid.UUID     `json:"token_id"`
+	Username  string        `json:"username"`
+	ExpiresAt time.Time     `json:"expires_at"`
+	SessionID uuid.NullUUID `json:"session_id"`
+}
+
+type EnrollTotpTxParams struct {
+	Username            string   `json:"username"`
+	EncryptedSecret     []byte   `json:"encrypted_secret"`
+	HashedRecoveryCodes []string `json:"hashed_recovery_codes"`
+}
+
+type ResetPasswordTxParams struct {
+	HashedToken    string `json:"hashed_token"`
+	HashedPassword string `json:"hashed_password"`
+}
+
+type ChangeAccountStatusTxParams struct {
+	AccountID int64  `json:"account_id"`
+	Status    string `json:"status"`
+	Reason    string `json:"reason"`
+	ChangedBy string `json:"changed_by"`
+}
+
+type CreateUserTxParams struct {
+	CreateUserParams
+	HashedVerifyToken    string    `json:"hashed_verify_token"`
+	VerifyTokenExpiresAt time.Time `json:"verify_token_expires_at"`
+}
+
+type UpdateUserTxParams struct {
+	UpdateUserParams
+	HashedVerifyToken    string    `json:"hashed_verify_token"`
+	VerifyTokenExpiresAt time.Time `json:"verify_token_expires_at"`
+}
+
+type UpdateUserTxResult struct {
+	User User `json:"user"`
+	// EmailChanged reports whether the update gave the user a new email
+	// address, for which the verification token was issued.
+	EmailChanged bool `json:"email_changed"`
+}
+
+type RunDueScheduledTransferTxParams struct {
+	Now time.Time `json:"now"`
+	// RetryDelay is how long to wait before retrying a run that failed for
+	// lack of funds or for an unexpected error.
+	RetryDelay time.Duration `json:"retry_delay"`
+	// MaxFailures is how many consecutive runs may fail for lack of funds or
+	// for an unexpected error before the scheduled transfer is paused. Any
+	// other refusal pauses it straight away.
+	MaxFailures          int32 `json:"max_failures"`
+	AllowFrozenRecipient bool  `json:"allow_frozen_recipient"`
+}
+
+type FailScheduledTransferTxParams struct {
+	RunDueScheduledTransferTxParams
+	// Scheduled is the transfer as the failed run locked it.
+	Scheduled ScheduledTransfer `json:"scheduled"`
+	Err       error             `json:"-"`
+}
+
+// ScheduledTransferError is returned by RunDueScheduledTransferTx when a due
+// transfer could not be run for a reason other than a refusal. Nothing of
+// the run was committed; FailScheduledTransferTx records the failure.
+type ScheduledTransferError struct {
+	Scheduled ScheduledTransfer
+	Err       error
+}
+
+func (e *ScheduledTransferError) Error() string {
+	return fmt.Sprintf("scheduled transfer %d: %v", e.Scheduled.ID, e.Err)
+}
+
+func (e *ScheduledTransferError) Unwrap() error {
+	return e.Err
+}
+
+type UserExport struct {
+	User      User       `json:"user"`
+	Accounts  []Account  `json:"accounts"`
+	Entries   []Entry    `json:"entries"`
+	Transfers []Transfer `json:"transfers"`
+}
+
+func NewStore(db *sql.DB) Store {
+	return &SQLStore{
 		Queries: New(db),
 		db:      db,
 	}
 }
 
-func (s *Store) DeleteAccountTx(ctx context.Context, accountId int64) error {
+// ChangeAccountStatusTx freezes or unfreezes an account and records who did
+// it and why.
+func (s *SQLStore) ChangeAccountStatusTx(ctx context.Context, arg ChangeAccountStatusTxParams) (Account, error) {
+	var result Account
 	err := s.execTrx(ctx, func(q *Queries) error {
 		var err error
 
-		err = q.DeleteEntry(ctx, accountId)
+		account, err := q.GetAccountForUpdate(ctx, arg.AccountID)
 		if err != nil {
 			return err
 		}
 
-		err = q.DeleteTransfer(ctx, accountId)
+		if statusChanges[account.Status] != arg.Status {
+			return ErrInvalidStatusChange
+		}
+
+		result, err = changeAccountStatus(ctx, q, account, arg.Status, arg.Reason, arg.ChangedBy)
+		if err != nil {
+			return err
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+// CloseAccountTx closes an empty, active account. The account and its ledger
+// rows are kept so the history of both parties stays intact.
+func (s *SQLStore) CloseAccountTx(ctx context.Context, accountId int64) (Account, error) {
+	var result Account
+	err := s.execTrx(ctx, func(q *Queries) error {
+		var err error
+
+		account, err := q.GetAccountForUpdate(ctx, accountId)
 		if err != nil {
 			return err
 		}
 
-		err = q.DeleteAccount(ctx, accountId)
+		if account.Status != AccountStatusActive {
+			return ErrAccountNotActive
+		}
+
+		if account.Balance != 0 {
+			return ErrAccountHasBalance
+		}
+
+		result, err = changeAccountStatus(ctx, q, account, AccountStatusClosed, "closed by owner", account.Owner)
 		if err != nil {
 			return err
 		}
@@ -57,52 +363,256 @@ func (s *Store) DeleteAccountTx(ctx context.Context, accountId int64) error {
 	})
 
 	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+// TransferTx moves money between two accounts. It returns sql.ErrNoRows if
+// either account does not exist, and ErrAccountFrozen, ErrAccountNotActive,
+// ErrCurrencyMismatch or ErrInsufficientFunds if the transfer is not allowed,
+// and ErrIdempotencyKeyInUse if its idempotency key was recorded meanwhile.
+func (s *SQLStore) TransferTx(ctx context.Context, arg TransferTxParams) (TransferTxResult, error) {
+	var result TransferTxResult
+	err := s.execTrx(ctx, func(q *Queries) error {
+		var err error
+		result, err = transferMoney(ctx, q, arg, sql.NullInt64{})
 		return err
+	})
+
+	if err != nil {
+		return result, err
 	}
 
-	return nil
+	return result, nil
 }
 
-func (s *Store) TransferTx(ctx context.Context, arg TransferTxParams) (TransferTxResult, error) {
+// ReverseTransferTx refunds a transfer, in whole or in part, with a transfer
+// the other way round that is linked to it. The amount is given in the
+// currency of the original source account and the refunds of a transfer can
+// add up to its amount at most; a cross-currency transfer is reversed at the
+// rate it went through, without a spread. Besides the errors of TransferTx,
+// it returns ErrTransferIsReversal, ErrTransferReversed,
+// ErrReversalExceedsTransfer or ErrReversalTooSmall if the reversal is not
+// allowed.
+func (s *SQLStore) ReverseTransferTx(ctx context.Context, arg ReverseTransferTxParams) (TransferTxResult, error) {
 	var result TransferTxResult
 	err := s.execTrx(ctx, func(q *Queries) error {
 		var err error
 
-		result.Transfer, err = q.CreateTransfer(ctx, CreateTransferParams{
+		// Locking the original serializes its reversals, so they cannot
+		// add up to more than it.
+		original, err := q.GetTransferForUpdate(ctx, arg.TransferID)
+		if err != nil {
+			return err
+		}
+
+		if original.ReversalOf.Valid {
+			return ErrTransferIsReversal
+		}
+
+		reversalOf := sql.NullInt64{Int64: original.ID, Valid: true}
+
+		reversed, err := q.GetTransferReversalTotals(ctx, reversalOf)
+		if err != nil {
+			return err
+		}
+
+		// Reversals debit the original recipient and credit the original
+		// sender, so what has been refunded so far is their to_amount.
+		left := original.Amount - reversed.ToAmount
+		if left <= 0 {
+			return ErrTransferReversed
+		}
+
+		amount := arg.Amount
+		if amount == 0 {
+			amount = left
+		}
+		if amount > left {
+			return ErrReversalExceedsTransfer
+		}
+
+		// The last refund takes back whatever is left, so rounding partial
+		// refunds down never leaves part of the transfer stranded.
+		debit := original.ToAmount - reversed.Amount
+		if amount < left {
+			debit = scaleAmount(amount, original.ToAmount, original.Amount)
+		}
+		if debit <= 0 {
+			return ErrReversalTooSmall
+		}
+
+		fromAcc, err := q.GetAccount(ctx, original.ToAccountID)
+		if err != nil {
+			return err
+		}
+
+		toAcc, err := q.GetAccount(ctx, original.FromAccountID)
+		if err != nil {
+			return err
+		}
+
+		transfer := TransferTxParams{
+			FromAccountID:        original.ToAccountID,
+			ToAccountID:          original.FromAccountID,
+			Amount:               debit,
+			Currency:             fromAcc.Currency,
+			AllowFrozenRecipient: arg.AllowFrozenRecipient,
+			Idempotency:          arg.Idempotency,
+		}
+
+		if fromAcc.Currency != toAcc.Currency {
+			transfer.ToCurrency = toAcc.Currency
+			transfer.ToAmount = amount
+			transfer.Spread = "0"
+			transfer.ExchangeRate, err = reversalRate(original)
+			if err != nil {
+				return err
+			}
+		}
+
+		result, err = transferMoney(ctx, q, transfer, reversalOf)
+		return err
+	})
+
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+// CreatePendingTransferTx authorizes a transfer without moving money: the
+// amount is held on the source account, out of its available balance, until
+// the transfer is captured or voided, or the hold expires. Holds are only
+// placed within a currency. It returns the same errors as TransferTx.
+func (s *SQLStore) CreatePendingTransferTx(ctx context.Context, arg CreatePendingTransferTxParams) (PendingTransferTxResult, error) {
+	var result PendingTransferTxResult
+	err := s.execTrx(ctx, func(q *Queries) error {
+		var err error
+
+		fromAcc, err := q.GetAccountForUpdate(ctx, arg.FromAccountID)
+		if err != nil {
+			return err
+		}
+
+		toAcc, err := q.GetAccount(ctx, arg.ToAccountID)
+		if err != nil {
+			return err
+		}
+
+		if err = checkTransferStatus(fromAcc, false); err != nil {
+			return err
+		}
+
+		if err = checkTransferStatus(toAcc, arg.AllowFrozenRecipient); err != nil {
+			return err
+		}
+
+		if fromAcc.Currency != arg.Currency || toAcc.Currency != arg.Currency {
+			return ErrCurrencyMismatch
+		}
+
+		if fromAcc.AvailableBalance < arg.Amount {
+			return ErrInsufficientFunds
+		}
+
+		result.FromAccount, err = q.AddAccountHold(ctx, AddAccountHoldParams{
+			ID:     arg.FromAccountID,
+			Amount: arg.Amount,
+		})
+		if err != nil {
+			return err
+		}
+
+		result.PendingTransfer, err = q.CreatePendingTransfer(ctx, CreatePendingTransferParams{
 			FromAccountID: arg.FromAccountID,
 			ToAccountID:   arg.ToAccountID,
 			Amount:        arg.Amount,
+			Currency:      arg.Currency,
+			ExpiresAt:     arg.ExpiresAt,
 		})
 		if err != nil {
 			return err
 		}
 
-		result.FromEntry, err = q.CreateEntry(ctx, CreateEntryParams{
-			AccountID: arg.FromAccountID,
-			Amount:    -arg.Amount,
-		})
+		return nil
+	})
+
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+// CapturePendingTransferTx moves all or part of the money held by a pending
+// transfer and releases the hold. It returns ErrTransferNotPending,
+// ErrHoldExpired or ErrCaptureExceedsHold if the capture is not allowed,
+// and otherwise the same errors as TransferTx; the hold is kept when the
+// transfer itself is refused.
+func (s *SQLStore) CapturePendingTransferTx(ctx context.Context, arg CapturePendingTransferTxParams) (CapturePendingTransferTxResult, error) {
+	var result CapturePendingTransferTxResult
+	err := s.execTrx(ctx, func(q *Queries) error {
+		var err error
+
+		pending, err := q.GetPendingTransferForUpdate(ctx, arg.ID)
+		if err != nil {
+			return err
+		}
+
+		if pending.Status != PendingTransferStatusPending {
+			return ErrTransferNotPending
+		}
+
+		if !pending.ExpiresAt.After(time.Now()) {
+			return ErrHoldExpired
+		}
+
+		amount := arg.Amount
+		if amount == 0 {
+			amount = pending.Amount
+		}
+		if amount > pending.Amount {
+			return ErrCaptureExceedsHold
+		}
+
+		// Lock the accounts in the same order the transfer will, then release
+		// the hold so the money it kept becomes available to the transfer.
+		_, err = lockAccounts(ctx, q, pending.FromAccountID, pending.ToAccountID)
 		if err != nil {
 			return err
 		}
 
-		result.ToEntry, err = q.CreateEntry(ctx, CreateEntryParams{
-			AccountID: arg.ToAccountID,
-			Amount:    arg.Amount,
+		_, err = q.AddAccountHold(ctx, AddAccountHoldParams{
+			ID:     pending.FromAccountID,
+			Amount: -pending.Amount,
 		})
 		if err != nil {
 			return err
 		}
 
-		if arg.FromAccountID < arg.ToAccountID {
-			result.FromAccount, result.ToAccount, err = addMoney(ctx, q, arg.FromAccountID, arg.ToAccountID, -arg.Amount, arg.Amount)
-			if err != nil {
-				return err
-			}
-		} else {
-			result.ToAccount, result.FromAccount, err = addMoney(ctx, q, arg.ToAccountID, arg.FromAccountID, arg.Amount, -arg.Amount)
-			if err != nil {
-				return err
-			}
+		result.Transfer, err = transferMoney(ctx, q, TransferTxParams{
+			FromAccountID:        pending.FromAccountID,
+			ToAccountID:          pending.ToAccountID,
+			Amount:               amount,
+			Currency:             pending.Currency,
+			AllowFrozenRecipient: arg.AllowFrozenRecipient,
+		}, sql.NullInt64{})
+		if err != nil {
+			return err
+		}
+
+		result.PendingTransfer, err = q.UpdatePendingTransfer(ctx, UpdatePendingTransferParams{
+			ID:             pending.ID,
+			CapturedAmount: amount,
+			TransferID:     sql.NullInt64{Int64: result.Transfer.Transfer.ID, Valid: true},
+			Status:         PendingTransferStatusCaptured,
+		})
+		if err != nil {
+			return err
 		}
 
 		return nil
@@ -115,27 +625,906 @@ func (s *Store) TransferTx(ctx context.Context, arg TransferTxParams) (TransferT
 	return result, nil
 }
 
-func addMoney(ctx context.Context, q *Queries, fromAccId, toAccId, fromAmount, toAmount int64) (fromAcc, toAcc Account, err error) {
-	fromAcc, err = q.AddAccountBalance(ctx, AddAccountBalanceParams{
-		ID:     fromAccId,
-		Amount: fromAmount,
+// VoidPendingTransferTx cancels a pending transfer and releases its hold.
+// It returns ErrTransferNotPending if it was already captured, voided or
+// expired.
+func (s *SQLStore) VoidPendingTransferTx(ctx context.Context, id int64) (PendingTransferTxResult, error) {
+	var result PendingTransferTxResult
+	err := s.execTrx(ctx, func(q *Queries) error {
+		var err error
+
+		pending, err := q.GetPendingTransferForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		if pending.Status != PendingTransferStatusPending {
+			return ErrTransferNotPending
+		}
+
+		result, err = releaseHold(ctx, q, pending, PendingTransferStatusVoided)
+		return err
 	})
+
 	if err != nil {
-		return
+		return result, err
 	}
 
-	toAcc, err = q.AddAccountBalance(ctx, AddAccountBalanceParams{
-		ID:     toAccId,
-		Amount: toAmount,
+	return result, nil
+}
+
+// ExpirePendingTransferTx releases the hold of one pending transfer that
+// expired by now, skipping any that another transaction has locked. It
+// returns sql.ErrNoRows when there is none left.
+func (s *SQLStore) ExpirePendingTransferTx(ctx context.Context, now time.Time) (PendingTransferTxResult, error) {
+	var result PendingTransferTxResult
+	err := s.execTrx(ctx, func(q *Queries) error {
+		var err error
+
+		pending, err := q.GetExpiredPendingTransferForUpdate(ctx, now)
+		if err != nil {
+			return err
+		}
+
+		result, err = releaseHold(ctx, q, pending, PendingTransferStatusExpired)
+		return err
 	})
+
 	if err != nil {
-		return
+		return result, err
 	}
-	return
+
+	return result, nil
+}
+
+// ChangeScheduledTransferStatusTx pauses, resumes or cancels a scheduled
+// transfer. Resuming clears its failures, and a due occurrence runs on the
+// next tick of the scheduler. It returns ErrInvalidStatusChange if the
+// transfer cannot move to status.
+func (s *SQLStore) ChangeScheduledTransferStatusTx(ctx context.Context, id int64, status string) (ScheduledTransfer, error) {
+	var result ScheduledTransfer
+	err := s.execTrx(ctx, func(q *Queries) error {
+		var err error
+
+		scheduled, err := q.GetScheduledTransferForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		if !containsString(scheduledTransferStatusChanges[scheduled.Status], status) {
+			return ErrInvalidStatusChange
+		}
+
+		result, err = q.UpdateScheduledTransfer(ctx, UpdateScheduledTransferParams{
+			ID:           scheduled.ID,
+			NextRunAt:    scheduled.NextRunAt,
+			Status:       status,
+			FailureCount: 0,
+		})
+		if err != nil {
+			return err
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+// RunDueScheduledTransferTx locks the scheduled transfer that has been due
+// the longest, skipping any another worker holds, runs it in the same
+// transaction and records the outcome, so that the money moves if and only
+// if the run is recorded. Recurring transfers then move to their next
+// occurrence after arg.Now; missed occurrences are skipped rather than run
+// back to back. It returns sql.ErrNoRows when nothing is due, and a
+// *ScheduledTransferError if the transfer failed other than by a refusal.
+func (s *SQLStore) RunDueScheduledTransferTx(ctx context.Context, arg RunDueScheduledTransferTxParams) (ScheduledTransferRun, error) {
+	var result ScheduledTransferRun
+	err := s.execTrx(ctx, func(q *Queries) error {
+		var err error
+
+		scheduled, err := q.GetDueScheduledTransferForUpdate(ctx, arg.Now)
+		if err != nil {
+			return err
+		}
+
+		// Refusals are decided before anything is written, so the failed
+		// run can still be recorded in this transaction.
+		transfer, runErr := transferMoney(ctx, q, TransferTxParams{
+			FromAccountID:        scheduled.FromAccountID,
+			ToAccountID:          scheduled.ToAccountID,
+			Amount:               scheduled.Amount,
+			Currency:             scheduled.Currency,
+			AllowFrozenRecipient: arg.AllowFrozenRecipient,
+		}, sql.NullInt64{})
+		if runErr != nil && !isTransferRefusal(runErr) {
+			return &ScheduledTransferError{Scheduled: scheduled, Err: runErr}
+		}
+
+		result, err = recordScheduledRun(ctx, q, scheduled, transfer.Transfer.ID, runErr, arg)
+		return err
+	})
+
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+// FailScheduledTransferTx records a run that failed with a
+// *ScheduledTransferError, so that the transfer is retried later or paused
+// instead of staying due ahead of every other. It returns sql.ErrNoRows if
+// the transfer has changed since the failed run locked it, e.g. because
+// another worker has run it meanwhile.
+func (s *SQLStore) FailScheduledTransferTx(ctx context.Context, arg FailScheduledTransferTxParams) (ScheduledTransferRun, error) {
+	var result ScheduledTransferRun
+	err := s.execTrx(ctx, func(q *Queries) error {
+		var err error
+
+		scheduled, err := q.GetScheduledTransferForUpdate(ctx, arg.Scheduled.ID)
+		if err != nil {
+			return err
+		}
+
+		if !scheduled.UpdatedAt.Equal(arg.Scheduled.UpdatedAt) {
+			return sql.ErrNoRows
+		}
+
+		result, err = recordScheduledRun(ctx, q, scheduled, 0, arg.Err, arg.RunDueScheduledTransferTxParams)
+		return err
+	})
+
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+// recordScheduledRun records the outcome of a run of a scheduled transfer
+// and moves it on. A run that failed for lack of funds or an unexpected
+// error is retried after arg.RetryDelay until arg.MaxFailures runs in a row
+// have failed; any other refusal pauses the transfer straight away.
+func recordScheduledRun(ctx context.Context, q *Queries, scheduled ScheduledTransfer, transferID int64, runErr error, arg RunDueScheduledTransferTxParams) (ScheduledTransferRun, error) {
+	run := CreateScheduledTransferRunParams{
+		ScheduledTransferID: scheduled.ID,
+		ScheduledFor:        scheduled.NextRunAt,
+		Status:              ScheduledTransferRunSucceeded,
+	}
+	update := UpdateScheduledTransferParams{
+		ID:           scheduled.ID,
+		NextRunAt:    scheduled.NextRunAt,
+		Status:       scheduled.Status,
+		FailureCount: 0,
+	}
+
+	retryable := runErr == ErrInsufficientFunds || !isTransferRefusal(runErr)
+
+	switch {
+	case runErr == nil:
+		run.TransferID = sql.NullInt64{Int64: transferID, Valid: true}
+		if scheduled.Recurrence == RecurrenceOnce {
+			update.Status = ScheduledTransferStatusCompleted
+		} else {
+			update.NextRunAt = nextScheduledRun(scheduled, arg.Now)
+		}
+	case retryable && scheduled.FailureCount+1 < arg.MaxFailures:
+		run.Status = ScheduledTransferRunFailed
+		run.Error = runErr.Error()
+		update.FailureCount = scheduled.FailureCount + 1
+		update.RetryAt = sql.NullTime{Time: arg.Now.Add(arg.RetryDelay), Valid: true}
+	default:
+		run.Status = ScheduledTransferRunFailed
+		run.Error = runErr.Error()
+		update.FailureCount = scheduled.FailureCount + 1
+		update.Status = ScheduledTransferStatusPaused
+	}
+
+	result, err := q.CreateScheduledTransferRun(ctx, run)
+	if err != nil {
+		return result, err
+	}
+
+	_, err = q.UpdateScheduledTransfer(ctx, update)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+// RevokeTokenTx adds a token to the revocation list, pruning revocations of
+// tokens that have already expired, and optionally blocks a session. The
+// session's refresh token, whose ID is the session ID, is revoked with it.
+func (s *SQLStore) RevokeTokenTx(ctx context.Context, arg RevokeTokenTxParams) error {
+	err := s.execTrx(ctx, func(q *Queries) error {
+		var err error
+
+		err = q.DeleteExpiredRevokedTokens(ctx)
+		if err != nil {
+			return err
+		}
+
+		// A token that is already revoked yields no row, which is fine:
+		// revoking is idempotent.
+		_, err = q.CreateRevokedToken(ctx, CreateRevokedTokenParams{
+			ID:        arg.TokenID,
+			Username:  arg.Username,
+			ExpiresAt: arg.ExpiresAt,
+		})
+		if err != nil && err != sql.ErrNoRows {
+			return err
+		}
+
+		if arg.SessionID.Valid {
+			session, err := q.BlockSession(ctx, arg.SessionID.UUID)
+			if err != nil {
+				return err
+			}
+
+			// Every access token of the session may log out with it.
+			_, err = q.CreateRevokedToken(ctx, CreateRevokedTokenParams{
+				ID:        session.ID,
+				Username:  session.Username,
+				ExpiresAt: session.ExpiresAt,
+			})
+			if err != nil && err != sql.ErrNoRows {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// EnrollTotpTx stores a new, not yet enabled, TOTP secret for a user and
+// replaces their recovery codes.
+func (s *SQLStore) EnrollTotpTx(ctx context.Context, arg EnrollTotpTxParams) (UserTotp, error) {
+	var result UserTotp
+	err := s.execTrx(ctx, func(q *Queries) error {
+		var err error
+
+		result, err = q.UpsertUserTotp(ctx, UpsertUserTotpParams{
+			Username:        arg.Username,
+			EncryptedSecret: arg.EncryptedSecret,
+		})
+		if err != nil {
+			return err
+		}
+
+		err = q.DeleteTotpRecoveryCodes(ctx, arg.Username)
+		if err != nil {
+			return err
+		}
+
+		for _, hashedCode := range arg.HashedRecoveryCodes {
+			_, err = q.CreateTotpRecoveryCode(ctx, CreateTotpRecoveryCodeParams{
+				Username:   arg.Username,
+				HashedCode: hashedCode,
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+func (s *SQLStore) DisableTotpTx(ctx context.Context, username string) error {
+	err := s.execTrx(ctx, func(q *Queries) error {
+		var err error
+
+		err = q.DeleteTotpRecoveryCodes(ctx, username)
+		if err != nil {
+			return err
+		}
+
+		err = q.DeleteUserTotp(ctx, username)
+		if err != nil {
+			return err
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ResetPasswordTx redeems a password reset token, sets the new password and
+// invalidates every other outstanding reset token of the user. It returns
+// sql.ErrNoRows if the token is unknown, used or expired.
+func (s *SQLStore) ResetPasswordTx(ctx context.Context, arg ResetPasswordTxParams) (User, error) {
+	var result User
+	err := s.execTrx(ctx, func(q *Queries) error {
+		var err error
+
+		resetToken, err := q.GetPasswordResetTokenForUpdate(ctx, arg.HashedToken)
+		if err != nil {
+			return err
+		}
+
+		result, err = q.UpdateUserPassword(ctx, UpdateUserPasswordParams{
+			Username:       resetToken.Username,
+			HashedPassword: arg.HashedPassword,
+		})
+		if err != nil {
+			return err
+		}
+
+		err = q.UsePasswordResetTokens(ctx, resetToken.Username)
+		if err != nil {
+			return err
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+// CreateUserTx creates a user together with the token that verifies their
+// email address. Sending the token is left to the caller, once the user has
+// been committed.
+func (s *SQLStore) CreateUserTx(ctx context.Context, arg CreateUserTxParams) (User, error) {
+	var result User
+	err := s.execTrx(ctx, func(q *Queries) error {
+		var err error
+
+		result, err = q.CreateUser(ctx, arg.CreateUserParams)
+		if err != nil {
+			return err
+		}
+
+		_, err = q.CreateVerifyEmail(ctx, CreateVerifyEmailParams{
+			Username:    result.Username,
+			Email:       result.Email,
+			HashedToken: arg.HashedVerifyToken,
+			ExpiresAt:   arg.VerifyTokenExpiresAt,
+		})
+		if err != nil {
+			return err
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+// UpdateUserTx applies a partial profile update. A changed email address must
+// be verified again, so a new verification token is issued for it. Sending the
+// token is left to the caller, once the update has been committed.
+func (s *SQLStore) UpdateUserTx(ctx context.Context, arg UpdateUserTxParams) (UpdateUserTxResult, error) {
+	var result UpdateUserTxResult
+	err := s.execTrx(ctx, func(q *Queries) error {
+		var err error
+
+		user, err := q.GetUser(ctx, arg.Username)
+		if err != nil {
+			return err
+		}
+
+		result.User, err = q.UpdateUser(ctx, arg.UpdateUserParams)
+		if err != nil {
+			return err
+		}
+
+		if result.User.Email == user.Email {
+			return nil
+		}
+
+		_, err = q.CreateVerifyEmail(ctx, CreateVerifyEmailParams{
+			Username:    result.User.Username,
+			Email:       result.User.Email,
+			HashedToken: arg.HashedVerifyToken,
+			ExpiresAt:   arg.VerifyTokenExpiresAt,
+		})
+		if err != nil {
+			return err
+		}
+
+		result.EmailChanged = true
+		return nil
+	})
+
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+// VerifyEmailTx redeems an email verification token and marks the address it
+// was sent to as verified. It returns sql.ErrNoRows if the token is unknown,
+// used or expired, or if the user has since changed their email.
+func (s *SQLStore) VerifyEmailTx(ctx context.Context, hashedToken string) (User, error) {
+	var result User
+	err := s.execTrx(ctx, func(q *Queries) error {
+		var err error
+
+		verifyEmail, err := q.GetVerifyEmailForUpdate(ctx, hashedToken)
+		if err != nil {
+			return err
+		}
+
+		result, err = q.VerifyUserEmail(ctx, VerifyUserEmailParams{
+			Username: verifyEmail.Username,
+			Email:    verifyEmail.Email,
+		})
+		if err != nil {
+			return err
+		}
+
+		err = q.UseVerifyEmail(ctx, verifyEmail.ID)
+		if err != nil {
+			return err
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+// ExportUserTx collects a user's profile together with every account, entry
+// and transfer they are a party to, read within a single repeatable-read
+// transaction so that balances, entries and transfers agree with each other.
+func (s *SQLStore) ExportUserTx(ctx context.Context, username string) (UserExport, error) {
+	var result UserExport
+	opts := &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true}
+	err := s.execTrxWithOptions(ctx, opts, func(q *Queries) error {
+		var err error
+
+		result.User, err = q.GetUser(ctx, username)
+		if err != nil {
+			return err
+		}
+
+		result.Accounts, err = q.ListAccountsByOwner(ctx, username)
+		if err != nil {
+			return err
+		}
+
+		result.Entries, err = q.ListEntriesByOwner(ctx, username)
+		if err != nil {
+			return err
+		}
+
+		result.Transfers, err = q.ListTransfersByOwner(ctx, username)
+		if err != nil {
+			return err
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+// DeleteUserTx closes a user's profile. Personal data is anonymized, every
+// credential is invalidated, scheduled transfers are cancelled and accounts
+// are closed, while accounts, entries and transfers are kept for audit. It
+// returns ErrAccountHasBalance if any account still holds funds and
+// sql.ErrNoRows if the user does not exist or was already deleted.
+func (s *SQLStore) DeleteUserTx(ctx context.Context, username string) (User, error) {
+	var result User
+	err := s.execTrx(ctx, func(q *Queries) error {
+		var err error
+
+		accounts, err := q.ListAccountsByOwnerForUpdate(ctx, username)
+		if err != nil {
+			return err
+		}
+
+		for _, account := range accounts {
+			if account.Status == AccountStatusFrozen {
+				return ErrAccountFrozen
+			}
+
+			if account.Balance != 0 {
+				return ErrAccountHasBalance
+			}
+		}
+
+		for _, account := range accounts {
+			if account.Status == AccountStatusClosed {
+				continue
+			}
+
+			_, err = changeAccountStatus(ctx, q, account, AccountStatusClosed, "owner deleted their profile", username)
+			if err != nil {
+				return err
+			}
+		}
+
+		result, err = q.AnonymizeUser(ctx, AnonymizeUserParams{
+			Username: username,
+			Email:    fmt.Sprintf("deleted+%s@invalid", username),
+		})
+		if err != nil {
+			return err
+		}
+
+		err = q.AnonymizeUserSessions(ctx, username)
+		if err != nil {
+			return err
+		}
+
+		err = q.RevokeUserApiKeys(ctx, username)
+		if err != nil {
+			return err
+		}
+
+		err = q.CancelUserScheduledTransfers(ctx, username)
+		if err != nil {
+			return err
+		}
+
+		err = q.UsePasswordResetTokens(ctx, username)
+		if err != nil {
+			return err
+		}
+
+		err = q.DeleteVerifyEmails(ctx, username)
+		if err != nil {
+			return err
+		}
+
+		err = q.DeleteTotpRecoveryCodes(ctx, username)
+		if err != nil {
+			return err
+		}
+
+		err = q.DeleteUserTotp(ctx, username)
+		if err != nil {
+			return err
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+// isTransferRefusal reports whether TransferTx turned a transfer down, as
+// opposed to failing to reach the database.
+func isTransferRefusal(err error) bool {
+	switch err {
+	case ErrInsufficientFunds, ErrCurrencyMismatch, ErrAccountNotActive, ErrAccountFrozen, sql.ErrNoRows:
+		return true
+	}
+	return false
+}
+
+// nextScheduledRun returns the first occurrence of a recurring transfer
+// after now. Monthly transfers run on their day of the month, or on the last
+// day of months too short to have it.
+func nextScheduledRun(scheduled ScheduledTransfer, now time.Time) time.Time {
+	next := scheduled.NextRunAt
+	for !next.After(now) {
+		switch scheduled.Recurrence {
+		case RecurrenceDaily:
+			next = next.AddDate(0, 0, 1)
+		case RecurrenceWeekly:
+			next = next.AddDate(0, 0, 7)
+		case RecurrenceMonthly:
+			year, month, _ := next.Date()
+			day := int(scheduled.DayOfMonth.Int32)
+			if last := daysIn(year, month+1, next.Location()); day > last {
+				day = last
+			}
+			next = time.Date(year, month+1, day, next.Hour(), next.Minute(), next.Second(), next.Nanosecond(), next.Location())
+		default:
+			return next
+		}
+	}
+	return next
+}
+
+// daysIn returns the number of days in a month; month may overflow into the
+// next year.
+func daysIn(year int, month time.Month, loc *time.Location) int {
+	return time.Date(year, month+1, 0, 0, 0, 0, 0, loc).Day()
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// checkTransferStatus reports whether money may move through an account.
+// Frozen accounts may only receive money, and only when allowFrozen is set.
+func checkTransferStatus(account Account, allowFrozen bool) error {
+	switch account.Status {
+	case AccountStatusActive:
+		return nil
+	case AccountStatusFrozen:
+		if allowFrozen {
+			return nil
+		}
+		return ErrAccountFrozen
+	default:
+		return ErrAccountNotActive
+	}
+}
+
+func changeAccountStatus(ctx context.Context, q *Queries, account Account, status, reason, changedBy string) (Account, error) {
+	_, err := q.CreateAccountStatusChange(ctx, CreateAccountStatusChangeParams{
+		AccountID:  account.ID,
+		FromStatus: account.Status,
+		ToStatus:   status,
+		Reason:     reason,
+		ChangedBy:  changedBy,
+	})
+	if err != nil {
+		return account, err
+	}
+
+	return q.UpdateAccountStatus(ctx, UpdateAccountStatusParams{
+		ID:     account.ID,
+		Status: status,
+	})
+}
+
+// recordIdempotencyKey stores the response of a request under its
+// idempotency key, pruning keys that have expired. A key that is still live
+// cannot be overwritten; ErrIdempotencyKeyInUse is returned instead.
+func recordIdempotencyKey(ctx context.Context, q *Queries, arg IdempotencyParams, response interface{}) error {
+	err := q.DeleteExpiredIdempotencyKeys(ctx)
+	if err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(response)
+	if err != nil {
+		return err
+	}
+
+	_, err = q.CreateIdempotencyKey(ctx, CreateIdempotencyKeyParams{
+		Username:    arg.Username,
+		Key:         arg.Key,
+		RequestHash: arg.RequestHash,
+		Response:    body,
+		ExpiresAt:   arg.ExpiresAt,
+	})
+	if err == sql.ErrNoRows {
+		return ErrIdempotencyKeyInUse
+	}
+
+	return err
+}
+
+// transferMoney runs a transfer inside a transaction, see TransferTx. A
+// valid reversalOf links it to the transfer it refunds.
+func transferMoney(ctx context.Context, q *Queries, arg TransferTxParams, reversalOf sql.NullInt64) (TransferTxResult, error) {
+	if arg.ToCurrency == "" {
+		arg.ToCurrency = arg.Currency
+		arg.ToAmount = arg.Amount
+		arg.ExchangeRate = "1"
+		arg.Spread = "0"
+	}
+
+	var result TransferTxResult
+
+	// No other transfer can change the balance or status of either account
+	// until this one commits.
+	accounts, err := lockAccounts(ctx, q, arg.FromAccountID, arg.ToAccountID)
+	if err != nil {
+		return result, err
+	}
+
+	fromAcc, toAcc := accounts[arg.FromAccountID], accounts[arg.ToAccountID]
+
+	if err = checkTransferStatus(fromAcc, false); err != nil {
+		return result, err
+	}
+
+	if err = checkTransferStatus(toAcc, arg.AllowFrozenRecipient); err != nil {
+		return result, err
+	}
+
+	if fromAcc.Currency != arg.Currency || toAcc.Currency != arg.ToCurrency {
+		return result, ErrCurrencyMismatch
+	}
+
+	// Money held for pending transfers cannot be spent.
+	if fromAcc.AvailableBalance < arg.Amount {
+		return result, ErrInsufficientFunds
+	}
+
+	result.Transfer, err = q.CreateTransfer(ctx, CreateTransferParams{
+		FromAccountID: arg.FromAccountID,
+		ToAccountID:   arg.ToAccountID,
+		Amount:        arg.Amount,
+		ToAmount:      arg.ToAmount,
+		ExchangeRate:  arg.ExchangeRate,
+		Spread:        arg.Spread,
+		ReversalOf:    reversalOf,
+	})
+	if err != nil {
+		return result, err
+	}
+
+	result.FromEntry, err = q.CreateEntry(ctx, CreateEntryParams{
+		AccountID: arg.FromAccountID,
+		Amount:    -arg.Amount,
+	})
+	if err != nil {
+		return result, err
+	}
+
+	result.ToEntry, err = q.CreateEntry(ctx, CreateEntryParams{
+		AccountID: arg.ToAccountID,
+		Amount:    arg.ToAmount,
+	})
+	if err != nil {
+		return result, err
+	}
+
+	if arg.FromAccountID < arg.ToAccountID {
+		result.FromAccount, result.ToAccount, err = addMoney(ctx, q, arg.FromAccountID, arg.ToAccountID, -arg.Amount, arg.ToAmount)
+		if err != nil {
+			return result, err
+		}
+	} else {
+		result.ToAccount, result.FromAccount, err = addMoney(ctx, q, arg.ToAccountID, arg.FromAccountID, arg.ToAmount, -arg.Amount)
+		if err != nil {
+			return result, err
+		}
+	}
+
+	if arg.Idempotency != nil {
+		err = recordIdempotencyKey(ctx, q, *arg.Idempotency, result)
+		if err != nil {
+			return result, err
+		}
+	}
+
+	return result, nil
+}
+
+// lockAccounts locks two accounts in id order, so that concurrent
+// transactions locking the same pair cannot deadlock, and returns them by id.
+func lockAccounts(ctx context.Context, q *Queries, firstId, secondId int64) (map[int64]Account, error) {
+	if firstId > secondId {
+		firstId, secondId = secondId, firstId
+	}
+
+	accounts := make(map[int64]Account, 2)
+	for _, id := range []int64{firstId, secondId} {
+		account, err := q.GetAccountForUpdate(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		accounts[id] = account
+	}
+
+	return accounts, nil
+}
+
+// releaseHold gives back what a pending transfer held on its source account
+// and closes it with status.
+func releaseHold(ctx context.Context, q *Queries, pending PendingTransfer, status string) (PendingTransferTxResult, error) {
+	var result PendingTransferTxResult
+	var err error
+
+	result.FromAccount, err = q.AddAccountHold(ctx, AddAccountHoldParams{
+		ID:     pending.FromAccountID,
+		Amount: -pending.Amount,
+	})
+	if err != nil {
+		return result, err
+	}
+
+	result.PendingTransfer, err = q.UpdatePendingTransfer(ctx, UpdatePendingTransferParams{
+		ID:     pending.ID,
+		Status: status,
+	})
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+// scaleAmount returns amount * num / den rounded down, without overflowing.
+func scaleAmount(amount, num, den int64) int64 {
+	scaled := new(big.Int).Mul(big.NewInt(amount), big.NewInt(num))
+	return scaled.Quo(scaled, big.NewInt(den)).Int64()
+}
+
+// reversalRate returns the rate that undoes a cross-currency transfer: the
+// inverse of the rate it went through once its spread was taken.
+func reversalRate(original Transfer) (string, error) {
+	rate, ok := new(big.Rat).SetString(original.ExchangeRate)
+	if !ok {
+		return "", fmt.Errorf("invalid exchange rate %q", original.ExchangeRate)
+	}
+
+	spread, ok := new(big.Rat).SetString(original.Spread)
+	if !ok {
+		return "", fmt.Errorf("invalid spread %q", original.Spread)
+	}
+
+	rate.Mul(rate, spread.Sub(big.NewRat(1, 1), spread))
+	if rate.Sign() <= 0 {
+		return "", fmt.Errorf("cannot reverse exchange rate %q", original.ExchangeRate)
+	}
+
+	return rate.Inv(rate).FloatString(10), nil
+}
+
+func addMoney(ctx context.Context, q *Queries, fromAccId, toAccId, fromAmount, toAmount int64) (fromAcc, toAcc Account, err error) {
+	fromAcc, err = q.AddAccountBalance(ctx, AddAccountBalanceParams{
+		ID:     fromAccId,
+		Amount: fromAmount,
+	})
+	if err != nil {
+		return
+	}
+
+	toAcc, err = q.AddAccountBalance(ctx, AddAccountBalanceParams{
+		ID:     toAccId,
+		Amount: toAmount,
+	})
+	if err != nil {
+		return
+	}
+	return
+}
+
+func (s *SQLStore) execTrx(ctx context.Context, fn func(*Queries) error) error {
+	return s.execTrxWithOptions(ctx, nil, fn)
 }
 
-func (s *Store) execTrx(ctx context.Context, fn func(*Queries) error) error {
-	tx, err := s.db.BeginTx(ctx, nil)
+func (s *SQLStore) execTrxWithOptions(ctx context.Context, opts *sql.TxOptions, fn func(*Queries) error) error {
+	tx, err := s.db.BeginTx(ctx, opts)
 	if err != nil {
 		return err
 	}
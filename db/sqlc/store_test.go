@@ -3,8 +3,12 @@ package db
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"testing"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/require"
 	"golang.org/x/crypto/bcrypt"
 )
@@ -27,6 +31,7 @@ func TestTransferTx(t *testing.T) {
 				FromAccountID: fromAcc.ID,
 				ToAccountID:   toAcc.ID,
 				Amount:        transferAmount,
+				Currency:      "CAD",
 			})
 
 			errors <- err
@@ -116,6 +121,7 @@ func TestTransferTxDeadlock(t *testing.T) {
 				FromAccountID: fromAccId,
 				ToAccountID:   toAccId,
 				Amount:        transferAmount,
+				Currency:      "CAD",
 			})
 
 			errors <- err
@@ -137,52 +143,1047 @@ func TestTransferTxDeadlock(t *testing.T) {
 	require.Equal(t, toAcc.Balance, updatedToAcc.Balance)
 }
 
-func TestDeleteAccountTx(t *testing.T) {
+func TestTransferTxChecks(t *testing.T) {
 	s := NewStore(testDB)
+	fromAcc := createRandomAccount(t)
+	toAcc := createRandomAccount(t)
+
+	usdAcc, err := testQueries.CreateAccount(context.Background(), CreateAccountParams{
+		Owner:    toAcc.Owner,
+		Balance:  0,
+		Currency: "USD",
+	})
+	require.NoError(t, err)
+
+	_, err = s.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: fromAcc.ID,
+		ToAccountID:   usdAcc.ID,
+		Amount:        1,
+		Currency:      "CAD",
+	})
+	require.ErrorIs(t, err, ErrCurrencyMismatch)
+
+	_, err = s.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: fromAcc.ID,
+		ToAccountID:   toAcc.ID,
+		Amount:        fromAcc.Balance + 1,
+		Currency:      "CAD",
+	})
+	require.ErrorIs(t, err, ErrInsufficientFunds)
+
+	_, err = s.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: fromAcc.ID,
+		ToAccountID:   fromAcc.ID + 1_000_000_000,
+		Amount:        1,
+		Currency:      "CAD",
+	})
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+
+	// Concurrent transfers cannot overdraw the account.
+	fromAcc, err = testQueries.UpdateAccount(context.Background(), UpdateAccountParams{
+		ID:      fromAcc.ID,
+		Balance: 100,
+	})
+	require.NoError(t, err)
+
+	n := 5
+	errs := make(chan error)
+	for i := 0; i < n; i++ {
+		go func() {
+			_, err := s.TransferTx(context.Background(), TransferTxParams{
+				FromAccountID: fromAcc.ID,
+				ToAccountID:   toAcc.ID,
+				Amount:        30,
+				Currency:      "CAD",
+			})
+			errs <- err
+		}()
+	}
+
+	var failed int
+	for i := 0; i < n; i++ {
+		err := <-errs
+		if err != nil {
+			require.ErrorIs(t, err, ErrInsufficientFunds)
+			failed++
+		}
+	}
+	require.Equal(t, 2, failed)
+
+	fromAcc, err = testQueries.GetAccount(context.Background(), fromAcc.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(10), fromAcc.Balance)
+}
+
+func TestTransferTxIdempotency(t *testing.T) {
+	s := NewStore(testDB)
+	fromAcc := createRandomAccount(t)
+	toAcc := createRandomAccount(t)
+
+	idempotency := &IdempotencyParams{
+		Username:    fromAcc.Owner,
+		Key:         randomString(16),
+		RequestHash: randomString(64),
+		ExpiresAt:   time.Now().Add(time.Hour),
+	}
+
+	arg := TransferTxParams{
+		FromAccountID: fromAcc.ID,
+		ToAccountID:   toAcc.ID,
+		Amount:        10,
+		Currency:      "CAD",
+		Idempotency:   idempotency,
+	}
+
+	result, err := s.TransferTx(context.Background(), arg)
+	require.NoError(t, err)
+
+	record, err := testQueries.GetIdempotencyKey(context.Background(), GetIdempotencyKeyParams{
+		Username: idempotency.Username,
+		Key:      idempotency.Key,
+	})
+	require.NoError(t, err)
+	require.Equal(t, idempotency.RequestHash, record.RequestHash)
+
+	var stored TransferTxResult
+	require.NoError(t, json.Unmarshal(record.Response, &stored))
+	require.Equal(t, result.Transfer.ID, stored.Transfer.ID)
+	require.Equal(t, result.FromAccount.Balance, stored.FromAccount.Balance)
+
+	// Reusing the key rolls the whole transfer back.
+	_, err = s.TransferTx(context.Background(), arg)
+	require.ErrorIs(t, err, ErrIdempotencyKeyInUse)
+
+	account, err := testQueries.GetAccount(context.Background(), fromAcc.ID)
+	require.NoError(t, err)
+	require.Equal(t, fromAcc.Balance-10, account.Balance)
+}
+
+func TestTransferTxCrossCurrency(t *testing.T) {
+	s := NewStore(testDB)
+	fromAcc := createRandomAccount(t)
+
+	toAcc, err := testQueries.CreateAccount(context.Background(), CreateAccountParams{
+		Owner:    createRandomUser(t).Username,
+		Balance:  0,
+		Currency: "USD",
+	})
+	require.NoError(t, err)
+
+	arg := TransferTxParams{
+		FromAccountID: fromAcc.ID,
+		ToAccountID:   toAcc.ID,
+		Amount:        100,
+		Currency:      "CAD",
+		ToCurrency:    "USD",
+		ToAmount:      74,
+		ExchangeRate:  "0.7500000000",
+		Spread:        "0.0100000000",
+	}
+
+	result, err := s.TransferTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.Amount, result.Transfer.Amount)
+	require.Equal(t, arg.ToAmount, result.Transfer.ToAmount)
+	require.Equal(t, arg.ExchangeRate, result.Transfer.ExchangeRate)
+	require.Equal(t, arg.Spread, result.Transfer.Spread)
+	require.Equal(t, -arg.Amount, result.FromEntry.Amount)
+	require.Equal(t, arg.ToAmount, result.ToEntry.Amount)
+	require.Equal(t, fromAcc.Balance-arg.Amount, result.FromAccount.Balance)
+	require.Equal(t, arg.ToAmount, result.ToAccount.Balance)
+
+	// The credit currency is checked against the locked account too.
+	arg.ToCurrency = "EUR"
+	_, err = s.TransferTx(context.Background(), arg)
+	require.ErrorIs(t, err, ErrCurrencyMismatch)
+
+	// Same-currency transfers record a neutral rate.
+	result, err = s.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: fromAcc.ID,
+		ToAccountID:   createRandomAccount(t).ID,
+		Amount:        10,
+		Currency:      "CAD",
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(10), result.Transfer.ToAmount)
+	require.Equal(t, "1", result.Transfer.ExchangeRate)
+	require.Equal(t, "0", result.Transfer.Spread)
+}
+
+func TestReverseTransferTx(t *testing.T) {
+	s := NewStore(testDB)
+	fromAcc := createRandomAccount(t)
+	toAcc := createRandomAccount(t)
+
+	original, err := s.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: fromAcc.ID,
+		ToAccountID:   toAcc.ID,
+		Amount:        100,
+		Currency:      fromAcc.Currency,
+	})
+	require.NoError(t, err)
+
+	// A partial refund first, then the rest of it.
+	result, err := s.ReverseTransferTx(context.Background(), ReverseTransferTxParams{TransferID: original.Transfer.ID, Amount: 30})
+	require.NoError(t, err)
+	require.Equal(t, toAcc.ID, result.Transfer.FromAccountID)
+	require.Equal(t, fromAcc.ID, result.Transfer.ToAccountID)
+	require.Equal(t, int64(30), result.Transfer.Amount)
+	require.Equal(t, int64(30), result.Transfer.ToAmount)
+	require.Equal(t, sql.NullInt64{Int64: original.Transfer.ID, Valid: true}, result.Transfer.ReversalOf)
+	require.Equal(t, int64(-30), result.FromEntry.Amount)
+	require.Equal(t, toAcc.ID, result.FromEntry.AccountID)
+	require.Equal(t, int64(30), result.ToEntry.Amount)
+	require.Equal(t, fromAcc.ID, result.ToEntry.AccountID)
+
+	_, err = s.ReverseTransferTx(context.Background(), ReverseTransferTxParams{TransferID: original.Transfer.ID, Amount: 71})
+	require.ErrorIs(t, err, ErrReversalExceedsTransfer)
+
+	_, err = s.ReverseTransferTx(context.Background(), ReverseTransferTxParams{TransferID: result.Transfer.ID})
+	require.ErrorIs(t, err, ErrTransferIsReversal)
 
+	result, err = s.ReverseTransferTx(context.Background(), ReverseTransferTxParams{TransferID: original.Transfer.ID})
+	require.NoError(t, err)
+	require.Equal(t, int64(70), result.Transfer.Amount)
+	require.Equal(t, fromAcc.Balance, result.ToAccount.Balance)
+	require.Equal(t, toAcc.Balance, result.FromAccount.Balance)
+
+	_, err = s.ReverseTransferTx(context.Background(), ReverseTransferTxParams{TransferID: original.Transfer.ID})
+	require.ErrorIs(t, err, ErrTransferReversed)
+
+	reversals, err := testQueries.ListTransferReversals(context.Background(), sql.NullInt64{Int64: original.Transfer.ID, Valid: true})
+	require.NoError(t, err)
+	require.Len(t, reversals, 2)
+
+	_, err = s.ReverseTransferTx(context.Background(), ReverseTransferTxParams{TransferID: original.Transfer.ID + 1_000_000_000})
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+}
+
+func TestReverseTransferTxCrossCurrency(t *testing.T) {
+	s := NewStore(testDB)
+	fromAcc := createRandomAccount(t)
+
+	toAcc, err := testQueries.CreateAccount(context.Background(), CreateAccountParams{
+		Owner:    createRandomUser(t).Username,
+		Balance:  0,
+		Currency: "USD",
+	})
+	require.NoError(t, err)
+
+	original, err := s.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: fromAcc.ID,
+		ToAccountID:   toAcc.ID,
+		Amount:        100,
+		Currency:      "CAD",
+		ToCurrency:    "USD",
+		ToAmount:      74,
+		ExchangeRate:  "0.7500000000",
+		Spread:        "0.0100000000",
+	})
+	require.NoError(t, err)
+
+	// Half of the CAD sent is 37 of the USD received; rates are undone
+	// net of the spread.
+	result, err := s.ReverseTransferTx(context.Background(), ReverseTransferTxParams{TransferID: original.Transfer.ID, Amount: 50})
+	require.NoError(t, err)
+	require.Equal(t, int64(37), result.Transfer.Amount)
+	require.Equal(t, int64(50), result.Transfer.ToAmount)
+	require.Equal(t, "1.3468013468", result.Transfer.ExchangeRate)
+	require.Equal(t, "0", result.Transfer.Spread)
+
+	result, err = s.ReverseTransferTx(context.Background(), ReverseTransferTxParams{TransferID: original.Transfer.ID})
+	require.NoError(t, err)
+	require.Equal(t, int64(37), result.Transfer.Amount)
+	require.Equal(t, int64(50), result.Transfer.ToAmount)
+	require.Zero(t, result.FromAccount.Balance)
+	require.Equal(t, fromAcc.Balance, result.ToAccount.Balance)
+}
+
+func TestReverseTransferTxConcurrent(t *testing.T) {
+	s := NewStore(testDB)
 	fromAcc := createRandomAccount(t)
 	toAcc := createRandomAccount(t)
 
+	original, err := s.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: fromAcc.ID,
+		ToAccountID:   toAcc.ID,
+		Amount:        100,
+		Currency:      fromAcc.Currency,
+	})
+	require.NoError(t, err)
+
 	n := 5
-	results := make([]TransferTxResult, 0, n)
+	errs := make(chan error)
+	for i := 0; i < n; i++ {
+		go func() {
+			_, err := s.ReverseTransferTx(context.Background(), ReverseTransferTxParams{TransferID: original.Transfer.ID})
+			errs <- err
+		}()
+	}
 
+	reversed := 0
 	for i := 0; i < n; i++ {
-		result, _ := s.TransferTx(context.Background(), TransferTxParams{
-			FromAccountID: fromAcc.ID,
-			ToAccountID:   toAcc.ID,
-			Amount:        10,
+		err := <-errs
+		if err == nil {
+			reversed++
+			continue
+		}
+		require.ErrorIs(t, err, ErrTransferReversed)
+	}
+	require.Equal(t, 1, reversed)
+
+	account, err := testQueries.GetAccount(context.Background(), fromAcc.ID)
+	require.NoError(t, err)
+	require.Equal(t, fromAcc.Balance, account.Balance)
+}
+
+func TestCreatePendingTransferTx(t *testing.T) {
+	s := NewStore(testDB)
+	fromAcc := createRandomAccount(t)
+	toAcc := createRandomAccount(t)
+
+	arg := CreatePendingTransferTxParams{
+		FromAccountID: fromAcc.ID,
+		ToAccountID:   toAcc.ID,
+		Amount:        fromAcc.Balance - 10,
+		Currency:      fromAcc.Currency,
+		ExpiresAt:     time.Now().Add(time.Hour),
+	}
+
+	result, err := s.CreatePendingTransferTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.Amount, result.PendingTransfer.Amount)
+	require.Equal(t, PendingTransferStatusPending, result.PendingTransfer.Status)
+	require.Equal(t, fromAcc.Balance, result.FromAccount.Balance)
+	require.Equal(t, arg.Amount, result.FromAccount.HeldAmount)
+	require.Equal(t, int64(10), result.FromAccount.AvailableBalance)
+
+	// Held money can be neither held again nor spent.
+	arg.Amount = 11
+	_, err = s.CreatePendingTransferTx(context.Background(), arg)
+	require.ErrorIs(t, err, ErrInsufficientFunds)
+
+	_, err = s.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: fromAcc.ID,
+		ToAccountID:   toAcc.ID,
+		Amount:        11,
+		Currency:      fromAcc.Currency,
+	})
+	require.ErrorIs(t, err, ErrInsufficientFunds)
+
+	_, err = s.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: fromAcc.ID,
+		ToAccountID:   toAcc.ID,
+		Amount:        10,
+		Currency:      fromAcc.Currency,
+	})
+	require.NoError(t, err)
+
+	// Holds are only placed within a currency.
+	usdAcc, err := testQueries.CreateAccount(context.Background(), CreateAccountParams{
+		Owner:    createRandomUser(t).Username,
+		Balance:  0,
+		Currency: "USD",
+	})
+	require.NoError(t, err)
+
+	_, err = s.CreatePendingTransferTx(context.Background(), CreatePendingTransferTxParams{
+		FromAccountID: toAcc.ID,
+		ToAccountID:   usdAcc.ID,
+		Amount:        10,
+		Currency:      toAcc.Currency,
+		ExpiresAt:     time.Now().Add(time.Hour),
+	})
+	require.ErrorIs(t, err, ErrCurrencyMismatch)
+}
+
+func TestCapturePendingTransferTx(t *testing.T) {
+	s := NewStore(testDB)
+	fromAcc := createRandomAccount(t)
+	toAcc := createRandomAccount(t)
+
+	created, err := s.CreatePendingTransferTx(context.Background(), CreatePendingTransferTxParams{
+		FromAccountID: fromAcc.ID,
+		ToAccountID:   toAcc.ID,
+		Amount:        100,
+		Currency:      fromAcc.Currency,
+		ExpiresAt:     time.Now().Add(time.Hour),
+	})
+	require.NoError(t, err)
+
+	_, err = s.CapturePendingTransferTx(context.Background(), CapturePendingTransferTxParams{ID: created.PendingTransfer.ID, Amount: 101})
+	require.ErrorIs(t, err, ErrCaptureExceedsHold)
+
+	// A partial capture moves part of the hold and releases the rest.
+	result, err := s.CapturePendingTransferTx(context.Background(), CapturePendingTransferTxParams{ID: created.PendingTransfer.ID, Amount: 60})
+	require.NoError(t, err)
+	require.Equal(t, PendingTransferStatusCaptured, result.PendingTransfer.Status)
+	require.Equal(t, int64(60), result.PendingTransfer.CapturedAmount)
+	require.Equal(t, sql.NullInt64{Int64: result.Transfer.Transfer.ID, Valid: true}, result.PendingTransfer.TransferID)
+	require.Equal(t, int64(60), result.Transfer.Transfer.Amount)
+	require.Equal(t, fromAcc.Balance-60, result.Transfer.FromAccount.Balance)
+	require.Zero(t, result.Transfer.FromAccount.HeldAmount)
+	require.Equal(t, fromAcc.Balance-60, result.Transfer.FromAccount.AvailableBalance)
+	require.Equal(t, toAcc.Balance+60, result.Transfer.ToAccount.Balance)
+
+	_, err = s.CapturePendingTransferTx(context.Background(), CapturePendingTransferTxParams{ID: created.PendingTransfer.ID})
+	require.ErrorIs(t, err, ErrTransferNotPending)
+
+	_, err = s.VoidPendingTransferTx(context.Background(), created.PendingTransfer.ID)
+	require.ErrorIs(t, err, ErrTransferNotPending)
+
+	// A refused transfer keeps the hold.
+	created, err = s.CreatePendingTransferTx(context.Background(), CreatePendingTransferTxParams{
+		FromAccountID: fromAcc.ID,
+		ToAccountID:   toAcc.ID,
+		Amount:        10,
+		Currency:      fromAcc.Currency,
+		ExpiresAt:     time.Now().Add(time.Hour),
+	})
+	require.NoError(t, err)
+
+	_, err = s.ChangeAccountStatusTx(context.Background(), ChangeAccountStatusTxParams{
+		AccountID: toAcc.ID,
+		Status:    AccountStatusFrozen,
+		Reason:    "under investigation",
+		ChangedBy: createRandomUser(t).Username,
+	})
+	require.NoError(t, err)
+
+	_, err = s.CapturePendingTransferTx(context.Background(), CapturePendingTransferTxParams{ID: created.PendingTransfer.ID})
+	require.ErrorIs(t, err, ErrAccountFrozen)
+
+	account, err := testQueries.GetAccount(context.Background(), fromAcc.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(10), account.HeldAmount)
+}
+
+func TestVoidPendingTransferTx(t *testing.T) {
+	s := NewStore(testDB)
+	fromAcc := createRandomAccount(t)
+
+	created, err := s.CreatePendingTransferTx(context.Background(), CreatePendingTransferTxParams{
+		FromAccountID: fromAcc.ID,
+		ToAccountID:   createRandomAccount(t).ID,
+		Amount:        100,
+		Currency:      fromAcc.Currency,
+		ExpiresAt:     time.Now().Add(time.Hour),
+	})
+	require.NoError(t, err)
+
+	result, err := s.VoidPendingTransferTx(context.Background(), created.PendingTransfer.ID)
+	require.NoError(t, err)
+	require.Equal(t, PendingTransferStatusVoided, result.PendingTransfer.Status)
+	require.Zero(t, result.PendingTransfer.CapturedAmount)
+	require.Equal(t, fromAcc.Balance, result.FromAccount.Balance)
+	require.Zero(t, result.FromAccount.HeldAmount)
+	require.Equal(t, fromAcc.Balance, result.FromAccount.AvailableBalance)
+
+	_, err = s.VoidPendingTransferTx(context.Background(), created.PendingTransfer.ID)
+	require.ErrorIs(t, err, ErrTransferNotPending)
+
+	_, err = s.VoidPendingTransferTx(context.Background(), created.PendingTransfer.ID+1_000_000_000)
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+}
+
+func TestExpirePendingTransferTx(t *testing.T) {
+	s := NewStore(testDB)
+	fromAcc := createRandomAccount(t)
+	now := time.Now()
+
+	created, err := s.CreatePendingTransferTx(context.Background(), CreatePendingTransferTxParams{
+		FromAccountID: fromAcc.ID,
+		ToAccountID:   createRandomAccount(t).ID,
+		Amount:        100,
+		Currency:      fromAcc.Currency,
+		ExpiresAt:     now.Add(-time.Minute),
+	})
+	require.NoError(t, err)
+
+	_, err = s.CapturePendingTransferTx(context.Background(), CapturePendingTransferTxParams{ID: created.PendingTransfer.ID})
+	require.ErrorIs(t, err, ErrHoldExpired)
+
+	// Other tests may have left expired holds too.
+	for i := 0; i < 1_000; i++ {
+		result, err := s.ExpirePendingTransferTx(context.Background(), now)
+		require.NoError(t, err)
+
+		if result.PendingTransfer.ID == created.PendingTransfer.ID {
+			require.Equal(t, PendingTransferStatusExpired, result.PendingTransfer.Status)
+			require.Zero(t, result.FromAccount.HeldAmount)
+			require.Equal(t, fromAcc.Balance, result.FromAccount.AvailableBalance)
+			return
+		}
+	}
+
+	t.Fatalf("pending transfer %d did not expire", created.PendingTransfer.ID)
+}
+
+func TestChangeAccountStatusTx(t *testing.T) {
+	s := NewStore(testDB)
+	frozenAcc := createRandomAccount(t)
+	otherAcc := createRandomAccount(t)
+	admin := createRandomUser(t)
+
+	arg := ChangeAccountStatusTxParams{
+		AccountID: frozenAcc.ID,
+		Status:    AccountStatusFrozen,
+		Reason:    "under investigation",
+		ChangedBy: admin.Username,
+	}
+
+	account, err := s.ChangeAccountStatusTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, AccountStatusFrozen, account.Status)
+
+	_, err = s.ChangeAccountStatusTx(context.Background(), arg)
+	require.ErrorIs(t, err, ErrInvalidStatusChange)
+
+	// Debits are always refused, credits only when allowed.
+	_, err = s.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID:        frozenAcc.ID,
+		ToAccountID:          otherAcc.ID,
+		Amount:               1,
+		Currency:             "CAD",
+		AllowFrozenRecipient: true,
+	})
+	require.ErrorIs(t, err, ErrAccountFrozen)
+
+	_, err = s.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: otherAcc.ID,
+		ToAccountID:   frozenAcc.ID,
+		Amount:        1,
+		Currency:      "CAD",
+	})
+	require.ErrorIs(t, err, ErrAccountFrozen)
+
+	_, err = s.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID:        otherAcc.ID,
+		ToAccountID:          frozenAcc.ID,
+		Amount:               1,
+		Currency:             "CAD",
+		AllowFrozenRecipient: true,
+	})
+	require.NoError(t, err)
+
+	arg.Status = AccountStatusActive
+	arg.Reason = "investigation closed"
+	account, err = s.ChangeAccountStatusTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, AccountStatusActive, account.Status)
+
+	changes, err := testQueries.ListAccountStatusChanges(context.Background(), frozenAcc.ID)
+	require.NoError(t, err)
+	require.Len(t, changes, 2)
+	require.Equal(t, AccountStatusFrozen, changes[0].ToStatus)
+	require.Equal(t, AccountStatusActive, changes[1].ToStatus)
+	require.Equal(t, admin.Username, changes[1].ChangedBy)
+
+	// Closing is not a status change an admin can make here.
+	arg.Status = AccountStatusClosed
+	_, err = s.ChangeAccountStatusTx(context.Background(), arg)
+	require.ErrorIs(t, err, ErrInvalidStatusChange)
+}
+
+func TestCloseAccountTx(t *testing.T) {
+	s := NewStore(testDB)
+
+	fromAcc := createRandomAccount(t)
+	toAcc := createRandomAccount(t)
+
+	fromAcc, err := testQueries.UpdateAccount(context.Background(), UpdateAccountParams{
+		ID:      fromAcc.ID,
+		Balance: 100,
+	})
+	require.NoError(t, err)
+
+	result, err := s.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: fromAcc.ID,
+		ToAccountID:   toAcc.ID,
+		Amount:        fromAcc.Balance,
+		Currency:      "CAD",
+	})
+	require.NoError(t, err)
+
+	_, err = s.CloseAccountTx(context.Background(), toAcc.ID)
+	require.ErrorIs(t, err, ErrAccountHasBalance)
+
+	closedAcc, err := s.CloseAccountTx(context.Background(), fromAcc.ID)
+	require.NoError(t, err)
+	require.Equal(t, AccountStatusClosed, closedAcc.Status)
+
+	_, err = s.CloseAccountTx(context.Background(), fromAcc.ID)
+	require.ErrorIs(t, err, ErrAccountNotActive)
+
+	// The ledger of both parties is kept.
+	_, err = testQueries.GetTransfer(context.Background(), result.Transfer.ID)
+	require.NoError(t, err)
+	_, err = testQueries.GetEntry(context.Background(), result.FromEntry.ID)
+	require.NoError(t, err)
+	_, err = testQueries.GetEntry(context.Background(), result.ToEntry.ID)
+	require.NoError(t, err)
+
+	// Money can no longer move in or out.
+	_, err = s.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: toAcc.ID,
+		ToAccountID:   fromAcc.ID,
+		Amount:        1,
+		Currency:      "CAD",
+	})
+	require.ErrorIs(t, err, ErrAccountNotActive)
+}
+
+func TestCloseAccountTxReopen(t *testing.T) {
+	s := NewStore(testDB)
+	account := createRandomAccount(t)
+
+	account, err := testQueries.UpdateAccount(context.Background(), UpdateAccountParams{ID: account.ID, Balance: 0})
+	require.NoError(t, err)
+
+	// An open account blocks a second one in the same currency.
+	arg := CreateAccountParams{Owner: account.Owner, Balance: 0, Currency: account.Currency}
+	_, err = testQueries.CreateAccount(context.Background(), arg)
+	require.Error(t, err)
+
+	_, err = s.CloseAccountTx(context.Background(), account.ID)
+	require.NoError(t, err)
+
+	// A closed one does not.
+	reopened, err := testQueries.CreateAccount(context.Background(), arg)
+	require.NoError(t, err)
+	require.NotEqual(t, account.ID, reopened.ID)
+	require.Equal(t, AccountStatusActive, reopened.Status)
+}
+
+func TestNextScheduledRun(t *testing.T) {
+	at := func(year int, month time.Month, day, hour int) time.Time {
+		return time.Date(year, month, day, hour, 0, 0, 0, time.UTC)
+	}
+	monthlyOn := func(day int32) sql.NullInt32 {
+		return sql.NullInt32{Int32: day, Valid: true}
+	}
+
+	testCases := []struct {
+		name      string
+		scheduled ScheduledTransfer
+		now       time.Time
+		want      time.Time
+	}{
+		{
+			name:      "Daily",
+			scheduled: ScheduledTransfer{Recurrence: RecurrenceDaily, NextRunAt: at(2022, 1, 1, 9)},
+			now:       at(2022, 1, 1, 9),
+			want:      at(2022, 1, 2, 9),
+		},
+		{
+			name:      "DailySkipsMissedRuns",
+			scheduled: ScheduledTransfer{Recurrence: RecurrenceDaily, NextRunAt: at(2022, 1, 1, 9)},
+			now:       at(2022, 1, 3, 10),
+			want:      at(2022, 1, 4, 9),
+		},
+		{
+			name:      "Weekly",
+			scheduled: ScheduledTransfer{Recurrence: RecurrenceWeekly, NextRunAt: at(2022, 1, 1, 9)},
+			now:       at(2022, 1, 1, 9),
+			want:      at(2022, 1, 8, 9),
+		},
+		{
+			name:      "MonthlyShortMonth",
+			scheduled: ScheduledTransfer{Recurrence: RecurrenceMonthly, DayOfMonth: monthlyOn(31), NextRunAt: at(2022, 1, 31, 9)},
+			now:       at(2022, 1, 31, 9),
+			want:      at(2022, 2, 28, 9),
+		},
+		{
+			name:      "MonthlyBackToDay",
+			scheduled: ScheduledTransfer{Recurrence: RecurrenceMonthly, DayOfMonth: monthlyOn(31), NextRunAt: at(2022, 2, 28, 9)},
+			now:       at(2022, 2, 28, 9),
+			want:      at(2022, 3, 31, 9),
+		},
+		{
+			name:      "MonthlyAcrossYears",
+			scheduled: ScheduledTransfer{Recurrence: RecurrenceMonthly, DayOfMonth: monthlyOn(15), NextRunAt: at(2021, 12, 15, 9)},
+			now:       at(2021, 12, 15, 9),
+			want:      at(2022, 1, 15, 9),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.want, nextScheduledRun(tc.scheduled, tc.now))
+		})
+	}
+}
+
+// runScheduledTransfer runs due scheduled transfers, which may include ones
+// left by other tests, until the one with the given id has run.
+func runScheduledTransfer(t *testing.T, s Store, id int64, arg RunDueScheduledTransferTxParams) ScheduledTransferRun {
+	for i := 0; i < 1_000; i++ {
+		run, err := s.RunDueScheduledTransferTx(context.Background(), arg)
+		require.NoError(t, err)
+
+		if run.ScheduledTransferID == id {
+			return run
+		}
+	}
+
+	t.Fatalf("scheduled transfer %d did not run", id)
+	return ScheduledTransferRun{}
+}
+
+func TestRunDueScheduledTransferTx(t *testing.T) {
+	s := NewStore(testDB)
+	fromAcc := createRandomAccount(t)
+	toAcc := createRandomAccount(t)
+	now := time.Now()
+
+	scheduled := createRandomScheduledTransfer(t, fromAcc, toAcc, RecurrenceOnce, now.Add(-time.Minute))
+	arg := RunDueScheduledTransferTxParams{Now: now, RetryDelay: time.Hour, MaxFailures: 3}
+
+	run := runScheduledTransfer(t, s, scheduled.ID, arg)
+	require.Equal(t, ScheduledTransferRunSucceeded, run.Status)
+	require.True(t, run.TransferID.Valid)
+	require.WithinDuration(t, scheduled.NextRunAt, run.ScheduledFor, time.Second)
+
+	transfer, err := testQueries.GetTransfer(context.Background(), run.TransferID.Int64)
+	require.NoError(t, err)
+	require.Equal(t, scheduled.Amount, transfer.Amount)
+
+	scheduled, err = testQueries.GetScheduledTransfer(context.Background(), scheduled.ID)
+	require.NoError(t, err)
+	require.Equal(t, ScheduledTransferStatusCompleted, scheduled.Status)
+
+	// A recurring transfer moves on to its next occurrence after now,
+	// skipping the one it missed.
+	recurring := createRandomScheduledTransfer(t, fromAcc, toAcc, RecurrenceDaily, now.Add(-time.Hour*36))
+
+	run = runScheduledTransfer(t, s, recurring.ID, arg)
+	require.Equal(t, ScheduledTransferRunSucceeded, run.Status)
+
+	updated, err := testQueries.GetScheduledTransfer(context.Background(), recurring.ID)
+	require.NoError(t, err)
+	require.Equal(t, ScheduledTransferStatusActive, updated.Status)
+	require.WithinDuration(t, recurring.NextRunAt.AddDate(0, 0, 2), updated.NextRunAt, time.Second)
+
+	account, err := testQueries.GetAccount(context.Background(), fromAcc.ID)
+	require.NoError(t, err)
+	require.Equal(t, fromAcc.Balance-scheduled.Amount-recurring.Amount, account.Balance)
+}
+
+func TestRunDueScheduledTransferTxRetries(t *testing.T) {
+	s := NewStore(testDB)
+	fromAcc := createRandomAccount(t)
+	toAcc := createRandomAccount(t)
+	now := time.Now()
+
+	fromAcc, err := testQueries.UpdateAccount(context.Background(), UpdateAccountParams{ID: fromAcc.ID, Balance: 0})
+	require.NoError(t, err)
+
+	scheduled := createRandomScheduledTransfer(t, fromAcc, toAcc, RecurrenceOnce, now.Add(-time.Minute))
+	arg := RunDueScheduledTransferTxParams{Now: now, RetryDelay: 0, MaxFailures: 2}
+
+	run := runScheduledTransfer(t, s, scheduled.ID, arg)
+	require.Equal(t, ScheduledTransferRunFailed, run.Status)
+	require.Equal(t, ErrInsufficientFunds.Error(), run.Error)
+	require.False(t, run.TransferID.Valid)
+
+	updated, err := testQueries.GetScheduledTransfer(context.Background(), scheduled.ID)
+	require.NoError(t, err)
+	require.Equal(t, ScheduledTransferStatusActive, updated.Status)
+	require.Equal(t, int32(1), updated.FailureCount)
+	require.True(t, updated.RetryAt.Valid)
+	require.Equal(t, scheduled.NextRunAt, updated.NextRunAt)
+
+	// The second failure in a row pauses it.
+	runScheduledTransfer(t, s, scheduled.ID, arg)
+
+	updated, err = testQueries.GetScheduledTransfer(context.Background(), scheduled.ID)
+	require.NoError(t, err)
+	require.Equal(t, ScheduledTransferStatusPaused, updated.Status)
+	require.Equal(t, int32(2), updated.FailureCount)
+
+	runs, err := testQueries.ListScheduledTransferRuns(context.Background(), scheduled.ID)
+	require.NoError(t, err)
+	require.Len(t, runs, 2)
+
+	// Once funded and resumed it goes through.
+	_, err = testQueries.UpdateAccount(context.Background(), UpdateAccountParams{ID: fromAcc.ID, Balance: 1_000})
+	require.NoError(t, err)
+
+	updated, err = s.ChangeScheduledTransferStatusTx(context.Background(), scheduled.ID, ScheduledTransferStatusActive)
+	require.NoError(t, err)
+	require.Zero(t, updated.FailureCount)
+
+	run = runScheduledTransfer(t, s, scheduled.ID, arg)
+	require.Equal(t, ScheduledTransferRunSucceeded, run.Status)
+}
+
+func TestFailScheduledTransferTx(t *testing.T) {
+	s := NewStore(testDB)
+	fromAcc := createRandomAccount(t)
+	toAcc := createRandomAccount(t)
+	now := time.Now()
+
+	// Not due yet, so that no other test runs it.
+	scheduled := createRandomScheduledTransfer(t, fromAcc, toAcc, RecurrenceOnce, now.Add(time.Hour))
+	arg := FailScheduledTransferTxParams{
+		RunDueScheduledTransferTxParams: RunDueScheduledTransferTxParams{Now: now, RetryDelay: time.Minute, MaxFailures: 2},
+		Scheduled:                       scheduled,
+		Err:                             errors.New("connection reset"),
+	}
+
+	run, err := s.FailScheduledTransferTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, ScheduledTransferRunFailed, run.Status)
+	require.Equal(t, "connection reset", run.Error)
+
+	updated, err := testQueries.GetScheduledTransfer(context.Background(), scheduled.ID)
+	require.NoError(t, err)
+	require.Equal(t, ScheduledTransferStatusActive, updated.Status)
+	require.Equal(t, int32(1), updated.FailureCount)
+	require.True(t, updated.RetryAt.Valid)
+	require.WithinDuration(t, now.Add(time.Minute), updated.RetryAt.Time, time.Second)
+
+	// A failure is not recorded against a transfer that changed meanwhile.
+	_, err = s.FailScheduledTransferTx(context.Background(), arg)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	// The second failure in a row pauses it.
+	arg.Scheduled = updated
+	_, err = s.FailScheduledTransferTx(context.Background(), arg)
+	require.NoError(t, err)
+
+	updated, err = testQueries.GetScheduledTransfer(context.Background(), scheduled.ID)
+	require.NoError(t, err)
+	require.Equal(t, ScheduledTransferStatusPaused, updated.Status)
+	require.Equal(t, int32(2), updated.FailureCount)
+
+	runs, err := testQueries.ListScheduledTransferRuns(context.Background(), scheduled.ID)
+	require.NoError(t, err)
+	require.Len(t, runs, 2)
+}
+
+func TestChangeScheduledTransferStatusTx(t *testing.T) {
+	s := NewStore(testDB)
+	scheduled := createRandomScheduledTransfer(t, createRandomAccount(t), createRandomAccount(t), RecurrenceWeekly, time.Now().Add(time.Hour))
+
+	updated, err := s.ChangeScheduledTransferStatusTx(context.Background(), scheduled.ID, ScheduledTransferStatusPaused)
+	require.NoError(t, err)
+	require.Equal(t, ScheduledTransferStatusPaused, updated.Status)
+
+	_, err = s.ChangeScheduledTransferStatusTx(context.Background(), scheduled.ID, ScheduledTransferStatusPaused)
+	require.ErrorIs(t, err, ErrInvalidStatusChange)
+
+	updated, err = s.ChangeScheduledTransferStatusTx(context.Background(), scheduled.ID, ScheduledTransferStatusCancelled)
+	require.NoError(t, err)
+	require.Equal(t, ScheduledTransferStatusCancelled, updated.Status)
+
+	// Cancelled is final.
+	_, err = s.ChangeScheduledTransferStatusTx(context.Background(), scheduled.ID, ScheduledTransferStatusActive)
+	require.ErrorIs(t, err, ErrInvalidStatusChange)
+
+	_, err = s.ChangeScheduledTransferStatusTx(context.Background(), scheduled.ID+1_000_000_000, ScheduledTransferStatusPaused)
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+}
+
+func TestRevokeTokenTx(t *testing.T) {
+	s := NewStore(testDB)
+	session := createRandomSession(t)
+
+	arg := RevokeTokenTxParams{
+		TokenID:   uuid.New(),
+		Username:  session.Username,
+		ExpiresAt: time.Now().Add(time.Minute),
+		SessionID: uuid.NullUUID{UUID: session.ID, Valid: true},
+	}
+
+	err := s.RevokeTokenTx(context.Background(), arg)
+	require.NoError(t, err)
+
+	revoked, err := testQueries.IsTokenRevoked(context.Background(), arg.TokenID)
+	require.NoError(t, err)
+	require.True(t, revoked)
+
+	blockedSession, err := testQueries.GetSession(context.Background(), session.ID)
+	require.NoError(t, err)
+	require.True(t, blockedSession.IsBlocked)
+
+	revoked, err = testQueries.IsTokenRevoked(context.Background(), session.ID)
+	require.NoError(t, err)
+	require.True(t, revoked)
+
+	// Another access token of the same session logs out with it too, and
+	// revoking a token twice is not an error.
+	arg.TokenID = uuid.New()
+	err = s.RevokeTokenTx(context.Background(), arg)
+	require.NoError(t, err)
+
+	err = s.RevokeTokenTx(context.Background(), arg)
+	require.NoError(t, err)
+}
+
+func TestEnrollTotpTx(t *testing.T) {
+	s := NewStore(testDB)
+	user := createRandomUser(t)
+
+	arg := EnrollTotpTxParams{
+		Username:            user.Username,
+		EncryptedSecret:     []byte(randomString(32)),
+		HashedRecoveryCodes: []string{randomString(64), randomString(64)},
+	}
+
+	userTotp, err := s.EnrollTotpTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.Username, userTotp.Username)
+	require.False(t, userTotp.IsEnabled)
+
+	for _, hashedCode := range arg.HashedRecoveryCodes {
+		_, err = testQueries.UseTotpRecoveryCode(context.Background(), UseTotpRecoveryCodeParams{
+			Username:   user.Username,
+			HashedCode: hashedCode,
 		})
+		require.NoError(t, err)
+	}
+
+	err = s.DisableTotpTx(context.Background(), user.Username)
+	require.NoError(t, err)
 
-		results = append(results, result)
+	_, err = testQueries.GetUserTotp(context.Background(), user.Username)
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+}
+
+func TestResetPasswordTx(t *testing.T) {
+	s := NewStore(testDB)
+	resetToken := createRandomPasswordResetToken(t, time.Now().Add(time.Hour))
+
+	hashedPass, err := HashPassword(randomString(8))
+	require.NoError(t, err)
+
+	arg := ResetPasswordTxParams{
+		HashedToken:    resetToken.HashedToken,
+		HashedPassword: hashedPass,
 	}
 
-	var err error
-	err = s.DeleteAccountTx(context.Background(), fromAcc.ID)
+	user, err := s.ResetPasswordTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, resetToken.Username, user.Username)
+	require.Equal(t, hashedPass, user.HashedPassword)
+	require.WithinDuration(t, time.Now(), user.PasswordChangedAt, time.Second)
+
+	// The token is single-use.
+	_, err = s.ResetPasswordTx(context.Background(), arg)
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+}
+
+func TestCreateUserTx(t *testing.T) {
+	s := NewStore(testDB)
+
+	hashedPass, err := HashPassword(randomString(8))
 	require.NoError(t, err)
-	err = s.DeleteAccountTx(context.Background(), toAcc.ID)
+
+	arg := CreateUserTxParams{
+		CreateUserParams: CreateUserParams{
+			Username:       randomString(8),
+			HashedPassword: hashedPass,
+			FullName:       randomString(6),
+			Email:          randomString(6) + "@" + randomString(4) + ".com",
+		},
+		HashedVerifyToken:    randomString(64),
+		VerifyTokenExpiresAt: time.Now().Add(time.Hour),
+	}
+
+	user, err := s.CreateUserTx(context.Background(), arg)
 	require.NoError(t, err)
+	require.Equal(t, arg.Username, user.Username)
+	require.False(t, user.IsEmailVerified)
 
-	for _, result := range results {
-		transfer := result.Transfer
-		fromEntry := result.FromEntry
-		toEntry := result.ToEntry
+	user, err = s.VerifyEmailTx(context.Background(), arg.HashedVerifyToken)
+	require.NoError(t, err)
+	require.Equal(t, arg.Username, user.Username)
+	require.True(t, user.IsEmailVerified)
 
-		queriedTransfer, err := testQueries.GetTransfer(context.Background(), transfer.ID)
-		require.Error(t, err)
-		require.EqualError(t, err, sql.ErrNoRows.Error())
-		require.Empty(t, queriedTransfer)
+	// The token is single-use.
+	_, err = s.VerifyEmailTx(context.Background(), arg.HashedVerifyToken)
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+}
 
-		queriedFromEntry, err := testQueries.GetEntry(context.Background(), fromEntry.ID)
-		require.Error(t, err)
-		require.EqualError(t, err, sql.ErrNoRows.Error())
-		require.Empty(t, queriedFromEntry)
+func TestUpdateUserTx(t *testing.T) {
+	s := NewStore(testDB)
+	user := createRandomUser(t)
 
-		queriedToEntry, err := testQueries.GetEntry(context.Background(), toEntry.ID)
-		require.Error(t, err)
-		require.EqualError(t, err, sql.ErrNoRows.Error())
-		require.Empty(t, queriedToEntry)
+	arg := UpdateUserTxParams{
+		UpdateUserParams: UpdateUserParams{
+			FullName: sql.NullString{String: randomString(6), Valid: true},
+			Username: user.Username,
+		},
+		HashedVerifyToken:    randomString(64),
+		VerifyTokenExpiresAt: time.Now().Add(time.Hour),
 	}
 
+	result, err := s.UpdateUserTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.FullName.String, result.User.FullName)
+	require.False(t, result.EmailChanged)
+
+	arg.Email = sql.NullString{String: randomString(6) + "@" + randomString(4) + ".com", Valid: true}
+	result, err = s.UpdateUserTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.Email.String, result.User.Email)
+	require.True(t, result.EmailChanged)
+
+	verifyEmail, err := testQueries.GetVerifyEmailForUpdate(context.Background(), arg.HashedVerifyToken)
+	require.NoError(t, err)
+	require.Equal(t, arg.Email.String, verifyEmail.Email)
+}
+
+func TestExportUserTx(t *testing.T) {
+	s := NewStore(testDB)
+	acc1 := createRandomAccount(t)
+	acc2 := createRandomAccount(t)
+	createRandomEntry(t, acc1)
+	createRandomTransfer(t, acc1, acc2)
+
+	export, err := s.ExportUserTx(context.Background(), acc1.Owner)
+	require.NoError(t, err)
+	require.Equal(t, acc1.Owner, export.User.Username)
+	require.Len(t, export.Accounts, 1)
+	require.Len(t, export.Entries, 1)
+	require.Len(t, export.Transfers, 1)
+}
+
+func TestDeleteUserTx(t *testing.T) {
+	s := NewStore(testDB)
+	account := createRandomAccount(t)
+
+	account, err := testQueries.UpdateAccount(context.Background(), UpdateAccountParams{
+		ID:      account.ID,
+		Balance: 10,
+	})
+	require.NoError(t, err)
+
+	_, err = s.DeleteUserTx(context.Background(), account.Owner)
+	require.ErrorIs(t, err, ErrAccountHasBalance)
+
+	_, err = testQueries.UpdateAccount(context.Background(), UpdateAccountParams{
+		ID:      account.ID,
+		Balance: 0,
+	})
+	require.NoError(t, err)
+
+	user, err := s.DeleteUserTx(context.Background(), account.Owner)
+	require.NoError(t, err)
+	require.Equal(t, account.Owner, user.Username)
+	require.Empty(t, user.FullName)
+	require.Empty(t, user.HashedPassword)
+	require.Equal(t, "deleted+"+account.Owner+"@invalid", user.Email)
+	require.True(t, user.DeletedAt.Valid)
+
+	// The ledger is kept and the account closed.
+	account, err = testQueries.GetAccount(context.Background(), account.ID)
+	require.NoError(t, err)
+	require.Equal(t, AccountStatusClosed, account.Status)
+
+	_, err = s.DeleteUserTx(context.Background(), account.Owner)
+	require.EqualError(t, err, sql.ErrNoRows.Error())
 }
 
 func TestPassword(t *testing.T) {
@@ -0,0 +1,123 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpsertUserTotp(t *testing.T) {
+	createRandomUserTotp(t)
+}
+
+func TestGetUserTotp(t *testing.T) {
+	createdTotp := createRandomUserTotp(t)
+	queriedTotp, err := testQueries.GetUserTotp(context.Background(), createdTotp.Username)
+
+	require.NoError(t, err)
+	require.NotEmpty(t, queriedTotp)
+	require.Equal(t, createdTotp.Username, queriedTotp.Username)
+	require.Equal(t, createdTotp.EncryptedSecret, queriedTotp.EncryptedSecret)
+	require.Equal(t, createdTotp.IsEnabled, queriedTotp.IsEnabled)
+	require.Equal(t, createdTotp.LastUsedStep, queriedTotp.LastUsedStep)
+	require.Equal(t, createdTotp.CreatedAt, queriedTotp.CreatedAt)
+}
+
+func TestEnableUserTotp(t *testing.T) {
+	createdTotp := createRandomUserTotp(t)
+
+	arg := EnableUserTotpParams{
+		Username:     createdTotp.Username,
+		LastUsedStep: randomInt(1, 1_000_000),
+	}
+
+	enabledTotp, err := testQueries.EnableUserTotp(context.Background(), arg)
+
+	require.NoError(t, err)
+	require.True(t, enabledTotp.IsEnabled)
+	require.Equal(t, arg.LastUsedStep, enabledTotp.LastUsedStep)
+
+	reenrolledTotp, err := testQueries.UpsertUserTotp(context.Background(), UpsertUserTotpParams{
+		Username:        createdTotp.Username,
+		EncryptedSecret: []byte(randomString(32)),
+	})
+
+	require.NoError(t, err)
+	require.False(t, reenrolledTotp.IsEnabled)
+	require.Zero(t, reenrolledTotp.LastUsedStep)
+}
+
+func TestUpdateUserTotpLastUsedStep(t *testing.T) {
+	createdTotp := createRandomUserTotp(t)
+
+	arg := UpdateUserTotpLastUsedStepParams{
+		Step:     randomInt(1, 1_000_000),
+		Username: createdTotp.Username,
+	}
+
+	updatedTotp, err := testQueries.UpdateUserTotpLastUsedStep(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.Step, updatedTotp.LastUsedStep)
+
+	// The same step cannot be accepted twice.
+	_, err = testQueries.UpdateUserTotpLastUsedStep(context.Background(), arg)
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+}
+
+func TestDeleteUserTotp(t *testing.T) {
+	createdTotp := createRandomUserTotp(t)
+
+	err := testQueries.DeleteUserTotp(context.Background(), createdTotp.Username)
+	require.NoError(t, err)
+
+	queriedTotp, err := testQueries.GetUserTotp(context.Background(), createdTotp.Username)
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+	require.Empty(t, queriedTotp)
+}
+
+func TestUseTotpRecoveryCode(t *testing.T) {
+	user := createRandomUser(t)
+	hashedCode := randomString(64)
+
+	code, err := testQueries.CreateTotpRecoveryCode(context.Background(), CreateTotpRecoveryCodeParams{
+		Username:   user.Username,
+		HashedCode: hashedCode,
+	})
+	require.NoError(t, err)
+	require.False(t, code.UsedAt.Valid)
+
+	arg := UseTotpRecoveryCodeParams{
+		Username:   user.Username,
+		HashedCode: hashedCode,
+	}
+
+	usedCode, err := testQueries.UseTotpRecoveryCode(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, code.ID, usedCode.ID)
+	require.True(t, usedCode.UsedAt.Valid)
+
+	_, err = testQueries.UseTotpRecoveryCode(context.Background(), arg)
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+}
+
+func createRandomUserTotp(t *testing.T) UserTotp {
+	user := createRandomUser(t)
+	arg := UpsertUserTotpParams{
+		Username:        user.Username,
+		EncryptedSecret: []byte(randomString(32)),
+	}
+
+	userTotp, err := testQueries.UpsertUserTotp(context.Background(), arg)
+
+	require.NoError(t, err)
+	require.NotEmpty(t, userTotp)
+	require.Equal(t, arg.Username, userTotp.Username)
+	require.Equal(t, arg.EncryptedSecret, userTotp.EncryptedSecret)
+	require.False(t, userTotp.IsEnabled)
+	require.Zero(t, userTotp.LastUsedStep)
+	require.NotZero(t, userTotp.CreatedAt)
+
+	return userTotp
+}
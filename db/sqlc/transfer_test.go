@@ -2,7 +2,6 @@ package db
 
 import (
 	"context"
-	"database/sql"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -27,6 +26,9 @@ func TestGetTransfer(t *testing.T) {
 	require.Equal(t, transfer.FromAccountID, queriedTransfer.FromAccountID)
 	require.Equal(t, transfer.ToAccountID, queriedTransfer.ToAccountID)
 	require.Equal(t, transfer.Amount, queriedTransfer.Amount)
+	require.Equal(t, transfer.ToAmount, queriedTransfer.ToAmount)
+	require.Equal(t, transfer.ExchangeRate, queriedTransfer.ExchangeRate)
+	require.Equal(t, transfer.Spread, queriedTransfer.Spread)
 }
 
 func TestListTransfer(t *testing.T) {
@@ -55,25 +57,15 @@ func TestListTransfer(t *testing.T) {
 	}
 }
 
-func TestDeleteTransfer(t *testing.T) {
-	var err error
-	transfer1 := createRandomTransfer(t, createRandomAccount(t), createRandomAccount(t))
-	err = testQueries.DeleteTransfer(context.Background(), transfer1.FromAccountID)
-	require.NoError(t, err)
-
-	queriedTransfer1, err := testQueries.GetTransfer(context.Background(), transfer1.ID)
-	require.Error(t, err)
-	require.EqualError(t, err, sql.ErrNoRows.Error())
-	require.Empty(t, queriedTransfer1)
+func TestListTransfersByOwner(t *testing.T) {
+	acc1 := createRandomAccount(t)
+	acc2 := createRandomAccount(t)
+	createRandomTransfer(t, acc1, acc2)
+	createRandomTransfer(t, acc2, acc1)
 
-	transfer2 := createRandomTransfer(t, createRandomAccount(t), createRandomAccount(t))
-	err = testQueries.DeleteTransfer(context.Background(), transfer2.ToAccountID)
+	transfers, err := testQueries.ListTransfersByOwner(context.Background(), acc1.Owner)
 	require.NoError(t, err)
-
-	queriedTransfer2, err := testQueries.GetTransfer(context.Background(), transfer1.ID)
-	require.Error(t, err)
-	require.EqualError(t, err, sql.ErrNoRows.Error())
-	require.Empty(t, queriedTransfer2)
+	require.Len(t, transfers, 2)
 }
 
 func createRandomTransfer(t *testing.T, from, to Account) Transfer {
@@ -81,7 +73,10 @@ func createRandomTransfer(t *testing.T, from, to Account) Transfer {
 		FromAccountID: from.ID,
 		ToAccountID:   to.ID,
 		Amount:        randomInt(100, 1_000_000),
+		ExchangeRate:  "1",
+		Spread:        "0",
 	}
+	arg.ToAmount = arg.Amount
 
 	transfer, err := testQueries.CreateTransfer(context.Background(), arg)
 
@@ -5,8 +5,43 @@ package db
 
 import (
 	"context"
+	"database/sql"
 )
 
+const anonymizeUser = `-- name: AnonymizeUser :one
+UPDATE users
+SET
+  hashed_password = '',
+  full_name = '',
+  email = $2,
+  is_email_verified = false,
+  deleted_at = now()
+WHERE username = $1 AND deleted_at IS NULL
+RETURNING username, hashed_password, password_changed_at, full_name, email, created_at, role, is_email_verified, deleted_at
+`
+
+type AnonymizeUserParams struct {
+	Username string `json:"username"`
+	Email    string `json:"email"`
+}
+
+func (q *Queries) AnonymizeUser(ctx context.Context, arg AnonymizeUserParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, anonymizeUser, arg.Username, arg.Email)
+	var i User
+	err := row.Scan(
+		&i.Username,
+		&i.HashedPassword,
+		&i.PasswordChangedAt,
+		&i.FullName,
+		&i.Email,
+		&i.CreatedAt,
+		&i.Role,
+		&i.IsEmailVerified,
+		&i.DeletedAt,
+	)
+	return i, err
+}
+
 const createUser = `-- name: CreateUser :one
 INSERT INTO users (
   username,
@@ -15,7 +50,7 @@ INSERT INTO users (
   email
 ) VALUES (
   $1, $2, $3, $4
-) RETURNING username, hashed_password, password_changed_at, full_name, email, created_at
+) RETURNING username, hashed_password, password_changed_at, full_name, email, created_at, role, is_email_verified, deleted_at
 `
 
 type CreateUserParams struct {
@@ -40,12 +75,15 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.FullName,
 		&i.Email,
 		&i.CreatedAt,
+		&i.Role,
+		&i.IsEmailVerified,
+		&i.DeletedAt,
 	)
 	return i, err
 }
 
 const getUser = `-- name: GetUser :one
-SELECT username, hashed_password, password_changed_at, full_name, email, created_at FROM users
+SELECT username, hashed_password, password_changed_at, full_name, email, created_at, role, is_email_verified, deleted_at FROM users
 WHERE username = $1 LIMIT 1
 `
 
@@ -59,6 +97,156 @@ func (q *Queries) GetUser(ctx context.Context, username string) (User, error) {
 		&i.FullName,
 		&i.Email,
 		&i.CreatedAt,
+		&i.Role,
+		&i.IsEmailVerified,
+		&i.DeletedAt,
+	)
+	return i, err
+}
+
+const getUserByEmail = `-- name: GetUserByEmail :one
+SELECT username, hashed_password, password_changed_at, full_name, email, created_at, role, is_email_verified, deleted_at FROM users
+WHERE email = $1 LIMIT 1
+`
+
+func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserByEmail, email)
+	var i User
+	err := row.Scan(
+		&i.Username,
+		&i.HashedPassword,
+		&i.PasswordChangedAt,
+		&i.FullName,
+		&i.Email,
+		&i.CreatedAt,
+		&i.Role,
+		&i.IsEmailVerified,
+		&i.DeletedAt,
+	)
+	return i, err
+}
+
+const updateUser = `-- name: UpdateUser :one
+UPDATE users
+SET
+  full_name = COALESCE($1, full_name),
+  email = COALESCE($2, email),
+  is_email_verified = CASE
+    WHEN $2 IS NULL OR $2 = email THEN is_email_verified
+    ELSE false
+  END
+WHERE username = $3
+RETURNING username, hashed_password, password_changed_at, full_name, email, created_at, role, is_email_verified, deleted_at
+`
+
+type UpdateUserParams struct {
+	FullName sql.NullString `json:"full_name"`
+	Email    sql.NullString `json:"email"`
+	Username string         `json:"username"`
+}
+
+func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, updateUser, arg.FullName, arg.Email, arg.Username)
+	var i User
+	err := row.Scan(
+		&i.Username,
+		&i.HashedPassword,
+		&i.PasswordChangedAt,
+		&i.FullName,
+		&i.Email,
+		&i.CreatedAt,
+		&i.Role,
+		&i.IsEmailVerified,
+		&i.DeletedAt,
+	)
+	return i, err
+}
+
+const updateUserPassword = `-- name: UpdateUserPassword :one
+UPDATE users
+SET
+  hashed_password = $2,
+  password_changed_at = now()
+WHERE username = $1
+RETURNING username, hashed_password, password_changed_at, full_name, email, created_at, role, is_email_verified, deleted_at
+`
+
+type UpdateUserPasswordParams struct {
+	Username       string `json:"username"`
+	HashedPassword string `json:"hashed_password"`
+}
+
+func (q *Queries) UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, updateUserPassword, arg.Username, arg.HashedPassword)
+	var i User
+	err := row.Scan(
+		&i.Username,
+		&i.HashedPassword,
+		&i.PasswordChangedAt,
+		&i.FullName,
+		&i.Email,
+		&i.CreatedAt,
+		&i.Role,
+		&i.IsEmailVerified,
+		&i.DeletedAt,
+	)
+	return i, err
+}
+
+const updateUserRole = `-- name: UpdateUserRole :one
+UPDATE users
+SET role = $2
+WHERE username = $1
+RETURNING username, hashed_password, password_changed_at, full_name, email, created_at, role, is_email_verified, deleted_at
+`
+
+type UpdateUserRoleParams struct {
+	Username string `json:"username"`
+	Role     string `json:"role"`
+}
+
+func (q *Queries) UpdateUserRole(ctx context.Context, arg UpdateUserRoleParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, updateUserRole, arg.Username, arg.Role)
+	var i User
+	err := row.Scan(
+		&i.Username,
+		&i.HashedPassword,
+		&i.PasswordChangedAt,
+		&i.FullName,
+		&i.Email,
+		&i.CreatedAt,
+		&i.Role,
+		&i.IsEmailVerified,
+		&i.DeletedAt,
+	)
+	return i, err
+}
+
+const verifyUserEmail = `-- name: VerifyUserEmail :one
+UPDATE users
+SET is_email_verified = true
+WHERE username = $1 AND email = $2
+RETURNING username, hashed_password, password_changed_at, full_name, email, created_at, role, is_email_verified, deleted_at
+`
+
+type VerifyUserEmailParams struct {
+	Username string `json:"username"`
+	Email    string `json:"email"`
+}
+
+func (q *Queries) VerifyUserEmail(ctx context.Context, arg VerifyUserEmailParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, verifyUserEmail, arg.Username, arg.Email)
+	var i User
+	err := row.Scan(
+		&i.Username,
+		&i.HashedPassword,
+		&i.PasswordChangedAt,
+		&i.FullName,
+		&i.Email,
+		&i.CreatedAt,
+		&i.Role,
+		&i.IsEmailVerified,
+		&i.DeletedAt,
 	)
 	return i, err
 }
@@ -2,7 +2,9 @@ package db
 
 import (
 	"context"
+	"database/sql"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 )
@@ -23,6 +25,119 @@ func TestGetUser(t *testing.T) {
 	require.Equal(t, createdUser.HashedPassword, queriedUser.HashedPassword)
 	require.Equal(t, createdUser.PasswordChangedAt, queriedUser.PasswordChangedAt)
 	require.Equal(t, createdUser.Username, queriedUser.Username)
+	require.Equal(t, createdUser.Role, queriedUser.Role)
+	require.Equal(t, createdUser.IsEmailVerified, queriedUser.IsEmailVerified)
+}
+
+func TestGetUserByEmail(t *testing.T) {
+	user1 := createRandomUser(t)
+	user2, err := testQueries.GetUserByEmail(context.Background(), user1.Email)
+
+	require.NoError(t, err)
+	require.NotEmpty(t, user2)
+	require.Equal(t, user1.Username, user2.Username)
+	require.Equal(t, user1.Email, user2.Email)
+}
+
+func TestUpdateUserFullName(t *testing.T) {
+	user1 := createRandomUser(t)
+	newFullName := randomString(6)
+
+	user2, err := testQueries.UpdateUser(context.Background(), UpdateUserParams{
+		FullName: sql.NullString{String: newFullName, Valid: true},
+		Username: user1.Username,
+	})
+
+	require.NoError(t, err)
+	require.Equal(t, newFullName, user2.FullName)
+	require.Equal(t, user1.Email, user2.Email)
+}
+
+func TestUpdateUserEmail(t *testing.T) {
+	user1 := createRandomUser(t)
+	user1, err := testQueries.VerifyUserEmail(context.Background(), VerifyUserEmailParams{
+		Username: user1.Username,
+		Email:    user1.Email,
+	})
+	require.NoError(t, err)
+
+	// Setting the same address keeps it verified.
+	user2, err := testQueries.UpdateUser(context.Background(), UpdateUserParams{
+		Email:    sql.NullString{String: user1.Email, Valid: true},
+		Username: user1.Username,
+	})
+	require.NoError(t, err)
+	require.True(t, user2.IsEmailVerified)
+
+	newEmail := randomString(6) + "@" + randomString(4) + ".com"
+	user2, err = testQueries.UpdateUser(context.Background(), UpdateUserParams{
+		Email:    sql.NullString{String: newEmail, Valid: true},
+		Username: user1.Username,
+	})
+	require.NoError(t, err)
+	require.Equal(t, newEmail, user2.Email)
+	require.Equal(t, user1.FullName, user2.FullName)
+	require.False(t, user2.IsEmailVerified)
+}
+
+func TestUpdateUserPassword(t *testing.T) {
+	createdUser := createRandomUser(t)
+	hashedPass, err := HashPassword(randomString(8))
+	require.NoError(t, err)
+
+	arg := UpdateUserPasswordParams{
+		Username:       createdUser.Username,
+		HashedPassword: hashedPass,
+	}
+
+	updatedUser, err := testQueries.UpdateUserPassword(context.Background(), arg)
+
+	require.NoError(t, err)
+	require.NotEmpty(t, updatedUser)
+	require.Equal(t, createdUser.Username, updatedUser.Username)
+	require.Equal(t, arg.HashedPassword, updatedUser.HashedPassword)
+	require.NotEqual(t, createdUser.HashedPassword, updatedUser.HashedPassword)
+	require.False(t, updatedUser.PasswordChangedAt.IsZero())
+	require.WithinDuration(t, time.Now(), updatedUser.PasswordChangedAt, time.Second)
+}
+
+func TestUpdateUserRole(t *testing.T) {
+	createdUser := createRandomUser(t)
+
+	arg := UpdateUserRoleParams{
+		Username: createdUser.Username,
+		Role:     "banker",
+	}
+
+	updatedUser, err := testQueries.UpdateUserRole(context.Background(), arg)
+
+	require.NoError(t, err)
+	require.NotEmpty(t, updatedUser)
+	require.Equal(t, createdUser.Username, updatedUser.Username)
+	require.Equal(t, arg.Role, updatedUser.Role)
+
+	arg.Role = randomString(6)
+	_, err = testQueries.UpdateUserRole(context.Background(), arg)
+	require.Error(t, err)
+}
+
+func TestVerifyUserEmail(t *testing.T) {
+	user1 := createRandomUser(t)
+
+	user2, err := testQueries.VerifyUserEmail(context.Background(), VerifyUserEmailParams{
+		Username: user1.Username,
+		Email:    user1.Email,
+	})
+	require.NoError(t, err)
+	require.Equal(t, user1.Username, user2.Username)
+	require.True(t, user2.IsEmailVerified)
+
+	// An address the user no longer has cannot be verified.
+	_, err = testQueries.VerifyUserEmail(context.Background(), VerifyUserEmailParams{
+		Username: user1.Username,
+		Email:    randomString(6) + "@" + randomString(4) + ".com",
+	})
+	require.EqualError(t, err, sql.ErrNoRows.Error())
 }
 
 func createRandomUser(t *testing.T) User {
@@ -43,6 +158,8 @@ func createRandomUser(t *testing.T) User {
 	require.Equal(t, arg.FullName, user.FullName)
 	require.Equal(t, arg.HashedPassword, user.HashedPassword)
 	require.Equal(t, arg.Username, user.Username)
+	require.Equal(t, "customer", user.Role)
+	require.False(t, user.IsEmailVerified)
 	require.True(t, user.PasswordChangedAt.IsZero())
 	require.NotZero(t, user.CreatedAt)
 
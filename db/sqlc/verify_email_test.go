@@ -0,0 +1,66 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateVerifyEmail(t *testing.T) {
+	createRandomVerifyEmail(t, time.Now().Add(time.Hour))
+}
+
+func TestGetVerifyEmailForUpdate(t *testing.T) {
+	created := createRandomVerifyEmail(t, time.Now().Add(time.Hour))
+
+	queried, err := testQueries.GetVerifyEmailForUpdate(context.Background(), created.HashedToken)
+	require.NoError(t, err)
+	require.Equal(t, created.ID, queried.ID)
+	require.Equal(t, created.Username, queried.Username)
+	require.Equal(t, created.Email, queried.Email)
+	require.WithinDuration(t, created.ExpiresAt, queried.ExpiresAt, time.Second)
+}
+
+func TestGetExpiredVerifyEmail(t *testing.T) {
+	created := createRandomVerifyEmail(t, time.Now().Add(-time.Minute))
+
+	_, err := testQueries.GetVerifyEmailForUpdate(context.Background(), created.HashedToken)
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+}
+
+func TestUseVerifyEmail(t *testing.T) {
+	created := createRandomVerifyEmail(t, time.Now().Add(time.Hour))
+
+	err := testQueries.UseVerifyEmail(context.Background(), created.ID)
+	require.NoError(t, err)
+
+	_, err = testQueries.GetVerifyEmailForUpdate(context.Background(), created.HashedToken)
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+}
+
+func createRandomVerifyEmail(t *testing.T, expiresAt time.Time) VerifyEmail {
+	user := createRandomUser(t)
+
+	arg := CreateVerifyEmailParams{
+		Username:    user.Username,
+		Email:       user.Email,
+		HashedToken: randomString(64),
+		ExpiresAt:   expiresAt,
+	}
+
+	verifyEmail, err := testQueries.CreateVerifyEmail(context.Background(), arg)
+
+	require.NoError(t, err)
+	require.NotZero(t, verifyEmail.ID)
+	require.Equal(t, arg.Username, verifyEmail.Username)
+	require.Equal(t, arg.Email, verifyEmail.Email)
+	require.Equal(t, arg.HashedToken, verifyEmail.HashedToken)
+	require.WithinDuration(t, arg.ExpiresAt, verifyEmail.ExpiresAt, time.Second)
+	require.False(t, verifyEmail.UsedAt.Valid)
+	require.NotZero(t, verifyEmail.CreatedAt)
+
+	return verifyEmail
+}
@@ -0,0 +1,56 @@
+package exchange
+
+import (
+	"context"
+	"math/big"
+	"sync"
+	"time"
+)
+
+type cachedRate struct {
+	rate      *big.Rat
+	expiresAt time.Time
+}
+
+// CachedRateProvider remembers the rates returned by another provider for
+// ttl. Errors are not cached.
+type CachedRateProvider struct {
+	provider RateProvider
+	ttl      time.Duration
+	now      func() time.Time
+
+	mu    sync.Mutex
+	rates map[string]cachedRate
+}
+
+func NewCachedRateProvider(provider RateProvider, ttl time.Duration) *CachedRateProvider {
+	return &CachedRateProvider{
+		provider: provider,
+		ttl:      ttl,
+		now:      time.Now,
+		rates:    make(map[string]cachedRate),
+	}
+}
+
+func (p *CachedRateProvider) Rate(ctx context.Context, from, to string) (*big.Rat, error) {
+	key := pairKey(from, to)
+
+	p.mu.Lock()
+	cached, ok := p.rates[key]
+	p.mu.Unlock()
+
+	if ok && p.now().Before(cached.expiresAt) {
+		return new(big.Rat).Set(cached.rate), nil
+	}
+
+	rate, err := p.provider.Rate(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	p.rates[key] = cachedRate{rate: new(big.Rat).Set(rate), expiresAt: p.now().Add(p.ttl)}
+	p.mu.Unlock()
+
+	return rate, nil
+}
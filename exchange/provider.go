@@ -0,0 +1,30 @@
+package exchange
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+var ErrRateNotFound = errors.New("exchange rate not found")
+
+// RateProvider returns the mid-market rate between two currencies, as the
+// number of units of to bought by one unit of from.
+type RateProvider interface {
+	Rate(ctx context.Context, from, to string) (*big.Rat, error)
+}
+
+// ParseRate parses a non-negative decimal such as "1.3562".
+func ParseRate(s string) (*big.Rat, error) {
+	r, ok := new(big.Rat).SetString(s)
+	if !ok || r.Sign() < 0 {
+		return nil, fmt.Errorf("invalid rate %q", s)
+	}
+
+	return r, nil
+}
+
+func pairKey(from, to string) string {
+	return from + "/" + to
+}
@@ -0,0 +1,154 @@
+package exchange
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStaticRateProvider(t *testing.T) {
+	provider, err := NewStaticRateProvider(map[string]string{"USD/CAD": "1.25"})
+	require.NoError(t, err)
+
+	rate, err := provider.Rate(context.Background(), "USD", "CAD")
+	require.NoError(t, err)
+	require.Equal(t, "1.25", rate.FloatString(2))
+
+	rate, err = provider.Rate(context.Background(), "CAD", "USD")
+	require.NoError(t, err)
+	require.Equal(t, "0.80", rate.FloatString(2))
+
+	rate, err = provider.Rate(context.Background(), "CAD", "CAD")
+	require.NoError(t, err)
+	require.Equal(t, "1", rate.RatString())
+
+	_, err = provider.Rate(context.Background(), "CAD", "EUR")
+	require.ErrorIs(t, err, ErrRateNotFound)
+
+	_, err = NewStaticRateProvider(map[string]string{"USDCAD": "1.25"})
+	require.Error(t, err)
+
+	_, err = NewStaticRateProvider(map[string]string{"USD/CAD": "0"})
+	require.Error(t, err)
+}
+
+func TestFileRateProvider(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rates.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"CAD/USD": "0.75"}`), 0600))
+
+	provider := NewFileRateProvider(path)
+	rate, err := provider.Rate(context.Background(), "CAD", "USD")
+	require.NoError(t, err)
+	require.Equal(t, "0.75", rate.FloatString(2))
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"CAD/USD": "0.70"}`), 0600))
+	rate, err = provider.Rate(context.Background(), "CAD", "USD")
+	require.NoError(t, err)
+	require.Equal(t, "0.70", rate.FloatString(2))
+
+	_, err = NewFileRateProvider(filepath.Join(t.TempDir(), "missing.json")).Rate(context.Background(), "CAD", "USD")
+	require.Error(t, err)
+}
+
+type countingProvider struct {
+	calls int
+	err   error
+}
+
+func (p *countingProvider) Rate(ctx context.Context, from, to string) (*big.Rat, error) {
+	p.calls++
+	if p.err != nil {
+		return nil, p.err
+	}
+	return big.NewRat(3, 4), nil
+}
+
+func TestCachedRateProvider(t *testing.T) {
+	inner := &countingProvider{}
+	provider := NewCachedRateProvider(inner, time.Minute)
+
+	now := time.Now()
+	provider.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		rate, err := provider.Rate(context.Background(), "CAD", "USD")
+		require.NoError(t, err)
+		require.Equal(t, "3/4", rate.RatString())
+	}
+	require.Equal(t, 1, inner.calls)
+
+	// Changing a returned rate does not change the cached one.
+	rate, err := provider.Rate(context.Background(), "CAD", "USD")
+	require.NoError(t, err)
+	rate.SetInt64(10)
+
+	rate, err = provider.Rate(context.Background(), "CAD", "USD")
+	require.NoError(t, err)
+	require.Equal(t, "3/4", rate.RatString())
+
+	now = now.Add(time.Minute)
+	_, err = provider.Rate(context.Background(), "CAD", "USD")
+	require.NoError(t, err)
+	require.Equal(t, 2, inner.calls)
+
+	inner.err = errors.New("provider down")
+	now = now.Add(time.Minute)
+	_, err = provider.Rate(context.Background(), "CAD", "USD")
+	require.Error(t, err)
+}
+
+func TestQuote(t *testing.T) {
+	provider, err := NewStaticRateProvider(map[string]string{"CAD/USD": "0.7351"})
+	require.NoError(t, err)
+
+	quote, err := NewQuote(context.Background(), provider, "CAD", "USD", big.NewRat(1, 100))
+	require.NoError(t, err)
+	require.Equal(t, "0.7351000000", quote.RateString())
+	require.Equal(t, "0.0100000000", quote.SpreadString())
+
+	// 10000 * 0.7351 * 0.99 = 7277.49, rounded down.
+	converted, err := quote.ConvertMinorUnits(10_000, 2, 2)
+	require.NoError(t, err)
+	require.Equal(t, int64(7277), converted)
+
+	// Inverse rates are rounded before they are applied.
+	quote, err = NewQuote(context.Background(), provider, "USD", "CAD", new(big.Rat))
+	require.NoError(t, err)
+	require.Equal(t, "1.3603591348", quote.RateString())
+	converted, err = quote.ConvertMinorUnits(100, 2, 2)
+	require.NoError(t, err)
+	require.Equal(t, int64(136), converted)
+
+	// 1.00 CAD is 110 JPY, which has no minor unit, and back.
+	jpy, err := NewStaticRateProvider(map[string]string{"CAD/JPY": "110"})
+	require.NoError(t, err)
+
+	quote, err = NewQuote(context.Background(), jpy, "CAD", "JPY", new(big.Rat))
+	require.NoError(t, err)
+	converted, err = quote.ConvertMinorUnits(100, 2, 0)
+	require.NoError(t, err)
+	require.Equal(t, int64(110), converted)
+
+	// Results that do not fit in an int64 are refused rather than wrapped.
+	_, err = quote.ConvertMinorUnits(math.MaxInt64/10, 0, 0)
+	require.ErrorIs(t, err, ErrAmountTooLarge)
+
+	quote, err = NewQuote(context.Background(), jpy, "JPY", "CAD", new(big.Rat))
+	require.NoError(t, err)
+	converted, err = quote.ConvertMinorUnits(110, 0, 2)
+	require.NoError(t, err)
+	require.Equal(t, int64(100), converted)
+
+	_, err = NewQuote(context.Background(), provider, "CAD", "USD", big.NewRat(1, 1))
+	require.ErrorIs(t, err, ErrInvalidSpread)
+
+	_, err = NewQuote(context.Background(), provider, "CAD", "EUR", new(big.Rat))
+	require.ErrorIs(t, err, ErrRateNotFound)
+}
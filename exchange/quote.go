@@ -0,0 +1,82 @@
+package exchange
+
+import (
+	"context"
+	"errors"
+	"math/big"
+)
+
+// rateDecimals is the precision rates are rounded to before they are
+// applied, so the rate stored with a transfer is exactly the one used.
+const rateDecimals = 10
+
+var (
+	ErrInvalidSpread  = errors.New("spread must be at least 0 and less than 1")
+	ErrAmountTooLarge = errors.New("converted amount is too large")
+)
+
+// Quote converts amounts, in minor units, at Rate and keeps Spread, a
+// fraction of the converted amount, as a fee.
+type Quote struct {
+	Rate   *big.Rat
+	Spread *big.Rat
+}
+
+// NewQuote asks provider for the rate from one currency to another and
+// applies spread to it.
+func NewQuote(ctx context.Context, provider RateProvider, from, to string, spread *big.Rat) (Quote, error) {
+	if spread.Sign() < 0 || spread.Cmp(big.NewRat(1, 1)) >= 0 {
+		return Quote{}, ErrInvalidSpread
+	}
+
+	rate, err := provider.Rate(ctx, from, to)
+	if err != nil {
+		return Quote{}, err
+	}
+
+	rate, _ = new(big.Rat).SetString(rate.FloatString(rateDecimals))
+	return Quote{Rate: rate, Spread: new(big.Rat).Set(spread)}, nil
+}
+
+// ConvertMinorUnits returns what amount is worth once converted and the
+// spread is taken, rounded down to a whole minor unit. The exponents give
+// the minor units of each currency, e.g. 2 for CAD cents and 0 for JPY. It
+// returns ErrAmountTooLarge if the result does not fit in an int64.
+func (q Quote) ConvertMinorUnits(amount int64, fromExponent, toExponent int32) (int64, error) {
+	kept := new(big.Rat).Sub(big.NewRat(1, 1), q.Spread)
+
+	v := new(big.Rat).SetInt64(amount)
+	v.Mul(v, q.Rate)
+	v.Mul(v, kept)
+
+	scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(abs32(toExponent-fromExponent))), nil)
+	if toExponent >= fromExponent {
+		v.Mul(v, new(big.Rat).SetInt(scale))
+	} else {
+		v.Quo(v, new(big.Rat).SetInt(scale))
+	}
+
+	converted := new(big.Int).Quo(v.Num(), v.Denom())
+	if !converted.IsInt64() {
+		return 0, ErrAmountTooLarge
+	}
+
+	return converted.Int64(), nil
+}
+
+// RateString formats the rate as stored with a transfer.
+func (q Quote) RateString() string {
+	return q.Rate.FloatString(rateDecimals)
+}
+
+// SpreadString formats the spread as stored with a transfer.
+func (q Quote) SpreadString() string {
+	return q.Spread.FloatString(rateDecimals)
+}
+
+func abs32(n int32) int32 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,87 @@
+package exchange
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+)
+
+// StaticRateProvider serves a fixed set of rates keyed by currency pair,
+// such as "CAD/USD". A pair that is missing is served as the inverse of the
+// opposite pair when that one is known.
+type StaticRateProvider struct {
+	rates map[string]*big.Rat
+}
+
+func NewStaticRateProvider(rates map[string]string) (*StaticRateProvider, error) {
+	p := &StaticRateProvider{rates: make(map[string]*big.Rat, len(rates))}
+
+	for pair, s := range rates {
+		parts := strings.Split(pair, "/")
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("invalid currency pair %q", pair)
+		}
+
+		r, err := ParseRate(s)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", pair, err)
+		}
+		if r.Sign() == 0 {
+			return nil, fmt.Errorf("%s: rate must be positive", pair)
+		}
+
+		p.rates[pair] = r
+	}
+
+	return p, nil
+}
+
+func (p *StaticRateProvider) Rate(ctx context.Context, from, to string) (*big.Rat, error) {
+	if from == to {
+		return big.NewRat(1, 1), nil
+	}
+
+	if r, ok := p.rates[pairKey(from, to)]; ok {
+		return new(big.Rat).Set(r), nil
+	}
+
+	if r, ok := p.rates[pairKey(to, from)]; ok {
+		return new(big.Rat).Inv(r), nil
+	}
+
+	return nil, fmt.Errorf("%w: %s to %s", ErrRateNotFound, from, to)
+}
+
+// FileRateProvider reads rates from a JSON file of currency pairs to
+// decimal strings, e.g. {"CAD/USD": "0.7350"}, every time a rate is asked
+// for, so the file can be updated without a restart. Wrap it in a
+// CachedRateProvider to avoid reading the file for every transfer.
+type FileRateProvider struct {
+	path string
+}
+
+func NewFileRateProvider(path string) *FileRateProvider {
+	return &FileRateProvider{path: path}
+}
+
+func (p *FileRateProvider) Rate(ctx context.Context, from, to string) (*big.Rat, error) {
+	data, err := os.ReadFile(p.path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read rates file: %w", err)
+	}
+
+	var rates map[string]string
+	if err = json.Unmarshal(data, &rates); err != nil {
+		return nil, fmt.Errorf("cannot parse rates file: %w", err)
+	}
+
+	static, err := NewStaticRateProvider(rates)
+	if err != nil {
+		return nil, err
+	}
+
+	return static.Rate(ctx, from, to)
+}
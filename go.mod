@@ -22,6 +22,7 @@ require (
 	github.com/go-playground/universal-translator v0.17.0 // indirect
 	github.com/go-playground/validator/v10 v10.4.1 // indirect
 	github.com/golang-jwt/jwt v3.2.2+incompatible
+	github.com/golang/mock v1.6.0
 	github.com/golang/protobuf v1.3.3 // indirect
 	github.com/google/uuid v1.3.0
 	github.com/json-iterator/go v1.1.9 // indirect
@@ -0,0 +1,31 @@
+package mail
+
+import (
+	"context"
+	"io"
+	"sync"
+)
+
+// LogSender writes messages to w instead of delivering them, for local
+// development.
+type LogSender struct {
+	mu   sync.Mutex
+	from string
+	w    io.Writer
+}
+
+func NewLogSender(w io.Writer, from string) Sender {
+	return &LogSender{w: w, from: from}
+}
+
+func (s *LogSender) Send(ctx context.Context, msg Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.w.Write(formatMessage(s.from, msg)); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(s.w, "\r\n\r\n")
+	return err
+}
@@ -0,0 +1,59 @@
+package mail
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogSender(t *testing.T) {
+	var buf bytes.Buffer
+	sender := NewLogSender(&buf, "no-reply@simplebank.test")
+
+	err := sender.Send(context.Background(), Message{
+		To:      []string{"alice@example.com"},
+		Subject: "Hello",
+		Body:    "Reset your password",
+	})
+	require.NoError(t, err)
+
+	out := buf.String()
+	require.Contains(t, out, "From: no-reply@simplebank.test\r\n")
+	require.Contains(t, out, "To: alice@example.com\r\n")
+	require.Contains(t, out, "Subject: Hello\r\n")
+	require.Contains(t, out, "\r\n\r\nReset your password")
+}
+
+func TestMemorySender(t *testing.T) {
+	sender := NewMemorySender()
+	require.Empty(t, sender.Messages())
+
+	msg := Message{
+		To:      []string{"alice@example.com"},
+		Subject: "Hello",
+		Body:    "Verify your email",
+	}
+
+	err := sender.Send(context.Background(), msg)
+	require.NoError(t, err)
+
+	messages := sender.Messages()
+	require.Len(t, messages, 1)
+	require.Equal(t, msg, messages[0])
+}
+
+func TestNewSMTPSender(t *testing.T) {
+	sender, err := NewSMTPSender("smtp.example.com:587", "user", "pass", "no-reply@simplebank.test")
+	require.NoError(t, err)
+	require.NotNil(t, sender)
+
+	sender, err = NewSMTPSender("smtp.example.com", "user", "pass", "no-reply@simplebank.test")
+	require.Error(t, err)
+	require.Nil(t, sender)
+
+	sender, err = NewSMTPSender("smtp.example.com:587", "", "", "")
+	require.Error(t, err)
+	require.Nil(t, sender)
+}
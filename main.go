@@ -1,13 +1,24 @@
 package main
 
 import (
+	"context"
+	"crypto/ed25519"
 	"database/sql"
+	"fmt"
 	"log"
 	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
 
 	"github.com/ferueda/simplebank-go/api"
 	db "github.com/ferueda/simplebank-go/db/sqlc"
+	"github.com/ferueda/simplebank-go/exchange"
+	"github.com/ferueda/simplebank-go/mail"
+	"github.com/ferueda/simplebank-go/scheduler"
 	"github.com/ferueda/simplebank-go/token"
+	"github.com/golang-jwt/jwt"
 	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
 )
@@ -15,7 +26,43 @@ import (
 var dbAddr string
 var appAddr string
 var dbDriver string
+var tokenType string
 var tokenKey string
+var tokenPrivateKeyFile string
+var tokenPublicKeyFile string
+var tokenKeyringFile string
+var tokenIssuer string
+var tokenAudience string
+var tokenClockSkew time.Duration
+var accessTokenDuration time.Duration
+var refreshTokenDuration time.Duration
+var mfaChallengeDuration time.Duration
+var totpEncryptionKey string
+var totpIssuer string
+var loginMaxAttempts int32
+var loginMaxAttemptsPerIP int32
+var loginBackoffBase time.Duration
+var loginLockoutDuration time.Duration
+var passwordResetTokenDuration time.Duration
+var passwordResetURL string
+var emailVerificationTokenDuration time.Duration
+var emailVerificationURL string
+var transferRequiresVerifiedEmail bool
+var frozenAccountsAcceptCredits bool
+var idempotencyKeyDuration time.Duration
+var exchangeRatesFile string
+var exchangeRateCacheDuration time.Duration
+var exchangeSpread string
+var holdDuration time.Duration
+var schedulerInterval time.Duration
+var scheduledTransferRetryDelay time.Duration
+var scheduledTransferMaxFailures int32
+var mailSender string
+var mailFrom string
+var mailLogFile string
+var smtpAddr string
+var smtpUsername string
+var smtpPassword string
 
 func init() {
 	env := os.Getenv("ENV")
@@ -29,7 +76,88 @@ func init() {
 	dbAddr = os.Getenv("DB_HOST")
 	appAddr = os.Getenv("APP_HOST")
 	dbDriver = os.Getenv("DB_DRIVER")
+	tokenType = os.Getenv("TOKEN_TYPE")
 	tokenKey = os.Getenv("TOKEN_SYMMETRIC_KEY")
+	tokenPrivateKeyFile = os.Getenv("TOKEN_PRIVATE_KEY_FILE")
+	tokenPublicKeyFile = os.Getenv("TOKEN_PUBLIC_KEY_FILE")
+	tokenKeyringFile = os.Getenv("TOKEN_KEYRING_FILE")
+	tokenIssuer = os.Getenv("TOKEN_ISSUER")
+	tokenAudience = os.Getenv("TOKEN_AUDIENCE")
+	tokenClockSkew = durationEnv("TOKEN_CLOCK_SKEW", 0)
+	accessTokenDuration = durationEnv("ACCESS_TOKEN_DURATION", time.Hour)
+	refreshTokenDuration = durationEnv("REFRESH_TOKEN_DURATION", time.Hour*24)
+	mfaChallengeDuration = durationEnv("MFA_CHALLENGE_DURATION", time.Minute*5)
+	totpEncryptionKey = os.Getenv("TOTP_ENCRYPTION_KEY")
+	totpIssuer = os.Getenv("TOTP_ISSUER")
+	if totpIssuer == "" {
+		totpIssuer = "SimpleBank"
+	}
+	loginMaxAttempts = intEnv("LOGIN_MAX_ATTEMPTS", 5)
+	loginMaxAttemptsPerIP = intEnv("LOGIN_MAX_ATTEMPTS_PER_IP", 20)
+	loginBackoffBase = durationEnv("LOGIN_BACKOFF_BASE", time.Second)
+	loginLockoutDuration = durationEnv("LOGIN_LOCKOUT_DURATION", time.Minute*15)
+	passwordResetTokenDuration = durationEnv("PASSWORD_RESET_TOKEN_DURATION", time.Hour)
+	passwordResetURL = os.Getenv("PASSWORD_RESET_URL")
+	emailVerificationTokenDuration = durationEnv("EMAIL_VERIFICATION_TOKEN_DURATION", time.Hour*24)
+	emailVerificationURL = os.Getenv("EMAIL_VERIFICATION_URL")
+	transferRequiresVerifiedEmail = boolEnv("TRANSFER_REQUIRES_VERIFIED_EMAIL", false)
+	frozenAccountsAcceptCredits = boolEnv("FROZEN_ACCOUNTS_ACCEPT_CREDITS", false)
+	idempotencyKeyDuration = durationEnv("IDEMPOTENCY_KEY_DURATION", time.Hour*24)
+	exchangeRatesFile = os.Getenv("EXCHANGE_RATES_FILE")
+	exchangeRateCacheDuration = durationEnv("EXCHANGE_RATE_CACHE_DURATION", time.Minute)
+	exchangeSpread = os.Getenv("EXCHANGE_SPREAD")
+	holdDuration = durationEnv("HOLD_DURATION", time.Hour*24*7)
+	schedulerInterval = durationEnv("SCHEDULER_INTERVAL", time.Minute)
+	scheduledTransferRetryDelay = durationEnv("SCHEDULED_TRANSFER_RETRY_DELAY", time.Hour)
+	scheduledTransferMaxFailures = intEnv("SCHEDULED_TRANSFER_MAX_FAILURES", 3)
+	mailSender = os.Getenv("MAIL_SENDER")
+	mailFrom = os.Getenv("MAIL_FROM")
+	mailLogFile = os.Getenv("MAIL_LOG_FILE")
+	smtpAddr = os.Getenv("SMTP_ADDR")
+	smtpUsername = os.Getenv("SMTP_USERNAME")
+	smtpPassword = os.Getenv("SMTP_PASSWORD")
+}
+
+func intEnv(key string, def int32) int32 {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+
+	n, err := strconv.ParseInt(v, 10, 32)
+	if err != nil {
+		log.Fatalf("invalid integer for %s: %v", key, err)
+	}
+
+	return int32(n)
+}
+
+func boolEnv(key string, def bool) bool {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		log.Fatalf("invalid boolean for %s: %v", key, err)
+	}
+
+	return b
+}
+
+func durationEnv(key string, def time.Duration) time.Duration {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		log.Fatalf("invalid duration for %s: %v", key, err)
+	}
+
+	return d
 }
 
 func main() {
@@ -38,18 +166,222 @@ func main() {
 		log.Fatal("cannot connect to db:", err)
 	}
 
-	tm, err := token.NewPasetoMaker(tokenKey)
+	tm, err := newTokenMaker()
 	if err != nil {
 		log.Fatal("cannot create token maker: %w", err)
 	}
 
 	store := db.NewStore(conn)
-	server, err := api.NewServer(store, tm)
+	config := api.Config{
+		AccessTokenDuration:  accessTokenDuration,
+		RefreshTokenDuration: refreshTokenDuration,
+		MFAChallengeDuration: mfaChallengeDuration,
+		TOTPEncryptionKey:    totpEncryptionKey,
+		TOTPIssuer:           totpIssuer,
+
+		LoginMaxAttempts:      loginMaxAttempts,
+		LoginMaxAttemptsPerIP: loginMaxAttemptsPerIP,
+		LoginBackoffBase:      loginBackoffBase,
+		LoginLockoutDuration:  loginLockoutDuration,
+
+		PasswordResetTokenDuration: passwordResetTokenDuration,
+		PasswordResetURL:           passwordResetURL,
+
+		EmailVerificationTokenDuration: emailVerificationTokenDuration,
+		EmailVerificationURL:           emailVerificationURL,
+		TransferRequiresVerifiedEmail:  transferRequiresVerifiedEmail,
+		FrozenAccountsAcceptCredits:    frozenAccountsAcceptCredits,
+		IdempotencyKeyDuration:         idempotencyKeyDuration,
+		ExchangeSpread:                 exchangeSpread,
+		HoldDuration:                   holdDuration,
+	}
+
+	mailer, err := newMailSender()
+	if err != nil {
+		log.Fatal("cannot create mail sender: ", err)
+	}
+
+	rates, err := newRateProvider()
+	if err != nil {
+		log.Fatal("cannot create rate provider: ", err)
+	}
+
+	server, err := api.NewServer(config, store, tm, mailer, rates)
 	if err != nil {
 		log.Fatal("cannot create server: %w", err)
 	}
 
+	// A zero interval leaves scheduled transfers and expired holds to other
+	// instances.
+	if schedulerInterval > 0 {
+		sched := scheduler.New(store, scheduler.Config{
+			Interval:             schedulerInterval,
+			RetryDelay:           scheduledTransferRetryDelay,
+			MaxFailures:          scheduledTransferMaxFailures,
+			AllowFrozenRecipient: frozenAccountsAcceptCredits,
+		})
+		go sched.Run(context.Background())
+	}
+
 	if err = server.Start(appAddr); err != nil {
 		log.Fatal("cannot start server: ", err)
 	}
 }
+
+// newMailSender builds the sender selected by MAIL_SENDER. The log sender
+// writes messages to MAIL_LOG_FILE, or stdout, for local development.
+func newMailSender() (mail.Sender, error) {
+	switch mailSender {
+	case "smtp":
+		return mail.NewSMTPSender(smtpAddr, smtpUsername, smtpPassword, mailFrom)
+	case "", "log":
+		if mailLogFile == "" {
+			return mail.NewLogSender(os.Stdout, mailFrom), nil
+		}
+
+		f, err := os.OpenFile(mailLogFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+		if err != nil {
+			return nil, err
+		}
+		return mail.NewLogSender(f, mailFrom), nil
+	default:
+		return nil, fmt.Errorf("unsupported mail sender %s", mailSender)
+	}
+}
+
+// newRateProvider reads exchange rates from EXCHANGE_RATES_FILE, caching
+// them for EXCHANGE_RATE_CACHE_DURATION. Without a file no rates are known
+// and cross-currency transfers are refused.
+func newRateProvider() (exchange.RateProvider, error) {
+	if exchangeRatesFile == "" {
+		return exchange.NewStaticRateProvider(nil)
+	}
+
+	return exchange.NewCachedRateProvider(exchange.NewFileRateProvider(exchangeRatesFile), exchangeRateCacheDuration), nil
+}
+
+func tokenOptions() []token.Option {
+	return []token.Option{
+		token.WithIssuer(tokenIssuer),
+		token.WithAudience(tokenAudience),
+		token.WithClockSkew(tokenClockSkew),
+	}
+}
+
+// newTokenMaker builds the token maker selected by TOKEN_TYPE. Asymmetric
+// makers read a PEM private key, or only a public key to verify tokens.
+func newTokenMaker() (token.Maker, error) {
+	switch tokenType {
+	case "", "paseto":
+		return token.NewPasetoMaker(tokenKey, tokenOptions()...)
+	case "jwt":
+		return token.NewJWTMaker(tokenKey, tokenOptions()...)
+	case "paseto_public", "jwt_eddsa":
+		return newEd25519TokenMaker()
+	case "jwt_rs256":
+		return newRS256TokenMaker()
+	case "paseto_keyring", "jwt_keyring":
+		return newKeyringTokenMaker()
+	default:
+		return nil, fmt.Errorf("unsupported token type %s", tokenType)
+	}
+}
+
+func newEd25519TokenMaker() (token.Maker, error) {
+	if tokenPrivateKeyFile == "" {
+		pem, err := os.ReadFile(tokenPublicKeyFile)
+		if err != nil {
+			return nil, err
+		}
+
+		key, err := jwt.ParseEdPublicKeyFromPEM(pem)
+		if err != nil {
+			return nil, err
+		}
+
+		publicKey, ok := key.(ed25519.PublicKey)
+		if !ok {
+			return nil, fmt.Errorf("public key is not an Ed25519 key")
+		}
+
+		if tokenType == "paseto_public" {
+			return token.NewPasetoPublicVerifier(publicKey, tokenOptions()...)
+		}
+		return token.NewJWTEdDSAVerifier(publicKey, tokenOptions()...)
+	}
+
+	pem, err := os.ReadFile(tokenPrivateKeyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := jwt.ParseEdPrivateKeyFromPEM(pem)
+	if err != nil {
+		return nil, err
+	}
+
+	privateKey, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("private key is not an Ed25519 key")
+	}
+
+	if tokenType == "paseto_public" {
+		return token.NewPasetoPublicMaker(privateKey, tokenOptions()...)
+	}
+	return token.NewJWTEdDSAMaker(privateKey, tokenOptions()...)
+}
+
+func newRS256TokenMaker() (token.Maker, error) {
+	if tokenPrivateKeyFile == "" {
+		pem, err := os.ReadFile(tokenPublicKeyFile)
+		if err != nil {
+			return nil, err
+		}
+
+		publicKey, err := jwt.ParseRSAPublicKeyFromPEM(pem)
+		if err != nil {
+			return nil, err
+		}
+
+		return token.NewJWTRS256Verifier(publicKey, tokenOptions()...)
+	}
+
+	pem, err := os.ReadFile(tokenPrivateKeyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	privateKey, err := jwt.ParseRSAPrivateKeyFromPEM(pem)
+	if err != nil {
+		return nil, err
+	}
+
+	return token.NewJWTRS256Maker(privateKey, tokenOptions()...)
+}
+
+// newKeyringTokenMaker loads TOKEN_KEYRING_FILE and reloads it whenever the
+// process receives SIGHUP, so keys can be rotated without a restart.
+func newKeyringTokenMaker() (token.Maker, error) {
+	keyring, err := token.LoadKeyringFile(tokenKeyringFile)
+	if err != nil {
+		return nil, err
+	}
+
+	go func() {
+		sig := make(chan os.Signal, 1)
+		signal.Notify(sig, syscall.SIGHUP)
+
+		for range sig {
+			if err := keyring.ReloadFile(tokenKeyringFile); err != nil {
+				log.Println("cannot reload token keyring:", err)
+				continue
+			}
+			log.Println("token keyring reloaded")
+		}
+	}()
+
+	if tokenType == "paseto_keyring" {
+		return token.NewKeyringPasetoMaker(keyring, tokenOptions()...)
+	}
+	return token.NewKeyringJWTMaker(keyring, tokenOptions()...)
+}
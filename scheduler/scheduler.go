@@ -0,0 +1,118 @@
+package scheduler
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	db "github.com/ferueda/simplebank-go/db/sqlc"
+)
+
+type Config struct {
+	// Interval is how often due transfers and expired holds are looked for.
+	Interval time.Duration
+	// RetryDelay is how long to wait before retrying a run that failed for
+	// lack of funds or for an unexpected error.
+	RetryDelay time.Duration
+	// MaxFailures is how many runs in a row may fail for lack of funds or
+	// for an unexpected error before a scheduled transfer is paused.
+	MaxFailures int32
+	// AllowFrozenRecipient lets scheduled transfers credit frozen accounts.
+	AllowFrozenRecipient bool
+}
+
+// Scheduler runs scheduled transfers as they fall due and releases the holds
+// of pending transfers that expired. Several servers can run one against the
+// same database: each row is locked by the first that picks it and skipped
+// by the others.
+type Scheduler struct {
+	store  db.Store
+	config Config
+}
+
+func New(store db.Store, config Config) *Scheduler {
+	return &Scheduler{store: store, config: config}
+}
+
+// Run runs due transfers and expires holds every Interval until ctx is done.
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := s.RunDue(ctx); err != nil {
+			log.Printf("cannot run scheduled transfers: %v", err)
+		}
+
+		if _, err := s.ExpireHolds(ctx); err != nil {
+			log.Printf("cannot expire holds: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunDue runs every transfer that is due, one transaction each, and
+// returns how many it ran, successfully or not. A transfer that fails is
+// recorded as a failed run and retried later or paused, so it does not hold
+// up the others. RunDue only stops early if a failure cannot be recorded.
+func (s *Scheduler) RunDue(ctx context.Context) (int, error) {
+	n := 0
+	for {
+		arg := db.RunDueScheduledTransferTxParams{
+			Now:                  time.Now(),
+			RetryDelay:           s.config.RetryDelay,
+			MaxFailures:          s.config.MaxFailures,
+			AllowFrozenRecipient: s.config.AllowFrozenRecipient,
+		}
+
+		run, err := s.store.RunDueScheduledTransferTx(ctx, arg)
+		if err == sql.ErrNoRows {
+			return n, nil
+		}
+
+		var failed *db.ScheduledTransferError
+		if errors.As(err, &failed) {
+			run, err = s.store.FailScheduledTransferTx(ctx, db.FailScheduledTransferTxParams{
+				RunDueScheduledTransferTxParams: arg,
+				Scheduled:                       failed.Scheduled,
+				Err:                             failed.Err,
+			})
+			if err == sql.ErrNoRows {
+				// Another worker got to it first.
+				continue
+			}
+		}
+		if err != nil {
+			return n, err
+		}
+
+		n++
+		if run.Status == db.ScheduledTransferRunFailed {
+			log.Printf("scheduled transfer %d failed: %s", run.ScheduledTransferID, run.Error)
+		}
+	}
+}
+
+// ExpireHolds releases the hold of every pending transfer that has expired,
+// one transaction each, and returns how many it released.
+func (s *Scheduler) ExpireHolds(ctx context.Context) (int, error) {
+	n := 0
+	for {
+		_, err := s.store.ExpirePendingTransferTx(ctx, time.Now())
+		if err == sql.ErrNoRows {
+			return n, nil
+		}
+		if err != nil {
+			return n, err
+		}
+
+		n++
+	}
+}
@@ -0,0 +1,103 @@
+package scheduler
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	mockdb "github.com/ferueda/simplebank-go/db/mock"
+	db "github.com/ferueda/simplebank-go/db/sqlc"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunDue(t *testing.T) {
+	broken := db.ScheduledTransfer{ID: 1, Status: db.ScheduledTransferStatusActive}
+	runErr := errors.New("connection reset")
+
+	testCases := []struct {
+		name       string
+		buildStubs func(store *mockdb.MockStore)
+		ran        int
+		err        bool
+	}{
+		{
+			// The failing transfer is recorded and the next one still runs.
+			name: "ContinuesPastFailure",
+			buildStubs: func(store *mockdb.MockStore) {
+				gomock.InOrder(
+					store.EXPECT().RunDueScheduledTransferTx(gomock.Any(), gomock.Any()).Times(1).
+						Return(db.ScheduledTransferRun{}, &db.ScheduledTransferError{Scheduled: broken, Err: runErr}),
+					store.EXPECT().FailScheduledTransferTx(gomock.Any(), gomock.Any()).Times(1).
+						DoAndReturn(func(_ context.Context, arg db.FailScheduledTransferTxParams) (db.ScheduledTransferRun, error) {
+							require.Equal(t, broken, arg.Scheduled)
+							require.Equal(t, runErr, arg.Err)
+							require.Equal(t, 3*time.Minute, arg.RetryDelay)
+							return db.ScheduledTransferRun{ScheduledTransferID: broken.ID, Status: db.ScheduledTransferRunFailed}, nil
+						}),
+					store.EXPECT().RunDueScheduledTransferTx(gomock.Any(), gomock.Any()).Times(1).
+						Return(db.ScheduledTransferRun{ScheduledTransferID: 2, Status: db.ScheduledTransferRunSucceeded}, nil),
+					store.EXPECT().RunDueScheduledTransferTx(gomock.Any(), gomock.Any()).Times(1).
+						Return(db.ScheduledTransferRun{}, sql.ErrNoRows),
+				)
+			},
+			ran: 2,
+		},
+		{
+			name: "HandledByAnotherWorker",
+			buildStubs: func(store *mockdb.MockStore) {
+				gomock.InOrder(
+					store.EXPECT().RunDueScheduledTransferTx(gomock.Any(), gomock.Any()).Times(1).
+						Return(db.ScheduledTransferRun{}, &db.ScheduledTransferError{Scheduled: broken, Err: runErr}),
+					store.EXPECT().FailScheduledTransferTx(gomock.Any(), gomock.Any()).Times(1).
+						Return(db.ScheduledTransferRun{}, sql.ErrNoRows),
+					store.EXPECT().RunDueScheduledTransferTx(gomock.Any(), gomock.Any()).Times(1).
+						Return(db.ScheduledTransferRun{}, sql.ErrNoRows),
+				)
+			},
+			ran: 0,
+		},
+		{
+			name: "CannotRecordFailure",
+			buildStubs: func(store *mockdb.MockStore) {
+				gomock.InOrder(
+					store.EXPECT().RunDueScheduledTransferTx(gomock.Any(), gomock.Any()).Times(1).
+						Return(db.ScheduledTransferRun{}, &db.ScheduledTransferError{Scheduled: broken, Err: runErr}),
+					store.EXPECT().FailScheduledTransferTx(gomock.Any(), gomock.Any()).Times(1).
+						Return(db.ScheduledTransferRun{}, sql.ErrConnDone),
+				)
+			},
+			err: true,
+		},
+		{
+			name: "CannotFindDueTransfers",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().RunDueScheduledTransferTx(gomock.Any(), gomock.Any()).Times(1).
+					Return(db.ScheduledTransferRun{}, sql.ErrConnDone)
+				store.EXPECT().FailScheduledTransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			err: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			s := New(store, Config{RetryDelay: 3 * time.Minute, MaxFailures: 3})
+			ran, err := s.RunDue(context.Background())
+			if tc.err {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+			require.Equal(t, tc.ran, ran)
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package token
+
+import (
+	"time"
+)
+
+// Config holds the registered claims a maker stamps on new tokens and
+// enforces when verifying them.
+type Config struct {
+	Issuer    string
+	Audience  string
+	ClockSkew time.Duration
+}
+
+type Option func(*Config)
+
+// WithIssuer sets the issuer of new tokens and rejects tokens from any other
+// issuer.
+func WithIssuer(issuer string) Option {
+	return func(c *Config) {
+		c.Issuer = issuer
+	}
+}
+
+// WithAudience sets the audience of new tokens and rejects tokens minted for
+// any other audience.
+func WithAudience(audience string) Option {
+	return func(c *Config) {
+		c.Audience = audience
+	}
+}
+
+// WithClockSkew tolerates clock drift between the issuing and verifying
+// servers when checking expiry and not-before.
+func WithClockSkew(skew time.Duration) Option {
+	return func(c *Config) {
+		c.ClockSkew = skew
+	}
+}
+
+func newConfig(opts []Option) Config {
+	var c Config
+	for _, opt := range opts {
+		opt(&c)
+	}
+	return c
+}
+
+func (c Config) newPayload(username string, role string, tokenType TokenType, duration time.Duration) (*Payload, error) {
+	payload, err := NewPayload(username, role, tokenType, duration)
+	if err != nil {
+		return nil, err
+	}
+
+	payload.Issuer = c.Issuer
+	payload.Audience = c.Audience
+	return payload, nil
+}
+
+func (c Config) validate(p *Payload) error {
+	now := time.Now()
+
+	if now.After(p.ExpiredAt.Add(c.ClockSkew)) {
+		return ErrExpiredToken
+	}
+
+	if now.Add(c.ClockSkew).Before(p.NotBefore) {
+		return ErrTokenNotYetValid
+	}
+
+	if c.Issuer != "" && p.Issuer != c.Issuer {
+		return ErrInvalidIssuer
+	}
+
+	if c.Audience != "" && p.Audience != c.Audience {
+		return ErrInvalidAudience
+	}
+
+	return nil
+}
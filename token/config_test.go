@@ -0,0 +1,151 @@
+package token
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/o1egl/paseto"
+	"github.com/stretchr/testify/require"
+)
+
+type makerFactory struct {
+	name     string
+	newMaker func(key string, opts ...Option) (Maker, error)
+	sign     func(t *testing.T, key string, payload *Payload) string
+}
+
+var makerFactories = []makerFactory{
+	{
+		name:     "Paseto",
+		newMaker: NewPasetoMaker,
+		sign: func(t *testing.T, key string, payload *Payload) string {
+			token, err := paseto.NewV2().Encrypt([]byte(key), payload, nil)
+			require.NoError(t, err)
+			return token
+		},
+	},
+	{
+		name:     "JWT",
+		newMaker: NewJWTMaker,
+		sign: func(t *testing.T, key string, payload *Payload) string {
+			token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, payload).SignedString([]byte(key))
+			require.NoError(t, err)
+			return token
+		},
+	},
+}
+
+func TestMakerRegisteredClaims(t *testing.T) {
+	for _, f := range makerFactories {
+		t.Run(f.name, func(t *testing.T) {
+			maker, err := f.newMaker(randomString(32), WithIssuer("simplebank"), WithAudience("accounts-api"))
+			require.NoError(t, err)
+
+			token, payload, err := maker.CreateToken(randomString(6), randomString(6), TokenTypeAccess, time.Minute)
+			require.NoError(t, err)
+			require.Equal(t, "simplebank", payload.Issuer)
+			require.Equal(t, "accounts-api", payload.Audience)
+			require.Equal(t, payload.IssuedAt, payload.NotBefore)
+
+			payload, err = maker.VerifyToken(token)
+			require.NoError(t, err)
+			require.Equal(t, "simplebank", payload.Issuer)
+			require.Equal(t, "accounts-api", payload.Audience)
+			require.WithinDuration(t, time.Now(), payload.NotBefore, time.Second)
+		})
+	}
+}
+
+func TestMakerRejectsOtherAudience(t *testing.T) {
+	for _, f := range makerFactories {
+		t.Run(f.name, func(t *testing.T) {
+			key := randomString(32)
+
+			issuer, err := f.newMaker(key, WithIssuer("simplebank"), WithAudience("reports-api"))
+			require.NoError(t, err)
+
+			verifier, err := f.newMaker(key, WithIssuer("simplebank"), WithAudience("accounts-api"))
+			require.NoError(t, err)
+
+			token, _, err := issuer.CreateToken(randomString(6), randomString(6), TokenTypeAccess, time.Minute)
+			require.NoError(t, err)
+
+			payload, err := verifier.VerifyToken(token)
+			require.EqualError(t, err, ErrInvalidAudience.Error())
+			require.Nil(t, payload)
+		})
+	}
+}
+
+func TestMakerRejectsOtherIssuer(t *testing.T) {
+	for _, f := range makerFactories {
+		t.Run(f.name, func(t *testing.T) {
+			key := randomString(32)
+
+			issuer, err := f.newMaker(key, WithIssuer("other"))
+			require.NoError(t, err)
+
+			verifier, err := f.newMaker(key, WithIssuer("simplebank"))
+			require.NoError(t, err)
+
+			token, _, err := issuer.CreateToken(randomString(6), randomString(6), TokenTypeAccess, time.Minute)
+			require.NoError(t, err)
+
+			payload, err := verifier.VerifyToken(token)
+			require.EqualError(t, err, ErrInvalidIssuer.Error())
+			require.Nil(t, payload)
+		})
+	}
+}
+
+func TestMakerNotBefore(t *testing.T) {
+	for _, f := range makerFactories {
+		t.Run(f.name, func(t *testing.T) {
+			key := randomString(32)
+
+			payload, err := NewPayload(randomString(6), randomString(6), TokenTypeAccess, time.Hour)
+			require.NoError(t, err)
+			payload.NotBefore = time.Now().Add(time.Minute)
+			token := f.sign(t, key, payload)
+
+			maker, err := f.newMaker(key)
+			require.NoError(t, err)
+
+			verified, err := maker.VerifyToken(token)
+			require.EqualError(t, err, ErrTokenNotYetValid.Error())
+			require.Nil(t, verified)
+
+			maker, err = f.newMaker(key, WithClockSkew(2*time.Minute))
+			require.NoError(t, err)
+
+			verified, err = maker.VerifyToken(token)
+			require.NoError(t, err)
+			require.Equal(t, payload.Username, verified.Username)
+		})
+	}
+}
+
+func TestMakerClockSkewOnExpiry(t *testing.T) {
+	for _, f := range makerFactories {
+		t.Run(f.name, func(t *testing.T) {
+			key := randomString(32)
+
+			maker, err := f.newMaker(key, WithClockSkew(time.Minute))
+			require.NoError(t, err)
+
+			token, _, err := maker.CreateToken(randomString(6), randomString(6), TokenTypeAccess, -30*time.Second)
+			require.NoError(t, err)
+
+			_, err = maker.VerifyToken(token)
+			require.NoError(t, err)
+
+			token, _, err = maker.CreateToken(randomString(6), randomString(6), TokenTypeAccess, -2*time.Minute)
+			require.NoError(t, err)
+
+			payload, err := maker.VerifyToken(token)
+			require.EqualError(t, err, ErrExpiredToken.Error())
+			require.Nil(t, payload)
+		})
+	}
+}
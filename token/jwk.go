@@ -0,0 +1,85 @@
+package token
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+)
+
+// JWK is a JSON Web Key (RFC 7517) describing a public verification key.
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+}
+
+// JWKSet is the document served to clients that verify our tokens.
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// PublicKeyProvider is implemented by makers that verify tokens with a public
+// key, so that other services can fetch it instead of sharing a secret.
+type PublicKeyProvider interface {
+	PublicJWKs() []JWK
+}
+
+func newJWK(publicKey crypto.PublicKey, alg string) JWK {
+	var jwk JWK
+
+	switch key := publicKey.(type) {
+	case ed25519.PublicKey:
+		jwk = JWK{
+			Kty: "OKP",
+			Crv: "Ed25519",
+			X:   base64.RawURLEncoding.EncodeToString(key),
+		}
+	case *rsa.PublicKey:
+		jwk = JWK{
+			Kty: "RSA",
+			N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}
+	}
+
+	jwk.Kid = jwkThumbprint(jwk)
+	jwk.Use = "sig"
+	jwk.Alg = alg
+	return jwk
+}
+
+// jwkThumbprint computes the RFC 7638 thumbprint of a key, which is used as
+// its key id.
+func jwkThumbprint(jwk JWK) string {
+	var members interface{}
+
+	switch jwk.Kty {
+	case "OKP":
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+		}{jwk.Crv, jwk.Kty, jwk.X}
+	case "RSA":
+		members = struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{jwk.E, jwk.Kty, jwk.N}
+	default:
+		return ""
+	}
+
+	data, _ := json.Marshal(members)
+	sum := sha256.Sum256(data)
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
@@ -0,0 +1,72 @@
+package token
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJWKThumbprint(t *testing.T) {
+	// Test vector from RFC 8037, appendix A.3.
+	x, err := base64.RawURLEncoding.DecodeString("11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo")
+	require.NoError(t, err)
+
+	jwk := newJWK(ed25519.PublicKey(x), "EdDSA")
+	require.Equal(t, "OKP", jwk.Kty)
+	require.Equal(t, "Ed25519", jwk.Crv)
+	require.Equal(t, "kPrK_qmxVWaYVA9wwBF6Iuo3vVzz7TxHCTwXBygrS4k", jwk.Kid)
+}
+
+func TestJWTEdDSAMakerPublicJWKs(t *testing.T) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	maker, err := NewJWTEdDSAMaker(privateKey)
+	require.NoError(t, err)
+
+	provider, ok := maker.(PublicKeyProvider)
+	require.True(t, ok)
+
+	jwks := provider.PublicJWKs()
+	require.Len(t, jwks, 1)
+	require.Equal(t, "EdDSA", jwks[0].Alg)
+	require.Equal(t, "sig", jwks[0].Use)
+	require.Equal(t, base64.RawURLEncoding.EncodeToString(publicKey), jwks[0].X)
+
+	token, _, err := maker.CreateToken(randomString(6), randomString(6), TokenTypeAccess, time.Minute)
+	require.NoError(t, err)
+
+	jwtToken, _, err := new(jwt.Parser).ParseUnverified(token, &Payload{})
+	require.NoError(t, err)
+	require.Equal(t, jwks[0].Kid, jwtToken.Header["kid"])
+}
+
+func TestJWTRS256MakerPublicJWKs(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, minRSAKeyBits)
+	require.NoError(t, err)
+
+	verifier, err := NewJWTRS256Verifier(&privateKey.PublicKey)
+	require.NoError(t, err)
+
+	jwks := verifier.(PublicKeyProvider).PublicJWKs()
+	require.Len(t, jwks, 1)
+	require.Equal(t, "RSA", jwks[0].Kty)
+	require.Equal(t, "RS256", jwks[0].Alg)
+	require.Equal(t, "AQAB", jwks[0].E)
+	require.NotEmpty(t, jwks[0].N)
+	require.NotEmpty(t, jwks[0].Kid)
+}
+
+func TestSymmetricMakersHaveNoPublicJWKs(t *testing.T) {
+	maker, err := NewPasetoMaker(randomString(32))
+	require.NoError(t, err)
+
+	_, ok := maker.(PublicKeyProvider)
+	require.False(t, ok)
+}
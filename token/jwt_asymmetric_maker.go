@@ -0,0 +1,99 @@
+package token
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+const minRSAKeyBits = 2048
+
+// JWTAsymmetricMaker signs JWTs with a private key (EdDSA or RS256) and
+// verifies them with the matching public key. A maker built from a public
+// key alone can only verify tokens.
+type JWTAsymmetricMaker struct {
+	method     jwt.SigningMethod
+	privateKey crypto.PrivateKey
+	publicKey  crypto.PublicKey
+	jwk        JWK
+	config     Config
+}
+
+func NewJWTEdDSAMaker(privateKey ed25519.PrivateKey, opts ...Option) (Maker, error) {
+	if len(privateKey) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("invalid private key size: must be exactly %d bytes", ed25519.PrivateKeySize)
+	}
+
+	return newJWTAsymmetricMaker(jwt.SigningMethodEdDSA, privateKey, privateKey.Public(), newConfig(opts)), nil
+}
+
+func NewJWTEdDSAVerifier(publicKey ed25519.PublicKey, opts ...Option) (Maker, error) {
+	if len(publicKey) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid public key size: must be exactly %d bytes", ed25519.PublicKeySize)
+	}
+
+	return newJWTAsymmetricMaker(jwt.SigningMethodEdDSA, nil, publicKey, newConfig(opts)), nil
+}
+
+func NewJWTRS256Maker(privateKey *rsa.PrivateKey, opts ...Option) (Maker, error) {
+	if privateKey == nil || privateKey.N.BitLen() < minRSAKeyBits {
+		return nil, fmt.Errorf("invalid key size: must be at least %d bits", minRSAKeyBits)
+	}
+
+	return newJWTAsymmetricMaker(jwt.SigningMethodRS256, privateKey, &privateKey.PublicKey, newConfig(opts)), nil
+}
+
+func NewJWTRS256Verifier(publicKey *rsa.PublicKey, opts ...Option) (Maker, error) {
+	if publicKey == nil || publicKey.N.BitLen() < minRSAKeyBits {
+		return nil, fmt.Errorf("invalid key size: must be at least %d bits", minRSAKeyBits)
+	}
+
+	return newJWTAsymmetricMaker(jwt.SigningMethodRS256, nil, publicKey, newConfig(opts)), nil
+}
+
+func newJWTAsymmetricMaker(method jwt.SigningMethod, privateKey crypto.PrivateKey, publicKey crypto.PublicKey, config Config) *JWTAsymmetricMaker {
+	return &JWTAsymmetricMaker{
+		method:     method,
+		privateKey: privateKey,
+		publicKey:  publicKey,
+		jwk:        newJWK(publicKey, method.Alg()),
+		config:     config,
+	}
+}
+
+func (m *JWTAsymmetricMaker) CreateToken(username string, role string, tokenType TokenType, duration time.Duration) (string, *Payload, error) {
+	if m.privateKey == nil {
+		return "", nil, ErrVerifyOnly
+	}
+
+	payload, err := m.config.newPayload(username, role, tokenType, duration)
+	if err != nil {
+		return "", payload, err
+	}
+
+	jwtToken := jwt.NewWithClaims(m.method, payload)
+	jwtToken.Header["kid"] = m.jwk.Kid
+
+	token, err := jwtToken.SignedString(m.privateKey)
+	return token, payload, err
+}
+
+func (m *JWTAsymmetricMaker) VerifyToken(token string) (*Payload, error) {
+	keyFunc := func(token *jwt.Token) (interface{}, error) {
+		if token.Method.Alg() != m.method.Alg() {
+			return nil, ErrInvalidToken
+		}
+
+		return m.publicKey, nil
+	}
+
+	return parseJWT(token, keyFunc, m.config)
+}
+
+func (m *JWTAsymmetricMaker) PublicJWKs() []JWK {
+	return []JWK{m.jwk}
+}
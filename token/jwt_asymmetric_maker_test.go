@@ -0,0 +1,112 @@
+package token
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJWTEdDSAMaker(t *testing.T) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	maker, err := NewJWTEdDSAMaker(privateKey)
+	require.NoError(t, err)
+
+	verifier, err := NewJWTEdDSAVerifier(publicKey)
+	require.NoError(t, err)
+
+	testAsymmetricJWTMaker(t, maker, verifier)
+}
+
+func TestJWTRS256Maker(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, minRSAKeyBits)
+	require.NoError(t, err)
+
+	maker, err := NewJWTRS256Maker(privateKey)
+	require.NoError(t, err)
+
+	verifier, err := NewJWTRS256Verifier(&privateKey.PublicKey)
+	require.NoError(t, err)
+
+	testAsymmetricJWTMaker(t, maker, verifier)
+}
+
+func TestJWTRS256MakerKeyTooSmall(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	require.NoError(t, err)
+
+	maker, err := NewJWTRS256Maker(privateKey)
+	require.Error(t, err)
+	require.Nil(t, maker)
+}
+
+func TestExpiredJWTEdDSAToken(t *testing.T) {
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	maker, err := NewJWTEdDSAMaker(privateKey)
+	require.NoError(t, err)
+
+	token, payload, err := maker.CreateToken(randomString(6), randomString(6), TokenTypeAccess, -time.Minute)
+	require.NoError(t, err)
+	require.NotEmpty(t, token)
+	require.NotEmpty(t, payload)
+
+	payload, err = maker.VerifyToken(token)
+	require.Error(t, err)
+	require.EqualError(t, err, ErrExpiredToken.Error())
+	require.Nil(t, payload)
+}
+
+func TestInvalidJWTEdDSATokenAlgHS256(t *testing.T) {
+	publicKey, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	payload, err := NewPayload(randomString(6), randomString(6), TokenTypeAccess, time.Minute)
+	require.NoError(t, err)
+
+	jwtToken := jwt.NewWithClaims(jwt.SigningMethodHS256, payload)
+	token, err := jwtToken.SignedString([]byte(publicKey))
+	require.NoError(t, err)
+
+	verifier, err := NewJWTEdDSAVerifier(publicKey)
+	require.NoError(t, err)
+
+	payload, err = verifier.VerifyToken(token)
+	require.Error(t, err)
+	require.EqualError(t, err, ErrInvalidToken.Error())
+	require.Nil(t, payload)
+}
+
+func testAsymmetricJWTMaker(t *testing.T, maker, verifier Maker) {
+	username := randomString(6)
+	role := randomString(6)
+	duration := time.Minute
+	issuedAt := time.Now()
+	expiredAt := issuedAt.Add(duration)
+
+	token, payload, err := maker.CreateToken(username, role, TokenTypeAccess, duration)
+	require.NoError(t, err)
+	require.NotEmpty(t, token)
+	require.NotEmpty(t, payload)
+
+	payload, err = verifier.VerifyToken(token)
+	require.NoError(t, err)
+	require.NotEmpty(t, payload)
+	require.Equal(t, username, payload.Username)
+	require.Equal(t, role, payload.Role)
+	require.Equal(t, TokenTypeAccess, payload.Type)
+	require.WithinDuration(t, issuedAt, payload.IssuedAt, time.Second)
+	require.WithinDuration(t, expiredAt, payload.ExpiredAt, time.Second)
+
+	token, payload, err = verifier.CreateToken(username, role, TokenTypeAccess, duration)
+	require.EqualError(t, err, ErrVerifyOnly.Error())
+	require.Empty(t, token)
+	require.Nil(t, payload)
+}
@@ -1,7 +1,6 @@
 package token
 
 import (
-	"errors"
 	"fmt"
 	"time"
 
@@ -12,23 +11,25 @@ const minSecretSize = 32
 
 type JWTMaker struct {
 	secretKey string
+	config    Config
 }
 
-func NewJWTMaker(secretKey string) (Maker, error) {
+func NewJWTMaker(secretKey string, opts ...Option) (Maker, error) {
 	if len(secretKey) < minSecretSize {
 		return nil, fmt.Errorf("invalid key size: must be at least %d characters long", minSecretSize)
 	}
-	return &JWTMaker{secretKey}, nil
+	return &JWTMaker{secretKey, newConfig(opts)}, nil
 }
 
-func (m *JWTMaker) CreateToken(username string, duration time.Duration) (string, error) {
-	payload, err := NewPayload(username, duration)
+func (m *JWTMaker) CreateToken(username string, role string, tokenType TokenType, duration time.Duration) (string, *Payload, error) {
+	payload, err := m.config.newPayload(username, role, tokenType, duration)
 	if err != nil {
-		return "", err
+		return "", payload, err
 	}
 
 	jwtToken := jwt.NewWithClaims(jwt.SigningMethodHS256, payload)
-	return jwtToken.SignedString([]byte(m.secretKey))
+	token, err := jwtToken.SignedString([]byte(m.secretKey))
+	return token, payload, err
 }
 
 func (m *JWTMaker) VerifyToken(token string) (*Payload, error) {
@@ -41,13 +42,16 @@ func (m *JWTMaker) VerifyToken(token string) (*Payload, error) {
 		return []byte(m.secretKey), nil
 	}
 
-	jwtToken, err := jwt.ParseWithClaims(token, &Payload{}, keyFunc)
-	if err != nil {
-		verr, ok := err.(*jwt.ValidationError)
-		if ok && errors.Is(verr.Inner, ErrExpiredToken) {
-			return nil, ErrExpiredToken
-		}
+	return parseJWT(token, keyFunc, m.config)
+}
+
+// parseJWT verifies the token signature with keyFunc and then checks the
+// claims against config, so that clock skew is applied consistently.
+func parseJWT(token string, keyFunc jwt.Keyfunc, config Config) (*Payload, error) {
+	parser := jwt.Parser{SkipClaimsValidation: true}
 
+	jwtToken, err := parser.ParseWithClaims(token, &Payload{}, keyFunc)
+	if err != nil {
 		return nil, ErrInvalidToken
 	}
 
@@ -56,5 +60,10 @@ func (m *JWTMaker) VerifyToken(token string) (*Payload, error) {
 		return nil, ErrInvalidToken
 	}
 
+	err = config.validate(payload)
+	if err != nil {
+		return nil, err
+	}
+
 	return payload, nil
 }
@@ -0,0 +1,151 @@
+package token
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/aead/chacha20poly1305"
+)
+
+// keySize is shared by every keyring maker so that a keyring can back
+// either PASETO v2.local or HS256 JWTs.
+const keySize = chacha20poly1305.KeySize
+
+type KeyStatus string
+
+const (
+	// KeyStatusActive marks the key used to sign new tokens.
+	KeyStatusActive KeyStatus = "active"
+	// KeyStatusVerify marks a key still accepted when verifying tokens.
+	KeyStatusVerify KeyStatus = "verify"
+	// KeyStatusRetired marks a key that is no longer accepted.
+	KeyStatusRetired KeyStatus = "retired"
+)
+
+var ErrUnknownKey = errors.New("token signed with an unknown or retired key")
+
+type Key struct {
+	ID     string    `json:"id"`
+	Secret string    `json:"secret"`
+	Status KeyStatus `json:"status"`
+}
+
+// Keyring holds the symmetric keys known to a keyring maker. Exactly one key
+// is active at a time; it can be swapped at runtime with Reload.
+type Keyring struct {
+	mu     sync.RWMutex
+	active Key
+	keys   map[string]Key
+}
+
+type keyringFile struct {
+	Keys []Key `json:"keys"`
+}
+
+func NewKeyring(keys []Key) (*Keyring, error) {
+	k := Keyring{}
+	if err := k.Reload(keys); err != nil {
+		return nil, err
+	}
+	return &k, nil
+}
+
+// LoadKeyringFile reads a keyring from a JSON file of the form
+// {"keys": [{"id": "...", "secret": "...", "status": "active"}]}.
+func LoadKeyringFile(path string) (*Keyring, error) {
+	keys, err := readKeyringFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewKeyring(keys)
+}
+
+// Reload atomically replaces the keys in the keyring. The keyring is left
+// untouched if the new keys are invalid.
+func (k *Keyring) Reload(keys []Key) error {
+	var active Key
+	set := make(map[string]Key, len(keys))
+
+	for _, key := range keys {
+		if key.ID == "" {
+			return errors.New("invalid keyring: key id is required")
+		}
+
+		if _, ok := set[key.ID]; ok {
+			return fmt.Errorf("invalid keyring: duplicate key id %s", key.ID)
+		}
+
+		if len(key.Secret) != keySize {
+			return fmt.Errorf("invalid keyring: key %s must be exactly %d characters", key.ID, keySize)
+		}
+
+		switch key.Status {
+		case KeyStatusActive:
+			if active.ID != "" {
+				return errors.New("invalid keyring: more than one active key")
+			}
+			active = key
+		case KeyStatusVerify, KeyStatusRetired:
+		default:
+			return fmt.Errorf("invalid keyring: unknown status %s for key %s", key.Status, key.ID)
+		}
+
+		set[key.ID] = key
+	}
+
+	if active.ID == "" {
+		return errors.New("invalid keyring: no active key")
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	k.active = active
+	k.keys = set
+	return nil
+}
+
+func (k *Keyring) ReloadFile(path string) error {
+	keys, err := readKeyringFile(path)
+	if err != nil {
+		return err
+	}
+	return k.Reload(keys)
+}
+
+// ActiveKey returns the key used to sign new tokens.
+func (k *Keyring) ActiveKey() Key {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	return k.active
+}
+
+// VerificationKey returns the key with the given id if it has not been retired.
+func (k *Keyring) VerificationKey(id string) (Key, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	key, ok := k.keys[id]
+	if !ok || key.Status == KeyStatusRetired {
+		return Key{}, ErrUnknownKey
+	}
+	return key, nil
+}
+
+func readKeyringFile(path string) ([]Key, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read keyring file: %w", err)
+	}
+
+	var f keyringFile
+	if err = json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("cannot parse keyring file: %w", err)
+	}
+
+	return f.Keys, nil
+}
@@ -0,0 +1,55 @@
+package token
+
+import (
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+// KeyringJWTMaker signs HS256 JWTs with the active key of a keyring and
+// records the key id in the kid header.
+type KeyringJWTMaker struct {
+	keyring *Keyring
+	config  Config
+}
+
+func NewKeyringJWTMaker(keyring *Keyring, opts ...Option) (Maker, error) {
+	return &KeyringJWTMaker{keyring, newConfig(opts)}, nil
+}
+
+func (m *KeyringJWTMaker) CreateToken(username string, role string, tokenType TokenType, duration time.Duration) (string, *Payload, error) {
+	payload, err := m.config.newPayload(username, role, tokenType, duration)
+	if err != nil {
+		return "", payload, err
+	}
+
+	key := m.keyring.ActiveKey()
+	jwtToken := jwt.NewWithClaims(jwt.SigningMethodHS256, payload)
+	jwtToken.Header["kid"] = key.ID
+
+	token, err := jwtToken.SignedString([]byte(key.Secret))
+	return token, payload, err
+}
+
+func (m *KeyringJWTMaker) VerifyToken(token string) (*Payload, error) {
+	keyFunc := func(token *jwt.Token) (interface{}, error) {
+		_, ok := token.Method.(*jwt.SigningMethodHMAC)
+		if !ok {
+			return nil, ErrInvalidToken
+		}
+
+		kid, ok := token.Header["kid"].(string)
+		if !ok {
+			return nil, ErrInvalidToken
+		}
+
+		key, err := m.keyring.VerificationKey(kid)
+		if err != nil {
+			return nil, err
+		}
+
+		return []byte(key.Secret), nil
+	}
+
+	return parseJWT(token, keyFunc, m.config)
+}
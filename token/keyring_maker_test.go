@@ -0,0 +1,103 @@
+package token
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/o1egl/paseto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyringPasetoMaker(t *testing.T) {
+	testKeyringMaker(t, NewKeyringPasetoMaker)
+}
+
+func TestKeyringJWTMaker(t *testing.T) {
+	testKeyringMaker(t, NewKeyringJWTMaker)
+}
+
+func TestKeyringPasetoMakerFooter(t *testing.T) {
+	keyring, err := NewKeyring([]Key{{ID: "k1", Secret: randomString(32), Status: KeyStatusActive}})
+	require.NoError(t, err)
+
+	maker, err := NewKeyringPasetoMaker(keyring)
+	require.NoError(t, err)
+
+	token, _, err := maker.CreateToken(randomString(6), randomString(6), TokenTypeAccess, time.Minute)
+	require.NoError(t, err)
+
+	var footer keyFooter
+	err = paseto.ParseFooter(token, &footer)
+	require.NoError(t, err)
+	require.Equal(t, "k1", footer.KeyID)
+}
+
+func TestKeyringJWTMakerHeader(t *testing.T) {
+	keyring, err := NewKeyring([]Key{{ID: "k1", Secret: randomString(32), Status: KeyStatusActive}})
+	require.NoError(t, err)
+
+	maker, err := NewKeyringJWTMaker(keyring)
+	require.NoError(t, err)
+
+	token, _, err := maker.CreateToken(randomString(6), randomString(6), TokenTypeAccess, time.Minute)
+	require.NoError(t, err)
+
+	jwtToken, _, err := new(jwt.Parser).ParseUnverified(token, &Payload{})
+	require.NoError(t, err)
+	require.Equal(t, "k1", jwtToken.Header["kid"])
+}
+
+func testKeyringMaker(t *testing.T, newMaker func(*Keyring, ...Option) (Maker, error)) {
+	key1 := Key{ID: "k1", Secret: randomString(32), Status: KeyStatusActive}
+	key2 := Key{ID: "k2", Secret: randomString(32), Status: KeyStatusActive}
+
+	keyring, err := NewKeyring([]Key{key1})
+	require.NoError(t, err)
+
+	maker, err := newMaker(keyring)
+	require.NoError(t, err)
+
+	username := randomString(6)
+	role := randomString(6)
+	oldToken, _, err := maker.CreateToken(username, role, TokenTypeAccess, time.Minute)
+	require.NoError(t, err)
+
+	payload, err := maker.VerifyToken(oldToken)
+	require.NoError(t, err)
+	require.Equal(t, username, payload.Username)
+	require.Equal(t, role, payload.Role)
+
+	// Rotate: k2 signs new tokens while k1 is still accepted.
+	key1.Status = KeyStatusVerify
+	err = keyring.Reload([]Key{key1, key2})
+	require.NoError(t, err)
+
+	newToken, _, err := maker.CreateToken(username, role, TokenTypeAccess, time.Minute)
+	require.NoError(t, err)
+
+	_, err = maker.VerifyToken(newToken)
+	require.NoError(t, err)
+
+	_, err = maker.VerifyToken(oldToken)
+	require.NoError(t, err)
+
+	// Retire k1: tokens signed with it are rejected.
+	key1.Status = KeyStatusRetired
+	err = keyring.Reload([]Key{key1, key2})
+	require.NoError(t, err)
+
+	payload, err = maker.VerifyToken(oldToken)
+	require.EqualError(t, err, ErrInvalidToken.Error())
+	require.Nil(t, payload)
+
+	_, err = maker.VerifyToken(newToken)
+	require.NoError(t, err)
+
+	expiredToken, _, err := maker.CreateToken(username, role, TokenTypeAccess, -time.Minute)
+	require.NoError(t, err)
+
+	payload, err = maker.VerifyToken(expiredToken)
+	require.EqualError(t, err, ErrExpiredToken.Error())
+	require.Nil(t, payload)
+}
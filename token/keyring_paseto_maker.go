@@ -0,0 +1,66 @@
+package token
+
+import (
+	"time"
+
+	"github.com/o1egl/paseto"
+)
+
+type keyFooter struct {
+	KeyID string `json:"kid"`
+}
+
+// KeyringPasetoMaker encrypts PASETO v2.local tokens with the active key of
+// a keyring and records the key id in the token footer.
+type KeyringPasetoMaker struct {
+	paseto  *paseto.V2
+	keyring *Keyring
+	config  Config
+}
+
+func NewKeyringPasetoMaker(keyring *Keyring, opts ...Option) (Maker, error) {
+	maker := KeyringPasetoMaker{
+		paseto:  paseto.NewV2(),
+		keyring: keyring,
+		config:  newConfig(opts),
+	}
+
+	return &maker, nil
+}
+
+func (m *KeyringPasetoMaker) CreateToken(username string, role string, tokenType TokenType, duration time.Duration) (string, *Payload, error) {
+	payload, err := m.config.newPayload(username, role, tokenType, duration)
+	if err != nil {
+		return "", payload, err
+	}
+
+	key := m.keyring.ActiveKey()
+	token, err := m.paseto.Encrypt([]byte(key.Secret), payload, keyFooter{KeyID: key.ID})
+	return token, payload, err
+}
+
+func (m *KeyringPasetoMaker) VerifyToken(token string) (*Payload, error) {
+	var footer keyFooter
+	if err := paseto.ParseFooter(token, &footer); err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	key, err := m.keyring.VerificationKey(footer.KeyID)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	payload := Payload{}
+
+	err = m.paseto.Decrypt(token, []byte(key.Secret), &payload, nil)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	err = m.config.validate(&payload)
+	if err != nil {
+		return nil, err
+	}
+
+	return &payload, nil
+}
@@ -0,0 +1,107 @@
+package token
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewKeyring(t *testing.T) {
+	keys := []Key{
+		{ID: "k1", Secret: randomString(32), Status: KeyStatusVerify},
+		{ID: "k2", Secret: randomString(32), Status: KeyStatusActive},
+		{ID: "k3", Secret: randomString(32), Status: KeyStatusRetired},
+	}
+
+	keyring, err := NewKeyring(keys)
+	require.NoError(t, err)
+	require.Equal(t, keys[1], keyring.ActiveKey())
+
+	key, err := keyring.VerificationKey("k1")
+	require.NoError(t, err)
+	require.Equal(t, keys[0], key)
+
+	_, err = keyring.VerificationKey("k3")
+	require.EqualError(t, err, ErrUnknownKey.Error())
+
+	_, err = keyring.VerificationKey("unknown")
+	require.EqualError(t, err, ErrUnknownKey.Error())
+}
+
+func TestNewKeyringInvalid(t *testing.T) {
+	testCases := []struct {
+		name string
+		keys []Key
+	}{
+		{
+			name: "NoActiveKey",
+			keys: []Key{{ID: "k1", Secret: randomString(32), Status: KeyStatusVerify}},
+		},
+		{
+			name: "TwoActiveKeys",
+			keys: []Key{
+				{ID: "k1", Secret: randomString(32), Status: KeyStatusActive},
+				{ID: "k2", Secret: randomString(32), Status: KeyStatusActive},
+			},
+		},
+		{
+			name: "DuplicateID",
+			keys: []Key{
+				{ID: "k1", Secret: randomString(32), Status: KeyStatusActive},
+				{ID: "k1", Secret: randomString(32), Status: KeyStatusVerify},
+			},
+		},
+		{
+			name: "InvalidSecretSize",
+			keys: []Key{{ID: "k1", Secret: randomString(16), Status: KeyStatusActive}},
+		},
+		{
+			name: "MissingID",
+			keys: []Key{{Secret: randomString(32), Status: KeyStatusActive}},
+		},
+		{
+			name: "UnknownStatus",
+			keys: []Key{{ID: "k1", Secret: randomString(32), Status: "disabled"}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			keyring, err := NewKeyring(tc.keys)
+			require.Error(t, err)
+			require.Nil(t, keyring)
+		})
+	}
+}
+
+func TestKeyringReloadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keyring.json")
+	secret1 := randomString(32)
+	secret2 := randomString(32)
+
+	err := os.WriteFile(path, []byte(`{"keys": [{"id": "k1", "secret": "`+secret1+`", "status": "active"}]}`), 0600)
+	require.NoError(t, err)
+
+	keyring, err := LoadKeyringFile(path)
+	require.NoError(t, err)
+	require.Equal(t, "k1", keyring.ActiveKey().ID)
+
+	err = os.WriteFile(path, []byte(`{"keys": [
+		{"id": "k1", "secret": "`+secret1+`", "status": "verify"},
+		{"id": "k2", "secret": "`+secret2+`", "status": "active"}
+	]}`), 0600)
+	require.NoError(t, err)
+
+	err = keyring.ReloadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, "k2", keyring.ActiveKey().ID)
+
+	err = os.WriteFile(path, []byte(`{"keys": []}`), 0600)
+	require.NoError(t, err)
+
+	err = keyring.ReloadFile(path)
+	require.Error(t, err)
+	require.Equal(t, "k2", keyring.ActiveKey().ID)
+}
@@ -11,9 +11,10 @@ import (
 type PasetoMaker struct {
 	paseto       *paseto.V2
 	symmetricKey []byte
+	config       Config
 }
 
-func NewPasetoMaker(sk string) (Maker, error) {
+func NewPasetoMaker(sk string, opts ...Option) (Maker, error) {
 	if len(sk) != chacha20poly1305.KeySize {
 		return nil, fmt.Errorf("invalid key size: must be exactly %d characters", chacha20poly1305.KeySize)
 	}
@@ -21,18 +22,20 @@ func NewPasetoMaker(sk string) (Maker, error) {
 	maker := PasetoMaker{
 		paseto:       paseto.NewV2(),
 		symmetricKey: []byte(sk),
+		config:       newConfig(opts),
 	}
 
 	return &maker, nil
 }
 
-func (m *PasetoMaker) CreateToken(username string, duration time.Duration) (string, error) {
-	payload, err := NewPayload(username, duration)
+func (m *PasetoMaker) CreateToken(username string, role string, tokenType TokenType, duration time.Duration) (string, *Payload, error) {
+	payload, err := m.config.newPayload(username, role, tokenType, duration)
 	if err != nil {
-		return "", err
+		return "", payload, err
 	}
 
-	return m.paseto.Encrypt(m.symmetricKey, payload, nil)
+	token, err := m.paseto.Encrypt(m.symmetricKey, payload, nil)
+	return token, payload, err
 }
 
 func (m *PasetoMaker) VerifyToken(token string) (*Payload, error) {
@@ -43,7 +46,7 @@ func (m *PasetoMaker) VerifyToken(token string) (*Payload, error) {
 		return nil, ErrInvalidToken
 	}
 
-	err = payload.Valid()
+	err = m.config.validate(&payload)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,81 @@
+package token
+
+import (
+	"crypto/ed25519"
+	"fmt"
+	"time"
+
+	"github.com/o1egl/paseto"
+)
+
+// PasetoPublicMaker signs PASETO v2.public tokens with an Ed25519 key pair.
+// A maker built from a public key alone can only verify tokens.
+type PasetoPublicMaker struct {
+	paseto     *paseto.V2
+	privateKey ed25519.PrivateKey
+	publicKey  ed25519.PublicKey
+	config     Config
+}
+
+func NewPasetoPublicMaker(privateKey ed25519.PrivateKey, opts ...Option) (Maker, error) {
+	if len(privateKey) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("invalid private key size: must be exactly %d bytes", ed25519.PrivateKeySize)
+	}
+
+	maker := PasetoPublicMaker{
+		paseto:     paseto.NewV2(),
+		privateKey: privateKey,
+		publicKey:  privateKey.Public().(ed25519.PublicKey),
+		config:     newConfig(opts),
+	}
+
+	return &maker, nil
+}
+
+func NewPasetoPublicVerifier(publicKey ed25519.PublicKey, opts ...Option) (Maker, error) {
+	if len(publicKey) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid public key size: must be exactly %d bytes", ed25519.PublicKeySize)
+	}
+
+	maker := PasetoPublicMaker{
+		paseto:    paseto.NewV2(),
+		publicKey: publicKey,
+		config:    newConfig(opts),
+	}
+
+	return &maker, nil
+}
+
+func (m *PasetoPublicMaker) CreateToken(username string, role string, tokenType TokenType, duration time.Duration) (string, *Payload, error) {
+	if m.privateKey == nil {
+		return "", nil, ErrVerifyOnly
+	}
+
+	payload, err := m.config.newPayload(username, role, tokenType, duration)
+	if err != nil {
+		return "", payload, err
+	}
+
+	token, err := m.paseto.Sign(m.privateKey, payload, nil)
+	return token, payload, err
+}
+
+func (m *PasetoPublicMaker) VerifyToken(token string) (*Payload, error) {
+	payload := Payload{}
+
+	err := m.paseto.Verify(token, m.publicKey, &payload, nil)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	err = m.config.validate(&payload)
+	if err != nil {
+		return nil, err
+	}
+
+	return &payload, nil
+}
+
+func (m *PasetoPublicMaker) PublicJWKs() []JWK {
+	return []JWK{newJWK(m.publicKey, "")}
+}
@@ -10,34 +10,57 @@ import (
 var (
 	ErrInvalidToken = errors.New("token is invalid")
 	ErrExpiredToken = errors.New("token has expired")
+	ErrVerifyOnly   = errors.New("token maker can only verify tokens")
+
+	ErrTokenNotYetValid = errors.New("token is not valid yet")
+	ErrInvalidIssuer    = errors.New("token has an invalid issuer")
+	ErrInvalidAudience  = errors.New("token has an invalid audience")
+)
+
+// TokenType is the purpose a token was issued for. An access token
+// authenticates API requests; a refresh token can only be exchanged for a
+// new access token.
+type TokenType string
+
+const (
+	TokenTypeAccess  TokenType = "access"
+	TokenTypeRefresh TokenType = "refresh"
 )
 
 type Payload struct {
 	ID        uuid.UUID `json:"id"`
 	Username  string    `json:"username"`
+	Role      string    `json:"role"`
+	Type      TokenType `json:"token_type"`
+	Issuer    string    `json:"issuer"`
+	Audience  string    `json:"audience"`
 	IssuedAt  time.Time `json:"issued_at"`
+	NotBefore time.Time `json:"not_before"`
 	ExpiredAt time.Time `json:"expired_at"`
 }
 
-func NewPayload(username string, duration time.Duration) (*Payload, error) {
+func NewPayload(username string, role string, tokenType TokenType, duration time.Duration) (*Payload, error) {
 	tokenId, err := uuid.NewRandom()
 	if err != nil {
 		return nil, err
 	}
 
+	now := time.Now()
 	payload := Payload{
 		ID:        tokenId,
 		Username:  username,
-		IssuedAt:  time.Now(),
-		ExpiredAt: time.Now().Add(duration),
+		Role:      role,
+		Type:      tokenType,
+		IssuedAt:  now,
+		NotBefore: now,
+		ExpiredAt: now.Add(duration),
 	}
 
 	return &payload, nil
 }
 
+// Valid checks the time-based claims without any clock skew tolerance.
+// Makers verify tokens against their own Config instead.
 func (p *Payload) Valid() error {
-	if time.Now().After(p.ExpiredAt) {
-		return ErrExpiredToken
-	}
-	return nil
+	return Config{}.validate(p)
 }
@@ -0,0 +1,38 @@
+package totp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCipher(t *testing.T) {
+	c, err := NewCipher("01234567890123456789012345678901")
+	require.NoError(t, err)
+
+	secret, err := GenerateSecret()
+	require.NoError(t, err)
+
+	encrypted, err := c.Encrypt(secret)
+	require.NoError(t, err)
+	require.NotContains(t, string(encrypted), secret)
+
+	decrypted, err := c.Decrypt(encrypted)
+	require.NoError(t, err)
+	require.Equal(t, secret, decrypted)
+
+	other, err := NewCipher("abcdefghijabcdefghijabcdefghijab")
+	require.NoError(t, err)
+
+	_, err = other.Decrypt(encrypted)
+	require.Error(t, err)
+
+	_, err = c.Decrypt([]byte("short"))
+	require.Error(t, err)
+}
+
+func TestNewCipherInvalidKey(t *testing.T) {
+	c, err := NewCipher("too-short")
+	require.Error(t, err)
+	require.Nil(t, c)
+}
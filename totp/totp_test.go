@@ -0,0 +1,84 @@
+package totp
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHOTPRFC6238Vectors(t *testing.T) {
+	key := []byte("12345678901234567890")
+
+	testCases := []struct {
+		unix int64
+		code string
+	}{
+		{59, "94287082"},
+		{1111111109, "07081804"},
+		{1111111111, "14050471"},
+		{1234567890, "89005924"},
+		{2000000000, "69279037"},
+		{20000000000, "65353130"},
+	}
+
+	for _, tc := range testCases {
+		step := Step(time.Unix(tc.unix, 0))
+		require.Equal(t, tc.code, hotp(key, uint64(step), 8))
+	}
+}
+
+func TestGenerateAndValidate(t *testing.T) {
+	secret, err := GenerateSecret()
+	require.NoError(t, err)
+	require.NotEmpty(t, secret)
+
+	now := time.Now()
+	code, err := GenerateCode(secret, now)
+	require.NoError(t, err)
+	require.Len(t, code, digits)
+
+	step, ok := Validate(code, secret, now)
+	require.True(t, ok)
+	require.Equal(t, Step(now), step)
+
+	step, ok = Validate(code, secret, now.Add(period*time.Second))
+	require.True(t, ok)
+	require.Equal(t, Step(now), step)
+
+	_, ok = Validate(code, secret, now.Add(3*period*time.Second))
+	require.False(t, ok)
+
+	otherSecret, err := GenerateSecret()
+	require.NoError(t, err)
+
+	_, ok = Validate(code, otherSecret, now)
+	require.False(t, ok)
+
+	_, ok = Validate("12345", secret, now)
+	require.False(t, ok)
+}
+
+func TestURL(t *testing.T) {
+	u := URL("SimpleBank", "alice", "JBSWY3DPEHPK3PXP")
+	require.True(t, strings.HasPrefix(u, "otpauth://totp/SimpleBank:alice?"))
+	require.Contains(t, u, "secret=JBSWY3DPEHPK3PXP")
+	require.Contains(t, u, "issuer=SimpleBank")
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, err := GenerateRecoveryCodes()
+	require.NoError(t, err)
+	require.Len(t, codes, recoveryCodeCount)
+
+	seen := map[string]bool{}
+	for _, code := range codes {
+		require.Len(t, code, 9)
+		require.False(t, seen[code])
+		seen[code] = true
+	}
+
+	require.Equal(t, HashRecoveryCode(codes[0]), HashRecoveryCode(" "+strings.ToUpper(codes[0])+" "))
+	require.NotEqual(t, HashRecoveryCode(codes[0]), HashRecoveryCode(codes[1]))
+}